@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/blck-snwmn/gocov/pkg/gocov"
+)
+
+// Threshold modes accepted by -threshold-mode
+const (
+	// ThresholdModeAbsolute compares the total coverage to -threshold
+	ThresholdModeAbsolute = "absolute"
+	// ThresholdModeBaseline fails when the total coverage drops below the -baseline report
+	ThresholdModeBaseline = "baseline"
+)
+
+// LoadBaseline reads a report previously written with -format json
+func LoadBaseline(path string) (*gocov.JSONReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, NewConfigError("baseline", path, err)
+	}
+
+	var report gocov.JSONReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, NewParseError(path, err)
+	}
+	// A report written with -no-total would otherwise read as a baseline of 0% that every run passes
+	var total struct {
+		Total *gocov.CoverageResult `json:"total"`
+	}
+	if err := json.Unmarshal(data, &total); err != nil || total.Total == nil {
+		return nil, NewParseError(path, ErrBaselineNoTotal)
+	}
+	return &report, nil
+}
+
+// RequiredCoverage returns the total coverage required by the configured threshold mode
+// In baseline mode the total may drop by at most config.BaselineTolerance percentage points.
+// The baseline is rounded to the threshold precision like the actual coverage, so a run matching its baseline passes
+func RequiredCoverage(config *Config, baseline *gocov.JSONReport) float64 {
+	if config.ThresholdMode == ThresholdModeBaseline {
+		return gocov.RoundCoverage(baseline.Total.Coverage, config.EffectiveThresholdPrecision()) - config.BaselineTolerance
+	}
+	return config.Threshold
+}
+
+// CheckThreshold decides whether the total coverage passes for the configured threshold mode
+// actual is compared rounded to the threshold precision, which defaults to the displayed one so what is shown matches the decision
+func CheckThreshold(config *Config, actual float64, baseline *gocov.JSONReport) error {
+	places := config.EffectiveThresholdPrecision()
+	if required := RequiredCoverage(config, baseline); gocov.BelowThreshold(actual, required, places) {
+		return NewThresholdError(required, actual, places)
+	}
+	return nil
+}
+
+// HasThresholdChecks reports whether any total or per-directory threshold is configured
+func HasThresholdChecks(config *Config) bool {
+	return config.Threshold > 0 || config.ThresholdMode == ThresholdModeBaseline ||
+		len(config.Thresholds) > 0 || config.FailUnderPerDir > 0 || config.SourceThresholds
+}
+
+// CheckCoverageThresholds runs the per-directory checks and then the total check
+// Per-directory violations are reported together and take precedence over the total.
+// A directory with a //gocov:threshold directive (sourceThresholds) is held to it instead of the thresholds map
+func CheckCoverageThresholds(config *Config, coverageByDir map[string]*gocov.DirCoverage, sourceThresholds map[string]float64, baseline *gocov.JSONReport) error {
+	stmts, covered := 0, 0
+	for _, cov := range coverageByDir {
+		stmts += cov.StmtCount
+		covered += cov.StmtCovered
+	}
+	total := gocov.CalculateCoverage(stmts, covered)
+
+	if violations := directoryViolations(config, coverageByDir, sourceThresholds); len(violations) > 0 {
+		return NewThresholdErrorWithViolations(RequiredCoverage(config, baseline), total, violations, config.EffectiveThresholdPrecision())
+	}
+	return CheckThreshold(config, total, baseline)
+}
+
+// DirectoryStatus reports whether each directory meets its per-directory thresholds
+// Directories without any per-directory threshold are left out, so they can be told apart from passing ones
+func DirectoryStatus(config *Config, coverageByDir map[string]*gocov.DirCoverage, sourceThresholds map[string]float64) map[string]bool {
+	status := make(map[string]bool)
+	for dir := range gocov.MatchThresholdPatterns(patternedDirectories(coverageByDir, sourceThresholds), config.Thresholds) {
+		status[dir] = true
+	}
+	for dir := range sourceThresholds {
+		if _, ok := coverageByDir[dir]; ok {
+			status[dir] = true
+		}
+	}
+	if config.FailUnderPerDir > 0 {
+		for dir, cov := range coverageByDir {
+			// Matches CheckMinDirectoryCoverage, which never checks directories without statements
+			if cov.StmtCount > 0 {
+				status[dir] = true
+			}
+		}
+	}
+	for _, violation := range directoryViolations(config, coverageByDir, sourceThresholds) {
+		status[violation.Directory] = false
+	}
+	return status
+}
+
+// directoryViolations runs the thresholds map, //gocov:threshold, and -fail-under-per-dir checks
+func directoryViolations(config *Config, coverageByDir map[string]*gocov.DirCoverage, sourceThresholds map[string]float64) []gocov.ThresholdViolation {
+	places := config.EffectiveThresholdPrecision()
+	violations := gocov.CheckDirectoryThresholds(patternedDirectories(coverageByDir, sourceThresholds), config.Thresholds, places)
+	violations = append(violations, gocov.CheckSourceThresholds(coverageByDir, sourceThresholds, places)...)
+	return append(violations, gocov.CheckMinDirectoryCoverage(coverageByDir, config.FailUnderPerDir, places)...)
+}
+
+// patternedDirectories returns the directories held to the thresholds map, i.e. those without a source directive
+func patternedDirectories(coverageByDir map[string]*gocov.DirCoverage, sourceThresholds map[string]float64) map[string]*gocov.DirCoverage {
+	if len(sourceThresholds) == 0 {
+		return coverageByDir
+	}
+	patterned := make(map[string]*gocov.DirCoverage, len(coverageByDir))
+	for dir, cov := range coverageByDir {
+		if _, ok := sourceThresholds[dir]; !ok {
+			patterned[dir] = cov
+		}
+	}
+	return patterned
+}
@@ -1,11 +1,23 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/blck-snwmn/gocov/pkg/gocov"
 	"golang.org/x/tools/cover"
 )
 
@@ -13,111 +25,660 @@ import (
 type CLI struct {
 	Output io.Writer
 	Args   []string
+	// Input is read for "-diff -"; defaults to os.Stdin when nil
+	Input io.Reader
+	// ErrOutput receives warnings; defaults to os.Stderr when nil
+	ErrOutput io.Writer
+
+	// watching is set while -watch re-runs the CLI, so the nested runs do not watch again
+	watching bool
+	// watchStop, watchInterval, and isTerminal let tests drive -watch
+	watchStop     <-chan struct{}
+	watchInterval time.Duration
+	isTerminal    func() bool
 }
 
 // NewCLI creates a new CLI instance
 func NewCLI(output io.Writer, args []string) *CLI {
 	return &CLI{
-		Output: output,
-		Args:   args,
+		Output:    output,
+		Args:      args,
+		Input:     os.Stdin,
+		ErrOutput: os.Stderr,
 	}
 }
 
 // Run executes the CLI
 func (c *CLI) Run() error {
+	// Dispatch subcommands
+	if len(c.Args) > 0 && c.Args[0] == "init" {
+		return c.runInit(c.Args[1:])
+	}
+
 	var (
 		coverProfile string
-		level        int
+		profileList  string
+		level        levelFlag
 		minCoverage  float64
 		maxCoverage  float64
+		below        float64
+		above        float64
 		outputFormat string
+		formats      formatFlag
 		ignoreDirs   string
 		configFile   string
 		concurrent   bool
 		threshold    float64
 		diffBase     string
+		diffFile     string
+		verbose      bool
+		quiet        bool
+		showHits     bool
+		weighted     bool
+		showVersion  bool
+		annotate     bool
+		workers      int
+		checkConfig  bool
+		validateOnly bool
+		diffThresh   float64
+		failOnEmpty  bool
+		allowMissing bool
+		minStmts     int
+		top          int
+		diffFileTh   float64
+		excludeTests bool
+		trimPrefix   string
+		includeDirs  string
+		showStats    bool
+		baseBranches string
+		diffInclude  string
+		diffExts     string
+		diffStrip    string
+		ignoreGen    bool
+		jsonCompact  bool
+		showLines    bool
+		diffSort     string
+		threshMode   string
+		baseline     string
+		tolerance    float64
+		histogram    bool
+		histBands    string
+		diffContext  int
+		perDirMin    float64
+		explain      bool
+		groupBy      string
+		metadata     bool
+		header       bool
+		noTotal      bool
+		srcThresh    bool
+		watch        bool
+		width        int
+		trend        string
+		history      string
+		sourceRoot   string
+		diffBaseProf string
+		ignoreFiles  string
+		historyRuns  int
+		uncovFuncs   bool
+		ascii        bool
+		color        string
+		round        int
+		thresholdPrc int
+		stream       bool
+		assumeMode   string
+		gitTimeout   time.Duration
 	)
 
 	flags := flag.NewFlagSet("gocov", flag.ContinueOnError)
 	flags.SetOutput(c.Output)
 
 	flags.StringVar(&coverProfile, "coverprofile", "", "Path to coverage profile file")
-	flags.IntVar(&level, "level", 0, "Directory level for aggregation (0 for leaf directories, -1 for all levels)")
+	flags.StringVar(&profileList, "coverprofile-list", "", "File listing coverage profiles to merge, one path per line (lines starting with # are comments)")
+	flags.StringVar(&groupBy, "by", "", "Group coverage by dir (directory as written in the profile, the default) or package (Go import path)")
+	flags.Var(&level, "level", "Directory level for aggregation (0 for leaf directories, -1 for all levels, auto for one level below the module root)")
 	flags.Float64Var(&minCoverage, "min", 0.0, "Minimum coverage percentage to display (0-100)")
 	flags.Float64Var(&maxCoverage, "max", 100.0, "Maximum coverage percentage to display (0-100)")
-	flags.StringVar(&outputFormat, "format", "", "Output format (table or json)")
+	flags.Float64Var(&below, "below", 100.0, "Show only directories at or below this coverage (shorthand for -max)")
+	flags.Float64Var(&above, "above", 0.0, "Show only directories at or above this coverage (shorthand for -min)")
+	flags.IntVar(&minStmts, "min-statements", 0, "Hide directories with fewer statements than this")
+	flags.IntVar(&top, "top", 0, "Show only the N directories with the lowest coverage, worst first (totals still cover every directory)")
+	flags.Var(&formats, "format", "Output format (table, json, html, summary, tsv, or tree); repeat as format:path to also write other formats to files")
 	flags.StringVar(&ignoreDirs, "ignore", "", "Comma-separated list of directories to ignore (supports wildcards)")
+	flags.StringVar(&ignoreFiles, "ignore-file", "", "Comma-separated list of files to ignore (base names like main.go, or paths with wildcards like **/gen/*.go)")
+	flags.StringVar(&trimPrefix, "trim-prefix", "", "Strip this prefix from directory names in the output (\"auto\" reads the module path from go.mod)")
+	flags.StringVar(&includeDirs, "include", "", "Comma-separated list of directories to include (supports wildcards, -ignore takes precedence)")
 	flags.StringVar(&configFile, "config", "", "Path to configuration file")
+	flags.BoolVar(&excludeTests, "exclude-tests", false, "Exclude _test.go files from coverage aggregation")
+	flags.IntVar(&width, "width", 0, "Width of the directory or file column in table output (defaults to fit the terminal, or 50)")
+	flags.BoolVar(&watch, "watch", false, "Re-run whenever the coverage profile changes (interactive terminals only)")
+	flags.StringVar(&sourceRoot, "source-root", "", "Module directory the profiled sources are read from; also drops blocks starting on a //gocov:ignore line")
+	flags.BoolVar(&srcThresh, "source-thresholds", false, "Enforce //gocov:threshold comments found in the source files of each package")
+	flags.BoolVar(&ignoreGen, "ignore-generated", false, "Exclude generated files (\"// Code generated ... DO NOT EDIT.\") from coverage aggregation")
 	flags.BoolVar(&concurrent, "concurrent", false, "Use concurrent processing for large coverage files")
+	flags.BoolVar(&stream, "stream", false, "Aggregate the coverage profile while reading it, without loading it whole (for very large profiles)")
+	flags.StringVar(&assumeMode, "assume-mode", "", "Coverage mode (set, count, or atomic) to assume for profiles without a mode: header")
+	flags.IntVar(&workers, "workers", 0, "Number of workers for concurrent processing (0 for number of CPUs)")
 	flags.Float64Var(&threshold, "threshold", 0.0, "Minimum total coverage threshold to pass (0-100)")
-	flags.StringVar(&diffBase, "diff", "", "Show coverage for changed lines only (e.g., main, HEAD~1)")
+	flags.Float64Var(&diffThresh, "diff-threshold", 0.0, "Minimum diff coverage threshold to pass (0-100, defaults to -threshold)")
+	flags.Float64Var(&diffFileTh, "diff-file-threshold", 0.0, "Minimum diff coverage required for every changed file (0-100)")
+	flags.Float64Var(&perDirMin, "fail-under-per-dir", 0.0, "Fail when any directory is below this coverage (0-100), listing every failing directory")
+	flags.StringVar(&threshMode, "threshold-mode", "", "How the total coverage is checked: absolute (against -threshold, the default) or baseline (no drop below -baseline)")
+	flags.StringVar(&baseline, "baseline", "", "Path to a previous -format json report used by -threshold-mode baseline")
+	flags.Float64Var(&tolerance, "baseline-tolerance", 0.0, "Percentage points the total coverage may drop below the baseline")
+	flags.StringVar(&diffBase, "diff", "", "Show coverage for changed lines only (e.g., main, HEAD~1, v1.2.0..v1.3.0, auto, - for a patch on stdin)")
+	flags.StringVar(&baseBranches, "diff-base-branch", "", "Comma-separated branches probed for the merge base with -diff auto (default main,master)")
+	flags.StringVar(&diffInclude, "diff-include", "", "Changed lines counted in diff mode: added (new lines only) or modified (also lines replacing deletions and -diff-include-context lines, the default)")
+	flags.IntVar(&diffContext, "diff-include-context", 0, "Also count this many unchanged lines around each change in diff mode (dropped by -diff-include added)")
+	flags.StringVar(&diffExts, "diff-ext", "", "Comma-separated file extensions considered in diff mode (default .go)")
+	flags.StringVar(&diffStrip, "diff-strip-prefix", "", "Strip this prefix from changed file paths before matching them against the coverage profile")
+	flags.StringVar(&diffSort, "diff-sort", "", "Order of files in diff mode: file (default) or uncovered (most uncovered lines first)")
+	flags.DurationVar(&gitTimeout, "git-timeout", 0, "Maximum time each git command may take in diff mode (default 30s)")
+	flags.StringVar(&diffBaseProf, "diff-base-profile", "", "Coverage profile recorded at the diff base; changed lines it covered that are now uncovered are reported as regressed")
+	flags.StringVar(&diffFile, "diff-file", "", "Show coverage for changed lines read from a unified diff file instead of git")
+	flags.BoolVar(&annotate, "annotate", false, "Emit GitHub Actions annotations for uncovered lines in diff mode")
+	flags.BoolVar(&jsonCompact, "json-compact", false, "Write JSON output on a single line instead of pretty-printing it")
+	flags.BoolVar(&noTotal, "no-total", false, "Omit the TOTAL and FILTERED TOTAL rows from table, TSV, and JSON output (thresholds are still checked)")
+	flags.BoolVar(&ascii, "ascii", false, "Mark directories in the table's pass column with PASS and FAIL instead of ✓ and ✗")
+	flags.StringVar(&color, "color", "", "When the table's pass column uses ✓ and ✗: auto (only on a terminal), always, or never (PASS and FAIL)")
+	flags.IntVar(&round, "round", gocov.DefaultPrecision, "Decimal places coverage is shown with; thresholds are compared against the rounded value")
+	flags.IntVar(&thresholdPrc, "threshold-precision", 0, "Decimal places coverage is rounded to before threshold checks (defaults to -round, -1 compares unrounded coverage)")
+	flags.BoolVar(&header, "header", false, "Print a header row with -format tsv")
+	flags.BoolVar(&metadata, "metadata", false, "Add a metadata object with the generation time and command line to JSON output")
+	flags.BoolVar(&verbose, "verbose", false, "Show uncovered line ranges for each directory")
+	flags.BoolVar(&quiet, "quiet", false, "Only print the total coverage")
+	flags.BoolVar(&showStats, "stats", false, "Print the number of files and directories analyzed")
+	flags.BoolVar(&histogram, "histogram", false, "Print how many directories fall into each coverage band")
+	flags.StringVar(&histBands, "histogram-bands", "", "Comma-separated ascending band boundaries for -histogram (default 50,80)")
+	flags.BoolVar(&showVersion, "version", false, "Print version information and exit")
+	flags.BoolVar(&uncovFuncs, "uncovered-funcs", false, "List functions with no covered statements (a JSON array with -format json) instead of the coverage table")
+	flags.BoolVar(&explain, "explain", false, "Print to stderr whether each profiled directory was kept or ignored and which pattern decided it")
+	flags.StringVar(&trend, "trend", "", "Comma-separated JSON reports to show the total coverage trend of, instead of analyzing a profile")
+	flags.StringVar(&history, "history", "", "Append the total coverage and the time of each run to this JSON history file")
+	flags.IntVar(&historyRuns, "history-sparkline", 0, "Print a sparkline of the last N runs recorded with -history")
+	flags.BoolVar(&checkConfig, "check-config", false, "Validate the configuration file and exit")
+	flags.BoolVar(&validateOnly, "validate-only", false, "Parse the coverage profile, print how many profiles and blocks it holds and any malformed blocks, and exit without a report")
+	flags.BoolVar(&showHits, "show-hits", false, "Show execution hit counts (most useful with -covermode=atomic or count)")
+	flags.BoolVar(&weighted, "weighted", false, "Show execution counts summed per block, regardless of block size (most useful with -covermode=atomic or count)")
+	flags.BoolVar(&showLines, "lines", false, "Show line-based coverage alongside statement coverage")
+	flags.BoolVar(&failOnEmpty, "fail-on-empty", false, "Fail when the coverage profile contains no statements")
+	flags.BoolVar(&allowMissing, "allow-missing", false, "Succeed without a report when the coverage profile does not exist (malformed profiles still fail)")
 
 	if err := flags.Parse(c.Args); err != nil {
 		return err
 	}
 
+	// Print version information without requiring a cover profile
+	if showVersion {
+		c.printVersion()
+		return nil
+	}
+
+	// Validate the configuration file without requiring a cover profile
+	if checkConfig {
+		return c.checkConfiguration(configFile)
+	}
+
+	// Show the trend of earlier reports without requiring a cover profile
+	if trend != "" {
+		config, err := c.loadConfiguration(configFile, ignoreDirs)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		if isFlagSet(flags, "round") {
+			config.Round = &round
+		}
+		if err := ValidateRound(config.EffectiveRound()); err != nil {
+			return err
+		}
+		return c.runTrend(trend, config.EffectiveRound())
+	}
+
 	// Validate cover profile
-	if coverProfile == "" {
+	if coverProfile == "" && profileList == "" {
 		flags.Usage()
 		return ErrNoInput
 	}
 
+	// Watch mode re-runs everything below with the same arguments
+	if watch && !c.watching {
+		return c.runWatch(coverProfile, profileList)
+	}
+
 	// Load configuration
 	config, err := c.loadConfiguration(configFile, ignoreDirs)
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
+	// The first -format decides the primary format, additional ones are written alongside it
+	outputs := make([]formatOutput, 0, len(formats))
+	for _, spec := range formats {
+		outputs = append(outputs, parseFormatOutput(spec))
+	}
+	if len(outputs) > 0 {
+		outputFormat = outputs[0].format
+	}
+
+	// -below and -above are shorthands for -max and -min
+	if err := applyBoundAlias(flags, "below", "max", below, &maxCoverage); err != nil {
+		return err
+	}
+	if err := applyBoundAlias(flags, "above", "min", above, &minCoverage); err != nil {
+		return err
+	}
+
 	// Merge command line flags with config
-	config.MergeWithFlags(&level, &minCoverage, &maxCoverage, &outputFormat, config.Ignore, &concurrent, &threshold)
+	config.MergeWithFlags(&level.value, &minCoverage, &maxCoverage, &outputFormat, config.Ignore, &concurrent, &threshold)
+	if verbose {
+		config.Verbose = true
+	}
+	if quiet {
+		config.Quiet = true
+	}
+	if showHits {
+		config.ShowHits = true
+	}
+	if weighted {
+		config.Weighted = true
+	}
+	if workers != 0 {
+		config.Workers = workers
+	}
+	if diffThresh != 0 {
+		config.DiffThreshold = diffThresh
+	}
+	if failOnEmpty {
+		config.FailOnEmpty = true
+	}
+	if allowMissing {
+		config.AllowMissing = true
+	}
+	if minStmts != 0 {
+		config.MinStatements = minStmts
+	}
+	if top != 0 {
+		config.Top = top
+	}
+	if diffFileTh != 0 {
+		config.DiffFileThreshold = diffFileTh
+	}
+	if excludeTests {
+		config.ExcludeTests = true
+	}
+	if trimPrefix != "" {
+		config.TrimPrefix = trimPrefix
+	}
+	if includeDirs != "" {
+		config.Include = SplitPatterns(includeDirs)
+	}
+	if ignoreFiles != "" {
+		config.IgnoreFiles = SplitPatterns(ignoreFiles)
+	}
+	if showStats {
+		config.Stats = true
+	}
+	if baseBranches != "" {
+		config.DiffBaseBranches = SplitPatterns(baseBranches)
+	}
+	if diffInclude != "" {
+		config.DiffInclude = diffInclude
+	}
+	if diffExts != "" {
+		config.DiffExtensions = SplitPatterns(diffExts)
+	}
+	if diffStrip != "" {
+		config.DiffStripPrefix = diffStrip
+	}
+	if diffBaseProf != "" {
+		config.DiffBaseProfile = diffBaseProf
+	}
+	if ignoreGen {
+		config.IgnoreGenerated = true
+	}
+	if jsonCompact {
+		config.JSONCompact = true
+	}
+	if metadata {
+		config.Metadata = true
+	}
+	if header {
+		config.Header = true
+	}
+	if noTotal {
+		config.NoTotal = true
+	}
+	if srcThresh {
+		config.SourceThresholds = true
+	}
+	if sourceRoot != "" {
+		config.SourceRoot = sourceRoot
+	}
+	if level.auto {
+		config.Level = resolveAutoLevel(config.SourceRoot)
+	}
+	if width != 0 {
+		config.Width = width
+	}
+	if ascii {
+		config.ASCII = true
+	}
+	if color != "" {
+		config.Color = color
+	}
+	if isFlagSet(flags, "round") {
+		config.Round = &round
+	}
+	if isFlagSet(flags, "threshold-precision") {
+		config.ThresholdPrecision = &thresholdPrc
+	}
+	if history != "" {
+		config.History = history
+	}
+	if historyRuns != 0 {
+		config.HistorySparkline = historyRuns
+	}
+	if stream {
+		config.Stream = true
+	}
+	if assumeMode != "" {
+		config.AssumeMode = assumeMode
+	}
+	if gitTimeout != 0 {
+		config.GitTimeout = gitTimeout
+	}
+	if showLines {
+		config.Lines = true
+	}
+	if diffSort != "" {
+		config.DiffSort = diffSort
+	}
+	if threshMode != "" {
+		config.ThresholdMode = threshMode
+	}
+	if baseline != "" {
+		config.Baseline = baseline
+	}
+	if tolerance != 0 {
+		config.BaselineTolerance = tolerance
+	}
+	if histogram {
+		config.Histogram = true
+	}
+	if diffContext != 0 {
+		config.DiffIncludeContext = diffContext
+	}
+	if perDirMin != 0 {
+		config.FailUnderPerDir = perDirMin
+	}
+	if groupBy != "" {
+		config.By = groupBy
+	}
+	if histBands != "" {
+		bands, err := ParseHistogramBands(histBands)
+		if err != nil {
+			return err
+		}
+		config.HistogramBands = bands
+	}
 
 	// Validate configuration
 	if err := c.validateConfiguration(config); err != nil {
 		return err
 	}
+	if len(outputs) == 0 {
+		outputs = []formatOutput{{format: config.Format}}
+	}
+	// Diff mode and -uncovered-funcs write a single report to stdout instead of going through the formatters
+	if (diffBase != "" || diffFile != "" || uncovFuncs) && (len(outputs) > 1 || outputs[0].path != "") {
+		return NewValidationError("format", formats.String(), "diff mode and -uncovered-funcs take a single -format without a file path")
+	}
 
-	// Parse coverage profile
-	profiles, err := cover.ParseProfiles(coverProfile)
-	if err != nil {
-		return NewParseError(coverProfile, err)
+	// Load the baseline up front so a bad path fails before any output is written
+	var baselineReport *gocov.JSONReport
+	if config.ThresholdMode == ThresholdModeBaseline {
+		baselineReport, err = LoadBaseline(config.Baseline)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Streaming aggregates a single profile without ever holding its parsed blocks
+	if config.Stream {
+		if option := streamConflict(coverProfile, profileList, diffBase, diffFile, explain, uncovFuncs, validateOnly, config); option != "" {
+			return NewConfigError("stream", option, ErrStreamConflict)
+		}
+	}
+
+	// Parse coverage profile, naming the list file in later errors when no profile was given directly
+	var profiles []*cover.Profile
+	var mode string
+	if !config.Stream {
+		profiles, err = loadProfiles(coverProfile, profileList, config.AssumeMode)
+		if err != nil {
+			return c.checkMissingProfile(err, config)
+		}
+		if coverProfile == "" {
+			coverProfile = profileList
+		}
+		if config.FailOnEmpty && len(profiles) == 0 {
+			return NewParseError(coverProfile, ErrEmptyProfile)
+		}
+		mode, err = ValidateProfileModes(profiles)
+		if err != nil {
+			return NewParseError(coverProfile, err)
+		}
+	}
+
+	if validateOnly {
+		return c.validateProfiles(coverProfile, profiles, mode)
 	}
 
 	// Check if diff mode is enabled
-	if diffBase != "" {
-		return c.runDiffMode(profiles, diffBase, config.Threshold)
+	if diffBase != "" || diffFile != "" {
+		return c.runDiffMode(profiles, diffBase, diffFile, config, annotate)
 	}
 
 	// Create analyzer
-	analyzer := NewCoverageAnalyzer(config.Level, config.Ignore)
+	analyzer := gocov.NewCoverageAnalyzer(config.Level, config.Ignore)
+	analyzer.SetVerbose(config.Verbose)
+	analyzer.SetWorkers(config.Workers)
+	analyzer.SetExcludeTests(config.ExcludeTests)
+	analyzer.SetIncludePatterns(config.Include)
+	analyzer.SetIgnoreFilePatterns(config.IgnoreFiles)
+	analyzer.SetGroupBy(config.By)
+	analyzer.SetLevelRules(config.Levels)
+	if config.IgnoreGenerated || config.SourceThresholds || config.SourceRoot != "" || uncovFuncs || len(config.Ignore) > 0 || len(config.Include) > 0 || len(config.IgnoreFiles) > 0 || config.By == gocov.GroupByPackage {
+		modulePath, dir, err := resolveSourceRoot(config.SourceRoot)
+		if err != nil {
+			return err
+		}
+		// Module path patterns then also match absolute profile paths, and local paths map to packages
+		analyzer.SetModuleRoot(modulePath, dir)
+		if config.IgnoreGenerated {
+			analyzer.SetIgnoreGenerated(modulePath, dir)
+		}
+		// Line directives are only read when the sources are pointed to explicitly
+		if config.SourceRoot != "" {
+			analyzer.SetIgnoreDirectives(modulePath, dir)
+		}
+	}
+
+	if explain {
+		c.explainDirectories(analyzer, profiles)
+	}
+
+	if uncovFuncs {
+		return c.writeUncoveredFuncs(analyzer.UncoveredFuncs(profiles), config)
+	}
 
 	// Aggregate coverage data
-	var coverageByDir map[string]*DirCoverage
-	if config.Concurrent {
-		coverageByDir = analyzer.AggregateConcurrent(profiles)
-	} else {
-		coverageByDir = analyzer.Aggregate(profiles)
+	// An explicit -concurrent=false forces sequential processing, otherwise the input size decides
+	ctx := context.Background()
+	var coverageByDir map[string]*gocov.DirCoverage
+	switch {
+	case config.Stream:
+		coverageByDir, mode, err = streamCoverage(analyzer, coverProfile, config.AssumeMode)
+	case isFlagSet(flags, "concurrent") && !concurrent:
+		coverageByDir, err = analyzer.AggregateContext(ctx, profiles)
+	case config.Concurrent:
+		coverageByDir, err = analyzer.AggregateConcurrentContext(ctx, profiles)
+	default:
+		coverageByDir, err = analyzer.AggregateAutoContext(ctx, profiles)
+	}
+	if err != nil {
+		return c.checkMissingProfile(err, config)
+	}
+	if config.FailOnEmpty && !hasStatements(coverageByDir) {
+		return NewParseError(coverProfile, ErrEmptyProfile)
 	}
 
-	// Create formatter
-	formatter, err := c.createFormatter(config.Format)
+	// Create formatters
+	formatters, closeOutputs, err := c.createFormatters(outputs, config.Quiet)
 	if err != nil {
 		return err
 	}
+	defer closeOutputs()
+
+	// Check thresholds before writing output so JSON reports can carry the result;
+	// the error is returned only after all output has been written
+	var sourceThresholds map[string]float64
+	if config.SourceThresholds {
+		sourceThresholds = analyzer.SourceThresholds(profiles)
+	}
+	thresholdErr := CheckCoverageThresholds(config, coverageByDir, sourceThresholds, baselineReport)
+	status := DirectoryStatus(config, coverageByDir, sourceThresholds)
+
+	// JSON output carries the stats inline; other text formats print them after the results
+	stats := gocov.CalculateStats(coverageByDir)
+	for i, formatter := range formatters {
+		switch formatter := formatter.(type) {
+		case *gocov.TableFormatter:
+			formatter.SetPrecision(config.EffectiveRound())
+			formatter.SetHideTotal(config.NoTotal)
+			formatter.SetASCII(c.useASCII(config, outputs[i].path))
+			formatter.SetGroupBy(config.By)
+			// Only tables on stdout are fitted to the terminal
+			if outputs[i].path == "" {
+				formatter.SetWidth(c.columnWidth(config.Width, tableColumnsWidth(config)))
+			} else {
+				formatter.SetWidth(config.Width)
+			}
+		case *gocov.TSVFormatter:
+			formatter.SetPrecision(config.EffectiveRound())
+			formatter.SetHeader(config.Header)
+			formatter.SetGroupBy(config.By)
+			formatter.SetHideTotal(config.NoTotal)
+		case *gocov.TreeFormatter:
+			formatter.SetPrecision(config.EffectiveRound())
+			formatter.SetHideTotal(config.NoTotal)
+			formatter.SetGroupBy(config.By)
+			if outputs[i].path == "" {
+				formatter.SetWidth(c.columnWidth(config.Width, treeColumnsWidth))
+			} else {
+				formatter.SetWidth(config.Width)
+			}
+		case *gocov.SummaryFormatter:
+			formatter.SetPrecision(config.EffectiveRound())
+		}
+		jsonFormatter, ok := formatter.(*gocov.JSONFormatter)
+		if !ok {
+			continue
+		}
+		jsonFormatter.SetHideTotal(config.NoTotal)
+		jsonFormatter.SetIndent(!config.JSONCompact)
+		// -top orders the results worst first
+		jsonFormatter.SetKeepOrder(config.Top > 0)
+		if config.Stats {
+			jsonFormatter.SetStats(stats)
+		}
+		if HasThresholdChecks(config) {
+			jsonFormatter.SetThresholdResult(RequiredCoverage(config, baselineReport), thresholdErr == nil)
+		}
+		if config.Metadata {
+			jsonFormatter.SetMetadata(append([]string{"gocov"}, c.Args...))
+		}
+	}
+
+	// Show the detected coverage mode in verbose table output
+	if config.Verbose && !config.Quiet && hasStdoutFormat(outputs, "table") && mode != "" {
+		fmt.Fprintf(c.Output, "Coverage mode: %s\n", mode)
+	}
+
+	// Shorten directory names for display only, so thresholds still see the full paths
+	displayCoverage := coverageByDir
+	if config.TrimPrefix != "" {
+		prefix, err := resolveTrimPrefix(config.TrimPrefix, config.SourceRoot)
+		if err != nil {
+			return err
+		}
+		displayCoverage = gocov.TrimDirectoryPrefix(coverageByDir, prefix)
+		status = trimStatus(status, prefix)
+	}
 
 	// Display results
-	totalCoverage, err := c.displayResults(coverageByDir, config.Coverage.Min, config.Coverage.Max, formatter)
+	totalCoverage, err := c.displayResults(displayCoverage, config.Coverage.Min, config.Coverage.Max, config.MinStatements, config.Top, config.ShowHits, config.Weighted, config.Lines, status, formatters...)
 	if err != nil {
 		return err
 	}
+	if err := closeOutputs(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if config.Stats && hasStdoutFormat(outputs, "table", "summary", "tree") {
+		fmt.Fprintf(c.Output, "analyzed %d files across %d directories\n", stats.Files, stats.Directories)
+	}
+	if config.Histogram && hasStdoutFormat(outputs, "table", "summary", "tree") {
+		bands := config.EffectiveHistogramBands()
+		c.writeHistogram(bands, gocov.CoverageHistogram(coverageByDir, bands))
+	}
+	if config.History != "" {
+		c.recordHistory(config, totalCoverage, hasStdoutFormat(outputs, "table", "summary", "tree"))
+	}
+
+	return thresholdErr
+}
 
-	// Check threshold
-	if config.Threshold > 0 && totalCoverage < config.Threshold {
-		return NewThresholdError(config.Threshold, totalCoverage)
+// recordHistory appends the total coverage to the -history file and prints the sparkline of recent runs when showSparkline is set
+// Failing to record is only a warning, so an unwritable or corrupt history never fails the run
+func (c *CLI) recordHistory(config *Config, totalCoverage float64, showSparkline bool) {
+	entries, err := RecordHistory(config.History, totalCoverage)
+	if err != nil {
+		fmt.Fprintf(c.errOutput(), "warning: coverage history not recorded: %v\n", err)
+		return
+	}
+	if config.HistorySparkline > 0 && showSparkline {
+		runs := min(config.HistorySparkline, len(entries))
+		fmt.Fprintf(c.Output, "History (last %d runs): %s\n", runs, HistorySparkline(entries, runs))
 	}
+}
 
+// runInit writes a commented default configuration file
+func (c *CLI) runInit(args []string) error {
+	var force bool
+
+	flags := flag.NewFlagSet("gocov init", flag.ContinueOnError)
+	flags.SetOutput(c.Output)
+	flags.BoolVar(&force, "force", false, "Overwrite an existing configuration file")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	path := configFileNames[0]
+	if err := WriteDefaultConfig(path, force); err != nil {
+		return err
+	}
+
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+	fmt.Fprintf(c.Output, "Created %s with default settings\n", path)
 	return nil
 }
 
+func (c *CLI) printVersion() {
+	fmt.Fprintf(c.Output, "gocov %s (commit: %s, built: %s, %s)\n", version, commit, date, runtime.Version())
+}
+
 func (c *CLI) loadConfiguration(configFile, ignoreDirs string) (*Config, error) {
 	config := DefaultConfig()
 
@@ -137,13 +698,25 @@ func (c *CLI) loadConfiguration(configFile, ignoreDirs string) (*Config, error)
 		}
 	}
 
+	// Apply environment variable overrides
+	if err := config.ApplyEnvOverrides(); err != nil {
+		return nil, err
+	}
+
 	// Parse ignore patterns from command line
 	if ignoreDirs != "" {
-		ignorePatterns := strings.Split(ignoreDirs, ",")
-		for i := range ignorePatterns {
-			ignorePatterns[i] = strings.TrimSpace(ignorePatterns[i])
+		config.Ignore = SplitPatterns(ignoreDirs)
+	}
+	// Comments may appear in ignore lists from any source, e.g. "*/vendor/*, # third party"
+	config.Ignore = StripPatternComments(config.Ignore)
+
+	// Patterns from a .gocovignore file add to those of the command line or configuration
+	if ignoreFile := FindIgnoreFile(); ignoreFile != "" {
+		patterns, err := LoadIgnoreFile(ignoreFile)
+		if err != nil {
+			return nil, err
 		}
-		config.Ignore = ignorePatterns
+		config.Ignore = append(config.Ignore, patterns...)
 	}
 
 	return config, nil
@@ -153,96 +726,803 @@ func (c *CLI) validateConfiguration(config *Config) error {
 	if err := ValidateCoverageConfig(config.Coverage.Min, config.Coverage.Max); err != nil {
 		return err
 	}
+	if err := ValidateWorkers(config.Workers); err != nil {
+		return err
+	}
+	if err := ValidateMinStatements(config.MinStatements); err != nil {
+		return err
+	}
+	if err := ValidateTop(config.Top); err != nil {
+		return err
+	}
 	if err := ValidateThreshold(config.Threshold); err != nil {
 		return err
 	}
+	if err := ValidateDiffThreshold(config.DiffThreshold); err != nil {
+		return err
+	}
+	if err := ValidateDiffFileThreshold(config.DiffFileThreshold); err != nil {
+		return err
+	}
+	if err := ValidateDiffInclude(config.DiffInclude); err != nil {
+		return err
+	}
+	if err := ValidateDiffSort(config.DiffSort); err != nil {
+		return err
+	}
+	if err := ValidateThresholdMode(config.ThresholdMode, config.Baseline); err != nil {
+		return err
+	}
+	if err := ValidateBaselineTolerance(config.BaselineTolerance); err != nil {
+		return err
+	}
+	if err := ValidateHistogramBands(config.HistogramBands); err != nil {
+		return err
+	}
+	if err := ValidateDiffIncludeContext(config.DiffIncludeContext); err != nil {
+		return err
+	}
+	if err := ValidateFailUnderPerDir(config.FailUnderPerDir); err != nil {
+		return err
+	}
+	if err := ValidateGroupBy(config.By); err != nil {
+		return err
+	}
+	if err := ValidateWidth(config.Width); err != nil {
+		return err
+	}
+	if err := ValidateColor(config.Color); err != nil {
+		return err
+	}
+	if err := ValidateRound(config.EffectiveRound()); err != nil {
+		return err
+	}
+	if err := ValidateThresholdPrecision(config.EffectiveThresholdPrecision()); err != nil {
+		return err
+	}
+	if err := ValidateGitTimeout(config.GitTimeout); err != nil {
+		return err
+	}
+	if err := ValidateHistorySparkline(config.HistorySparkline, config.History); err != nil {
+		return err
+	}
+	if err := ValidateAssumeMode(config.AssumeMode); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkConfiguration loads and validates a configuration file without running analysis
+func (c *CLI) checkConfiguration(configFile string) error {
+	if configFile == "" {
+		configFile = FindConfigFile()
+		if configFile == "" {
+			fmt.Fprintln(c.Output, "No configuration file found, using defaults")
+			return nil
+		}
+	} else if _, err := os.Stat(configFile); os.IsNotExist(err) {
+		return NewConfigError("config", configFile, ErrConfigNotFound)
+	}
+
+	config, err := c.loadConfiguration(configFile, "")
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := c.validateConfiguration(config); err != nil {
+		return err
+	}
+	if err := ValidateFormat(config.Format); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.Output, "Configuration file %s is valid\n", configFile)
+	return nil
+}
+
+// levelFlag accepts an integer -level or "auto"
+type levelFlag struct {
+	value int
+	auto  bool
+}
+
+func (f *levelFlag) String() string {
+	if f.auto {
+		return "auto"
+	}
+	return strconv.Itoa(f.value)
+}
+
+func (f *levelFlag) Set(value string) error {
+	if value == "auto" {
+		f.auto = true
+		return nil
+	}
+	level, err := strconv.Atoi(value)
+	if err != nil {
+		return errors.New(`must be an integer or "auto"`)
+	}
+	f.value = level
+	f.auto = false
+	return nil
+}
+
+// formatFlag collects repeated -format values
+type formatFlag []string
+
+func (f *formatFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *formatFlag) Set(value string) error {
+	*f = append(*f, value)
 	return nil
 }
 
-func (c *CLI) createFormatter(format string) (OutputFormatter, error) {
+// formatOutput is one -format destination; an empty path writes to the CLI output
+type formatOutput struct {
+	format string
+	path   string
+}
+
+// parseFormatOutput splits a "format:path" -format value
+func parseFormatOutput(spec string) formatOutput {
+	format, path, _ := strings.Cut(spec, ":")
+	return formatOutput{format: format, path: path}
+}
+
+// hasStdoutFormat reports whether an output without a path uses one of formats
+func hasStdoutFormat(outputs []formatOutput, formats ...string) bool {
+	for _, output := range outputs {
+		if output.path == "" && slices.Contains(formats, output.format) {
+			return true
+		}
+	}
+	return false
+}
+
+// createFormatters builds a formatter for every output, creating the files of outputs with a path
+// The returned function closes those files and reports the first close error
+func (c *CLI) createFormatters(outputs []formatOutput, quiet bool) ([]gocov.OutputFormatter, func() error, error) {
+	var files []*os.File
+	closeFiles := func() error {
+		var firstErr error
+		for _, file := range files {
+			if err := file.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		files = nil
+		return firstErr
+	}
+
+	formatters := make([]gocov.OutputFormatter, 0, len(outputs))
+	for _, output := range outputs {
+		w := c.Output
+		if output.path != "" {
+			file, err := os.Create(output.path)
+			if err != nil {
+				closeFiles()
+				return nil, nil, fmt.Errorf("failed to create output file: %w", err)
+			}
+			files = append(files, file)
+			w = file
+		}
+
+		formatter, err := c.createFormatter(output.format, quiet, w)
+		if err != nil {
+			closeFiles()
+			return nil, nil, err
+		}
+		formatters = append(formatters, formatter)
+	}
+	return formatters, closeFiles, nil
+}
+
+func (c *CLI) createFormatter(format string, quiet bool, w io.Writer) (gocov.OutputFormatter, error) {
 	switch format {
 	case "json":
-		return &JSONFormatter{writer: c.Output}, nil
+		return gocov.NewJSONFormatter(w, quiet), nil
 	case "table":
-		return &TableFormatter{writer: c.Output}, nil
+		return gocov.NewTableFormatter(w, quiet), nil
+	case "html":
+		return gocov.NewHTMLFormatter(w), nil
+	case "summary":
+		return gocov.NewSummaryFormatter(w), nil
+	case "tsv":
+		return gocov.NewTSVFormatter(w, quiet), nil
+	case "tree":
+		return gocov.NewTreeFormatter(w, quiet), nil
 	default:
 		return nil, NewConfigError("format", format, ErrInvalidFormat)
 	}
 }
 
-func (c *CLI) displayResults(coverageByDir map[string]*DirCoverage, minCoverage, maxCoverage float64, formatter OutputFormatter) (float64, error) {
-	// Filter directories based on coverage
-	filteredDirs := FilterDirectories(coverageByDir, minCoverage, maxCoverage)
+// trimStatus rekeys the result of DirectoryStatus like TrimDirectoryPrefix; merged directories pass only if all did
+func trimStatus(status map[string]bool, prefix string) map[string]bool {
+	trimmed := make(map[string]bool, len(status))
+	for dir, pass := range status {
+		dir = gocov.TrimDirectory(dir, prefix)
+		if existing, exists := trimmed[dir]; exists {
+			pass = pass && existing
+		}
+		trimmed[dir] = pass
+	}
+	return trimmed
+}
+
+// displayResults writes the directories that pass the display filters to every formatter
+// status holds the per-directory threshold result (see DirectoryStatus); directories missing from it have no pass field
+func (c *CLI) displayResults(coverageByDir map[string]*gocov.DirCoverage, minCoverage, maxCoverage float64, minStatements, top int, showHits, weighted, showLines bool, status map[string]bool, formatters ...gocov.OutputFormatter) (float64, error) {
+	// Filter directories based on coverage and statement count
+	filteredDirs := gocov.FilterDirectories(coverageByDir, minCoverage, maxCoverage, minStatements)
 
 	// Build results
 	// Pre-allocate with the size of filtered directories
-	results := make([]CoverageResult, 0, len(filteredDirs))
+	results := make([]gocov.CoverageResult, 0, len(filteredDirs))
 	filteredStmts := 0
 	filteredCovered := 0
+	filteredHits := 0
+	filteredExecutions := 0
+	filteredLines := 0
+	filteredLinesCovered := 0
 
 	for _, dir := range filteredDirs {
 		cov := coverageByDir[dir]
-		coverage := CalculateCoverage(cov.StmtCount, cov.StmtCovered)
-
-		results = append(results, CoverageResult{
-			Directory:  dir,
-			Statements: cov.StmtCount,
-			Covered:    cov.StmtCovered,
-			Coverage:   coverage,
-		})
+		results = append(results, coverageResult(dir, cov, showHits, weighted, showLines, status))
 
 		filteredStmts += cov.StmtCount
 		filteredCovered += cov.StmtCovered
+		filteredHits += cov.TotalHits
+		filteredExecutions += cov.Executions
+		filteredLines += cov.LineCount
+		filteredLinesCovered += cov.LineCovered
+	}
+
+	// Keep the top lowest-coverage directories, after the filtered total has been summed
+	if top > 0 {
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].Coverage < results[j].Coverage
+		})
+		if len(results) > top {
+			results = results[:top]
+		}
 	}
 
 	// Calculate totals
 	totalStmts := 0
 	totalCovered := 0
+	totalHits := 0
+	totalExecutions := 0
+	totalLines := 0
+	totalLinesCovered := 0
 	for _, cov := range coverageByDir {
 		totalStmts += cov.StmtCount
 		totalCovered += cov.StmtCovered
+		totalHits += cov.TotalHits
+		totalExecutions += cov.Executions
+		totalLines += cov.LineCount
+		totalLinesCovered += cov.LineCovered
 	}
 
-	totalResult := CoverageResult{
-		Directory:  "TOTAL",
-		Statements: totalStmts,
-		Covered:    totalCovered,
-		Coverage:   CalculateCoverage(totalStmts, totalCovered),
+	totalResult := gocov.CoverageResult{
+		Directory:   "TOTAL",
+		Statements:  totalStmts,
+		Covered:     totalCovered,
+		Coverage:    gocov.CalculateCoverage(totalStmts, totalCovered),
+		Hits:        countIf(showHits, totalHits),
+		Executions:  countIf(weighted, totalExecutions),
+		LineCount:   countIf(showLines, totalLines),
+		LineCovered: countIf(showLines, totalLinesCovered),
 	}
 
-	// Prepare filtered total if filters are applied
-	var filteredTotal *CoverageResult
-	if minCoverage > 0.0 || maxCoverage < 100.0 {
-		filteredTotal = &CoverageResult{
-			Directory:  "FILTERED TOTAL",
-			Statements: filteredStmts,
-			Covered:    filteredCovered,
-			Coverage:   CalculateCoverage(filteredStmts, filteredCovered),
+	// Prepare filtered total and the directories the filters dropped if filters are applied
+	var filteredTotal *gocov.CoverageResult
+	var excluded []gocov.CoverageResult
+	if minCoverage > 0.0 || maxCoverage < 100.0 || minStatements > 0 {
+		kept := make(map[string]bool, len(filteredDirs))
+		for _, dir := range filteredDirs {
+			kept[dir] = true
+		}
+		excluded = make([]gocov.CoverageResult, 0, len(coverageByDir)-len(filteredDirs))
+		for dir, cov := range coverageByDir {
+			if !kept[dir] {
+				excluded = append(excluded, coverageResult(dir, cov, showHits, weighted, showLines, status))
+			}
+		}
+		filteredTotal = &gocov.CoverageResult{
+			Directory:   "FILTERED TOTAL",
+			Statements:  filteredStmts,
+			Covered:     filteredCovered,
+			Coverage:    gocov.CalculateCoverage(filteredStmts, filteredCovered),
+			Hits:        countIf(showHits, filteredHits),
+			Executions:  countIf(weighted, filteredExecutions),
+			LineCount:   countIf(showLines, filteredLines),
+			LineCovered: countIf(showLines, filteredLinesCovered),
 		}
 	}
 
-	err := formatter.Format(results, totalResult, filteredTotal)
-	return totalResult.Coverage, err
+	for _, formatter := range formatters {
+		if jsonFormatter, ok := formatter.(*gocov.JSONFormatter); ok {
+			jsonFormatter.SetExcluded(excluded)
+		}
+		if err := formatter.Format(results, totalResult, filteredTotal); err != nil {
+			return totalResult.Coverage, err
+		}
+	}
+	return totalResult.Coverage, nil
+}
+
+// coverageResult builds the output row of dir; status holds the per-directory threshold result (see DirectoryStatus)
+func coverageResult(dir string, cov *gocov.DirCoverage, showHits, weighted, showLines bool, status map[string]bool) gocov.CoverageResult {
+	var pass *bool
+	if p, ok := status[dir]; ok {
+		pass = &p
+	}
+	return gocov.CoverageResult{
+		Directory:       dir,
+		Statements:      cov.StmtCount,
+		Covered:         cov.StmtCovered,
+		Coverage:        gocov.CalculateCoverage(cov.StmtCount, cov.StmtCovered),
+		Hits:            countIf(showHits, cov.TotalHits),
+		Executions:      countIf(weighted, cov.Executions),
+		LineCount:       countIf(showLines, cov.LineCount),
+		LineCovered:     countIf(showLines, cov.LineCovered),
+		UncoveredBlocks: sortUncoveredBlocks(cov.UncoveredBlocks),
+		Pass:            pass,
+	}
+}
+
+// useASCII reports whether a table written to path (empty for stdout) marks its pass column with PASS and FAIL
+// -color auto, the default, keeps ✓ and ✗ for terminals only
+func (c *CLI) useASCII(config *Config, path string) bool {
+	switch {
+	case config.ASCII || config.Color == "never":
+		return true
+	case config.Color == "always":
+		return false
+	default:
+		return path != "" || !c.outputIsTerminal()
+	}
+}
+
+// treeColumnsWidth is the width of the tree without the directory column
+const treeColumnsWidth = 31
+
+// tableColumnsWidth returns the width of the coverage table without the directory column
+func tableColumnsWidth(config *Config) int {
+	width := 31
+	if config.ShowHits {
+		width += 13
+	}
+	if config.Weighted {
+		width += 13
+	}
+	if config.Lines {
+		width += 25
+	}
+	if len(config.Thresholds) > 0 || config.FailUnderPerDir > 0 || config.SourceThresholds {
+		width += 5
+	}
+	return width
+}
+
+// columnWidth returns the directory or file column width for output to c.Output
+// An explicit width wins; on a terminal the column gets the space the other columns leave,
+// and otherwise 0 keeps the formatter default
+func (c *CLI) columnWidth(width, otherColumns int) int {
+	if width > 0 {
+		return width
+	}
+	columns := terminalWidth(c.Output)
+	if columns == 0 {
+		return 0
+	}
+	return max(columns-otherColumns, gocov.MinColumnWidth)
+}
+
+// applyBoundAlias copies the value of the alias flag to bound when the alias was given
+// Giving both flags is only accepted when they agree
+func applyBoundAlias(flags *flag.FlagSet, alias, name string, value float64, bound *float64) error {
+	if !isFlagSet(flags, alias) {
+		return nil
+	}
+	if isFlagSet(flags, name) && *bound != value {
+		return NewValidationError(alias, value, fmt.Sprintf("conflicts with -%s %v", name, *bound))
+	}
+	*bound = value
+	return nil
+}
+
+// isFlagSet reports whether the named flag was given on the command line
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// writeHistogram prints one line per coverage band with its directory count
+func (c *CLI) writeHistogram(bands []float64, counts []int) {
+	fmt.Fprintln(c.Output, "Coverage histogram:")
+	lower := 0.0
+	for i, count := range counts {
+		upper := 100.0
+		if i < len(bands) {
+			upper = bands[i]
+		}
+		fmt.Fprintf(c.Output, "  %-10s %d dirs\n", fmt.Sprintf("%g-%g%%", lower, upper), count)
+		lower = upper
+	}
+}
+
+// hasStatements reports whether any directory has at least one statement
+func hasStatements(coverageByDir map[string]*gocov.DirCoverage) bool {
+	for _, cov := range coverageByDir {
+		if cov.StmtCount > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// countIf returns a pointer to n when the optional metric should be displayed
+func countIf(show bool, n int) *int {
+	if !show {
+		return nil
+	}
+	return &n
+}
+
+// sortUncoveredBlocks orders uncovered blocks by file and line so output is stable
+// regardless of whether aggregation ran concurrently
+func sortUncoveredBlocks(blocks []gocov.UncoveredBlock) []gocov.UncoveredBlock {
+	sort.Slice(blocks, func(i, j int) bool {
+		if blocks[i].File != blocks[j].File {
+			return blocks[i].File < blocks[j].File
+		}
+		return blocks[i].StartLine < blocks[j].StartLine
+	})
+	return blocks
+}
+
+// writeUncoveredFuncs lists uncovered functions as "file:line: name (N statements)" lines, or as a JSON array
+func (c *CLI) writeUncoveredFuncs(funcs []gocov.UncoveredFunc, config *Config) error {
+	if config.Format == "json" {
+		if funcs == nil {
+			funcs = []gocov.UncoveredFunc{}
+		}
+		var data []byte
+		var err error
+		if config.JSONCompact {
+			data, err = json.Marshal(funcs)
+		} else {
+			data, err = json.MarshalIndent(funcs, "", "  ")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to encode uncovered functions: %w", err)
+		}
+		fmt.Fprintf(c.Output, "%s\n", data)
+		return nil
+	}
+
+	for _, fn := range funcs {
+		fmt.Fprintf(c.Output, "%s:%d: %s (%d statements)\n", fn.File, fn.Line, fn.Name, fn.Statements)
+	}
+	return nil
+}
+
+// explainDirectories writes the ignore/include decision for every profiled directory to ErrOutput
+func (c *CLI) explainDirectories(analyzer *gocov.CoverageAnalyzer, profiles []*cover.Profile) {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, profile := range profiles {
+		dir := analyzer.GroupKey(profile.FileName)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		kept, reason := analyzer.ExplainDirectory(dir)
+		decision := "kept"
+		if !kept {
+			decision = "ignored"
+		}
+		fmt.Fprintf(c.errOutput(), "explain: %s %s (%s)\n", decision, dir, reason)
+	}
+}
+
+// parseCoverProfiles parses the coverage profile at path
+// A path with glob wildcards reads every matching file and merges the profiles of each source file
+func parseCoverProfiles(path, assumeMode string) ([]*cover.Profile, error) {
+	if !strings.ContainsAny(path, "*?[") {
+		profiles, err := parseProfileFile(path, assumeMode)
+		if err != nil {
+			return nil, NewParseError(path, err)
+		}
+		return profiles, nil
+	}
+
+	files, err := filepath.Glob(path)
+	if err != nil {
+		return nil, NewParseError(path, err)
+	}
+	if len(files) == 0 {
+		return nil, NewParseError(path, ErrNoProfileMatch)
+	}
+
+	var profiles []*cover.Profile
+	for _, file := range files {
+		parsed, err := parseProfileFile(file, assumeMode)
+		if err != nil {
+			return nil, NewParseError(file, err)
+		}
+		profiles = append(profiles, parsed...)
+	}
+	// Check modes before merging, which would hide a file profiled in different modes
+	if _, err := ValidateProfileModes(profiles); err != nil {
+		return nil, NewParseError(path, err)
+	}
+	return gocov.MergeProfiles(profiles), nil
+}
+
+// validateProfiles prints each malformed block and a one-line summary of the parsed profiles for -validate-only
+// Malformed blocks fail the run with ErrMalformedProfile
+func (c *CLI) validateProfiles(path string, profiles []*cover.Profile, mode string) error {
+	blocks := 0
+	for _, profile := range profiles {
+		blocks += len(profile.Blocks)
+	}
+	malformed := MalformedBlocks(profiles)
+	for _, block := range malformed {
+		fmt.Fprintln(c.Output, block)
+	}
+
+	summary := fmt.Sprintf("%s: %d profiles, %d blocks", path, len(profiles), blocks)
+	if mode != "" {
+		summary += ", mode " + mode
+	}
+	if len(malformed) > 0 {
+		summary += fmt.Sprintf(", %d malformed", len(malformed))
+	}
+	fmt.Fprintln(c.Output, summary)
+
+	if len(malformed) > 0 {
+		return NewParseError(path, ErrMalformedProfile)
+	}
+	return nil
+}
+
+// parseProfileFile parses the single coverage profile at path
+// With assumeMode set, a profile without a mode: header is parsed as if it started with one for assumeMode
+func parseProfileFile(path, assumeMode string) ([]*cover.Profile, error) {
+	if assumeMode == "" {
+		return gocov.ParseProfiles(path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return gocov.ParseProfilesFromReader(gocov.AssumeMode(gocov.NewProfileReader(file), assumeMode))
+}
+
+// errOutput returns the writer for warnings and notices, falling back to os.Stderr when ErrOutput is nil
+func (c *CLI) errOutput() io.Writer {
+	if c.ErrOutput == nil {
+		return os.Stderr
+	}
+	return c.ErrOutput
+}
+
+// checkMissingProfile returns err unless it reports a coverage profile that does not exist and
+// AllowMissing is set, in which case a notice is written and the run succeeds without a report
+func (c *CLI) checkMissingProfile(err error, config *Config) error {
+	if !config.AllowMissing || !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	fmt.Fprintf(c.errOutput(), "notice: skipping coverage report, %v\n", err)
+	return nil
+}
+
+// streamConflict returns the option that needs the parsed profiles -stream does not keep, or "" if there is none
+func streamConflict(coverProfile, profileList, diffBase, diffFile string, explain, uncovFuncs, validateOnly bool, config *Config) string {
+	switch {
+	case profileList != "":
+		return "-coverprofile-list"
+	case strings.ContainsAny(coverProfile, "*?["):
+		return "-coverprofile " + coverProfile
+	case diffBase != "":
+		return "-diff"
+	case diffFile != "":
+		return "-diff-file"
+	case explain:
+		return "-explain"
+	case uncovFuncs:
+		return "-uncovered-funcs"
+	case validateOnly:
+		return "-validate-only"
+	case config.SourceThresholds:
+		return "-source-thresholds"
+	}
+	return ""
+}
+
+// streamCoverage aggregates the profile at path with AggregateReader and returns its mode
+// With assumeMode set, a profile without a mode: header is read as if it started with one for assumeMode
+func streamCoverage(analyzer *gocov.CoverageAnalyzer, path, assumeMode string) (map[string]*gocov.DirCoverage, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", NewParseError(path, err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if assumeMode != "" {
+		r = gocov.AssumeMode(gocov.NewProfileReader(file), assumeMode)
+	}
+	coverageByDir, mode, err := analyzer.AggregateReader(r)
+	if err != nil {
+		return nil, "", NewParseError(path, err)
+	}
+	return coverageByDir, mode, nil
+}
+
+// loadProfiles parses the -coverprofile profile (or glob) and every profile named in the -coverprofile-list file,
+// merging them when more than one source is read
+func loadProfiles(coverProfile, listPath, assumeMode string) ([]*cover.Profile, error) {
+	var profiles []*cover.Profile
+	if coverProfile != "" {
+		parsed, err := parseCoverProfiles(coverProfile, assumeMode)
+		if err != nil {
+			return nil, err
+		}
+		profiles = parsed
+	}
+	if listPath == "" {
+		return profiles, nil
+	}
+
+	files, err := readProfileList(listPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		parsed, err := parseProfileFile(file, assumeMode)
+		if err != nil {
+			return nil, NewParseError(file, err)
+		}
+		profiles = append(profiles, parsed...)
+	}
+	if _, err := ValidateProfileModes(profiles); err != nil {
+		return nil, NewParseError(listPath, err)
+	}
+	return gocov.MergeProfiles(profiles), nil
+}
+
+// readProfileList reads the profile paths listed in path, skipping blank lines and lines starting with #
+// A # elsewhere belongs to the path. Relative paths are resolved from the working directory, like -coverprofile
+func readProfileList(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, NewParseError(path, err)
+	}
+	var files []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			files = append(files, line)
+		}
+	}
+	if len(files) == 0 {
+		return nil, NewParseError(path, ErrEmptyProfileList)
+	}
+	return files, nil
 }
 
 // runDiffMode runs coverage analysis for changed lines only
-func (c *CLI) runDiffMode(profiles []*cover.Profile, diffBase string, threshold float64) error {
-	// Get git diff
-	diff, err := GetGitDiffWithContext(diffBase)
+func (c *CLI) runDiffMode(profiles []*cover.Profile, diffBase, diffFile string, config *Config, annotate bool) error {
+	// Get the diff from a patch file, stdin, or git
+	diff, err := c.readDiff(diffBase, diffFile, config)
 	if err != nil {
-		return fmt.Errorf("failed to get git diff: %w", err)
+		return err
+	}
+
+	// Lines replacing deleted lines and context lines are counted unless only brand-new lines were requested
+	if config.DiffInclude == "added" {
+		diff.Lines = FilterDiffLines(diff.Lines, "added")
+	}
+
+	// Reconcile git paths with the path scheme used in the profile; reported paths stay repository-relative
+	diff.StripPrefix = config.DiffStripPrefix
+
+	// Compare against the coverage at the base commit when it was supplied
+	var baseProfiles []*cover.Profile
+	if config.DiffBaseProfile != "" {
+		baseProfiles, err = parseCoverProfiles(config.DiffBaseProfile, config.AssumeMode)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Calculate diff coverage
-	summary := CalculateDiffCoverage(profiles, diff)
+	summary := CalculateDiffCoverage(profiles, diff, baseProfiles)
+	SortDiffResults(summary, config.DiffSort)
+	if NoProfileMatched(summary) {
+		fmt.Fprintln(c.errOutput(), "warning: none of the changed files matched the coverage profile; check that the paths agree or adjust them with -diff-strip-prefix")
+	}
 
 	// Format and display results
-	fmt.Fprint(c.Output, FormatDiffCoverage(summary))
+	switch {
+	case config.Format == "json":
+		output, err := FormatDiffCoverageJSON(summary, !config.JSONCompact, config.Quiet)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(c.Output, output)
+	case config.Quiet:
+		fmt.Fprint(c.Output, FormatDiffCoverageTotal(summary, c.columnWidth(config.Width, diffColumnsWidth), config.EffectiveRound()))
+	default:
+		fmt.Fprint(c.Output, FormatDiffCoverage(summary, c.columnWidth(config.Width, diffColumnsWidth), config.EffectiveRound()))
+	}
+
+	// Emit GitHub Actions annotations for uncovered lines
+	// They go to stderr with JSON output, which must stay a single document; the runner reads commands from both
+	if annotate {
+		annotationOutput := c.Output
+		if config.Format == "json" {
+			annotationOutput = c.errOutput()
+		}
+		fmt.Fprint(annotationOutput, FormatGitHubAnnotations(summary))
+	}
+
+	// Check per-file thresholds independently of the aggregate threshold
+	threshold := config.EffectiveDiffThreshold()
+	places := config.EffectiveThresholdPrecision()
+	violations := CheckDiffFileThresholds(summary, config.DiffFileThreshold, places)
+	if len(violations) > 0 {
+		return NewThresholdErrorWithViolations(threshold, summary.Coverage, violations, places)
+	}
 
 	// Check threshold if specified
-	if threshold > 0 && summary.Coverage < threshold {
-		return NewThresholdError(threshold, summary.Coverage)
+	if threshold > 0 && gocov.BelowThreshold(summary.Coverage, threshold, places) {
+		return NewThresholdError(threshold, summary.Coverage, places)
 	}
 
 	return nil
 }
+
+// readDiff loads the diff from -diff-file, stdin ("-diff -"), or git
+func (c *CLI) readDiff(diffBase, diffFile string, config *Config) (*GitDiff, error) {
+	switch {
+	case diffFile != "":
+		file, err := os.Open(diffFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read diff file: %w", err)
+		}
+		defer file.Close()
+		return ParseUnifiedDiff(file, config.DiffIncludeContext, config.DiffExtensions...)
+	case diffBase == "-":
+		input := c.Input
+		if input == nil {
+			input = os.Stdin
+		}
+		return ParseUnifiedDiff(input, config.DiffIncludeContext, config.DiffExtensions...)
+	default:
+		// "auto" detects the merge base with the configured base branches
+		if diffBase == "auto" {
+			diffBase = ""
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), config.EffectiveGitTimeout())
+		defer cancel()
+		diff, err := GetGitDiffWithContext(ctx, diffBase, config.DiffExtensions, config.DiffIncludeContext, config.DiffBaseBranches...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get git diff: %w", err)
+		}
+		return diff, nil
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
@@ -32,11 +34,13 @@ func TestRunDiffMode(t *testing.T) {
 
 	// Test cases for different scenarios
 	tests := []struct {
-		name         string
-		diffBase     string
-		threshold    float64
-		wantErr      bool
-		wantInOutput []string
+		name          string
+		diffBase      string
+		threshold     float64
+		quiet         bool
+		wantErr       bool
+		wantInOutput  []string
+		wantNotOutput []string
 	}{
 		{
 			name:         "diff with HEAD",
@@ -59,19 +63,31 @@ func TestRunDiffMode(t *testing.T) {
 			wantErr:      true,
 			wantInOutput: []string{"Diff Coverage Report:"},
 		},
+		{
+			name:          "quiet still fails threshold",
+			diffBase:      "HEAD",
+			threshold:     90.0,
+			quiet:         true,
+			wantErr:       true,
+			wantInOutput:  []string{"TOTAL DIFF"},
+			wantNotOutput: []string{"Diff Coverage Report:", "File Cov"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Skip if not in a git repository
-			if _, err := getMergeBase(); err != nil {
+			if _, err := getMergeBase(context.Background()); err != nil {
 				t.Skip("Skipping git-dependent test - not in a git repository")
 			}
 
 			var buf bytes.Buffer
 			cli := &CLI{Output: &buf}
+			config := DefaultConfig()
+			config.Threshold = tt.threshold
+			config.Quiet = tt.quiet
 
-			err := cli.runDiffMode(profiles, tt.diffBase, tt.threshold)
+			err := cli.runDiffMode(profiles, tt.diffBase, "", config, false)
 
 			// Check error expectation
 			if (err != nil) != tt.wantErr {
@@ -92,6 +108,11 @@ func TestRunDiffMode(t *testing.T) {
 					t.Errorf("Output missing expected string: %q", expected)
 				}
 			}
+			for _, unexpected := range tt.wantNotOutput {
+				if strings.Contains(output, unexpected) {
+					t.Errorf("Output contains unexpected string: %q", unexpected)
+				}
+			}
 		})
 	}
 }
@@ -118,26 +139,41 @@ pkg/util/helper.go:5.1,15.1 1 1
 
 	// Test CLI with diff flag
 	tests := []struct {
-		name    string
-		args    []string
-		wantErr bool
+		name       string
+		args       []string
+		wantErr    bool
+		wantOutput string
 	}{
 		{
-			name:    "diff mode with HEAD",
-			args:    []string{"-coverprofile", coverageFile, "-diff", "HEAD"},
-			wantErr: false,
+			name:       "diff mode with HEAD",
+			args:       []string{"-coverprofile", coverageFile, "-diff", "HEAD"},
+			wantErr:    false,
+			wantOutput: "Diff Coverage Report:",
 		},
 		{
-			name:    "diff mode with threshold",
-			args:    []string{"-coverprofile", coverageFile, "-diff", "HEAD", "-threshold", "0"},
-			wantErr: false,
+			name:       "diff mode with threshold",
+			args:       []string{"-coverprofile", coverageFile, "-diff", "HEAD", "-threshold", "0"},
+			wantErr:    false,
+			wantOutput: "Diff Coverage Report:",
+		},
+		{
+			name:       "diff threshold overrides threshold",
+			args:       []string{"-coverprofile", coverageFile, "-diff", "HEAD", "-threshold", "0", "-diff-threshold", "90"},
+			wantErr:    true,
+			wantOutput: "Diff Coverage Report:",
+		},
+		{
+			name:       "diff mode with json format",
+			args:       []string{"-coverprofile", coverageFile, "-diff", "HEAD", "-format", "json"},
+			wantErr:    false,
+			wantOutput: `"total_lines"`,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Skip if not in a git repository
-			if _, err := getMergeBase(); err != nil {
+			if _, err := getMergeBase(context.Background()); err != nil {
 				t.Skip("Skipping git-dependent test - not in a git repository")
 			}
 
@@ -151,9 +187,295 @@ pkg/util/helper.go:5.1,15.1 1 1
 
 			// Check that diff mode was activated
 			output := buf.String()
-			if !strings.Contains(output, "Diff Coverage Report:") {
-				t.Error("Expected diff coverage report in output")
+			if !strings.Contains(output, tt.wantOutput) {
+				t.Errorf("Expected %q in output", tt.wantOutput)
 			}
 		})
 	}
 }
+
+func TestCLIWithDiffFile(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	coverageFile := filepath.Join(tmpDir, "coverage.out")
+	coverageContent := `mode: set
+main.go:10.1,20.1 1 1
+main.go:30.1,40.1 1 0
+`
+	if err := os.WriteFile(coverageFile, []byte(coverageContent), 0644); err != nil {
+		t.Fatalf("Failed to write coverage file: %v", err)
+	}
+
+	patch := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -14,0 +15,1 @@
++	covered()
+@@ -34,0 +35,1 @@
++	uncovered()
+`
+	patchFile := filepath.Join(tmpDir, "changes.patch")
+	if err := os.WriteFile(patchFile, []byte(patch), 0644); err != nil {
+		t.Fatalf("Failed to write patch file: %v", err)
+	}
+
+	t.Run("diff-file", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{"-coverprofile", coverageFile, "-diff-file", patchFile})
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		output := buf.String()
+		if !strings.Contains(output, "Uncovered lines: [35]") {
+			t.Errorf("Output should list the uncovered changed line\nGot: %s", output)
+		}
+		if !strings.Contains(output, "50.0%") {
+			t.Errorf("Output should report 50%% diff coverage\nGot: %s", output)
+		}
+	})
+
+	t.Run("diff from stdin", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{"-coverprofile", coverageFile, "-diff", "-", "-threshold", "80"})
+		cli.Input = strings.NewReader(patch)
+
+		err := cli.Run()
+		if _, ok := err.(*ThresholdError); !ok {
+			t.Errorf("Expected ThresholdError, got %T (%v)", err, err)
+		}
+		if !strings.Contains(buf.String(), "TOTAL DIFF") {
+			t.Errorf("Output should contain the diff report\nGot: %s", buf.String())
+		}
+	})
+
+	t.Run("diff-include added skips modified lines", func(t *testing.T) {
+		modifiedPatch := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -15,1 +15,1 @@
+-	old()
++	covered()
+@@ -34,0 +35,1 @@
++	uncovered()
+`
+		modifiedFile := filepath.Join(tmpDir, "modified.patch")
+		if err := os.WriteFile(modifiedFile, []byte(modifiedPatch), 0644); err != nil {
+			t.Fatalf("Failed to write patch file: %v", err)
+		}
+
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{"-coverprofile", coverageFile, "-diff-file", modifiedFile, "-diff-include", "added"})
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), "0.0%") {
+			t.Errorf("Only the added uncovered line should be counted\nGot: %s", buf.String())
+		}
+
+		buf.Reset()
+		cli = NewCLI(&buf, []string{"-coverprofile", coverageFile, "-diff-file", modifiedFile, "-diff-include", "modified"})
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), "50.0%") {
+			t.Errorf("Modified lines should be counted\nGot: %s", buf.String())
+		}
+	})
+
+	t.Run("diff-include-context counts surrounding lines", func(t *testing.T) {
+		contextPatch := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -33,3 +33,4 @@
+ 	a()
+ 	b()
++	uncovered()
+ 	c()
+`
+
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{"-coverprofile", coverageFile, "-diff", "-", "-diff-include-context", "1"})
+		cli.Input = strings.NewReader(contextPatch)
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), "Uncovered lines: [34 35 36]") {
+			t.Errorf("Context lines next to the change should be counted\nGot: %s", buf.String())
+		}
+
+		buf.Reset()
+		cli = NewCLI(&buf, []string{"-coverprofile", coverageFile, "-diff", "-", "-diff-include-context", "1", "-diff-include", "added"})
+		cli.Input = strings.NewReader(contextPatch)
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), "Uncovered lines: [35]") {
+			t.Errorf("-diff-include added should drop context lines\nGot: %s", buf.String())
+		}
+	})
+
+	t.Run("diff-ext includes templates", func(t *testing.T) {
+		tmplCoverage := filepath.Join(tmpDir, "tmpl.out")
+		if err := os.WriteFile(tmplCoverage, []byte(coverageContent+"views/index.tmpl:1.1,5.1 1 0\n"), 0644); err != nil {
+			t.Fatalf("Failed to write coverage file: %v", err)
+		}
+		tmplPatch := patch + `diff --git a/views/index.tmpl b/views/index.tmpl
+--- a/views/index.tmpl
++++ b/views/index.tmpl
+@@ -2,0 +3,1 @@
++{{ .Title }}
+`
+
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{"-coverprofile", tmplCoverage, "-diff", "-", "-diff-ext", ".go,.tmpl"})
+		cli.Input = strings.NewReader(tmplPatch)
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), "views/index.tmpl") {
+			t.Errorf("Output should include the changed template\nGot: %s", buf.String())
+		}
+
+		buf.Reset()
+		cli = NewCLI(&buf, []string{"-coverprofile", tmplCoverage, "-diff", "-"})
+		cli.Input = strings.NewReader(tmplPatch)
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if strings.Contains(buf.String(), "views/index.tmpl") {
+			t.Errorf("Templates should be skipped by default\nGot: %s", buf.String())
+		}
+	})
+
+	t.Run("warns when no changed file matches the profile", func(t *testing.T) {
+		monorepoPatch := `diff --git a/services/api/server.go b/services/api/server.go
+--- a/services/api/server.go
++++ b/services/api/server.go
+@@ -14,0 +15,1 @@
++	covered()
+`
+		var buf, errBuf bytes.Buffer
+		cli := NewCLI(&buf, []string{"-coverprofile", coverageFile, "-diff", "-"})
+		cli.Input = strings.NewReader(monorepoPatch)
+		cli.ErrOutput = &errBuf
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(errBuf.String(), "-diff-strip-prefix") {
+			t.Errorf("Expected a path mismatch warning\nGot: %s", errBuf.String())
+		}
+
+		buf.Reset()
+		errBuf.Reset()
+		cli = NewCLI(&buf, []string{"-coverprofile", coverageFile, "-diff", "-", "-diff-strip-prefix", "services/api"})
+		cli.Input = strings.NewReader(strings.ReplaceAll(monorepoPatch, "server.go", "main.go"))
+		cli.ErrOutput = &errBuf
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if errBuf.Len() != 0 {
+			t.Errorf("Expected no warning after stripping the prefix\nGot: %s", errBuf.String())
+		}
+		if !strings.Contains(buf.String(), "100.0%") {
+			t.Errorf("Stripped path should match the profile\nGot: %s", buf.String())
+		}
+	})
+
+	t.Run("strip prefix keeps repository paths in the report and annotations", func(t *testing.T) {
+		patch := `diff --git a/services/api/main.go b/services/api/main.go
+--- a/services/api/main.go
++++ b/services/api/main.go
+@@ -34,0 +35,1 @@
++	uncovered()
+`
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{"-coverprofile", coverageFile, "-diff", "-", "-diff-strip-prefix", "services/api", "-annotate"})
+		cli.Input = strings.NewReader(patch)
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), "::warning file=services/api/main.go,line=35::line not covered\n") {
+			t.Errorf("Annotation should name the repository path\nGot: %s", buf.String())
+		}
+		if !strings.Contains(buf.String(), "services/api/main.go") || !strings.Contains(buf.String(), "0.0%") {
+			t.Errorf("Report should list the repository path matched against the profile\nGot: %s", buf.String())
+		}
+	})
+
+	t.Run("annotations with JSON output go to stderr", func(t *testing.T) {
+		patch := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -34,0 +35,1 @@
++	uncovered()
+`
+		var buf, errBuf bytes.Buffer
+		cli := NewCLI(&buf, []string{"-coverprofile", coverageFile, "-diff", "-", "-annotate", "-format", "json"})
+		cli.Input = strings.NewReader(patch)
+		cli.ErrOutput = &errBuf
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !json.Valid(buf.Bytes()) {
+			t.Errorf("stdout should be a single JSON document\nGot: %s", buf.String())
+		}
+		if errBuf.String() != "::warning file=main.go,line=35::line not covered\n" {
+			t.Errorf("stderr = %q, want the annotation", errBuf.String())
+		}
+	})
+
+	t.Run("invalid diff-include", func(t *testing.T) {
+		cli := NewCLI(&bytes.Buffer{}, []string{"-coverprofile", coverageFile, "-diff-file", patchFile, "-diff-include", "deleted"})
+		if err := cli.Run(); err == nil {
+			t.Error("Expected error for invalid -diff-include")
+		}
+	})
+
+	t.Run("missing diff file", func(t *testing.T) {
+		cli := NewCLI(&bytes.Buffer{}, []string{"-coverprofile", coverageFile, "-diff-file", filepath.Join(tmpDir, "missing.patch")})
+		if err := cli.Run(); err == nil {
+			t.Error("Expected error for missing diff file")
+		}
+	})
+
+	t.Run("diff-base-profile reports regressed lines", func(t *testing.T) {
+		basePatch := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -35,1 +35,2 @@
+-	old()
++	rewritten()
++	added()
+`
+		baseCoverage := filepath.Join(tmpDir, "base.out")
+		if err := os.WriteFile(baseCoverage, []byte("mode: set\nmain.go:30.1,40.1 1 1\n"), 0644); err != nil {
+			t.Fatalf("Failed to write coverage file: %v", err)
+		}
+
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{"-coverprofile", coverageFile, "-diff", "-", "-diff-base-profile", baseCoverage, "-annotate"})
+		cli.Input = strings.NewReader(basePatch)
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		// Line 35 replaces a line covered at the base; line 36 is new, so it cannot have regressed
+		if !strings.Contains(buf.String(), "  Regressed lines: [35]\n") {
+			t.Errorf("Output should list the regressed line\nGot: %s", buf.String())
+		}
+		if !strings.Contains(buf.String(), "::warning file=main.go,line=35::line no longer covered (covered at the base)") ||
+			!strings.Contains(buf.String(), "::warning file=main.go,line=36::line not covered") {
+			t.Errorf("Annotations should tell regressions apart\nGot: %s", buf.String())
+		}
+
+		buf.Reset()
+		cli = NewCLI(&buf, []string{"-coverprofile", coverageFile, "-diff", "-", "-diff-base-profile", baseCoverage, "-format", "json"})
+		cli.Input = strings.NewReader(basePatch)
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), `"regressed_lines": [`) || !strings.Contains(buf.String(), `"regressed_lines": 1`) {
+			t.Errorf("JSON should carry the regressed lines\nGot: %s", buf.String())
+		}
+	})
+}
@@ -4,11 +4,18 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/blck-snwmn/gocov/pkg/gocov"
 )
 
 func TestNewCLI(t *testing.T) {
@@ -42,6 +49,24 @@ func TestCLIRun(t *testing.T) {
 		}
 	})
 
+	t.Run("version flag", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{"-version"})
+
+		err := cli.Run()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		output := buf.String()
+		if !strings.Contains(output, "gocov dev") {
+			t.Errorf("Output should contain version string\nGot: %s", output)
+		}
+		if !strings.Contains(output, runtime.Version()) {
+			t.Errorf("Output should contain Go runtime version\nGot: %s", output)
+		}
+	})
+
 	t.Run("invalid coverage profile", func(t *testing.T) {
 		var buf bytes.Buffer
 		cli := NewCLI(&buf, []string{"-coverprofile", "testdata/invalid.out"})
@@ -56,6 +81,164 @@ func TestCLIRun(t *testing.T) {
 		}
 	})
 
+	t.Run("glob coverage profile", func(t *testing.T) {
+		dir := t.TempDir()
+		profiles := map[string]string{
+			"util.out":   "mode: set\ngithub.com/example/project/pkg/util/a.go:1.1,2.1 2 1\ngithub.com/example/project/pkg/util/a.go:3.1,4.1 2 0\n",
+			"server.out": "mode: set\ngithub.com/example/project/cmd/server/main.go:1.1,2.1 1 0\ngithub.com/example/project/pkg/util/a.go:3.1,4.1 2 1\n",
+		}
+		for name, content := range profiles {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write coverage file: %v", err)
+			}
+		}
+
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{"-coverprofile", filepath.Join(dir, "*.out"), "-format", "json"})
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var report gocov.JSONReport
+		if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v", err)
+		}
+		// a.go appears in both profiles, so its blocks are merged instead of counted twice
+		if report.Total.Statements != 5 || report.Total.Covered != 4 {
+			t.Errorf("Expected merged totals of 4/5 statements, got %+v", report.Total)
+		}
+	})
+
+	t.Run("coverage profile list", func(t *testing.T) {
+		dir := t.TempDir()
+		profiles := map[string]string{
+			"util.out":   "mode: set\ngithub.com/example/project/pkg/util/a.go:1.1,2.1 2 1\ngithub.com/example/project/pkg/util/a.go:3.1,4.1 2 0\n",
+			"server.out": "mode: set\ngithub.com/example/project/cmd/server/main.go:1.1,2.1 1 0\ngithub.com/example/project/pkg/util/a.go:3.1,4.1 2 1\n",
+			"broken.out": "not a profile\n",
+		}
+		for name, content := range profiles {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write coverage file: %v", err)
+			}
+		}
+		writeList := func(name string, lines ...string) string {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+				t.Fatalf("Failed to write profile list: %v", err)
+			}
+			return path
+		}
+
+		list := writeList("profiles.txt", "# unit tests", filepath.Join(dir, "util.out"), "", "  # server", filepath.Join(dir, "server.out")+"  ")
+		var buf bytes.Buffer
+		if err := NewCLI(&buf, []string{"-coverprofile-list", list, "-format", "json"}).Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var report gocov.JSONReport
+		if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v", err)
+		}
+		if report.Total.Statements != 5 || report.Total.Covered != 4 {
+			t.Errorf("Expected merged totals of 4/5 statements, got %+v", report.Total)
+		}
+
+		broken := filepath.Join(dir, "broken.out")
+		err := NewCLI(io.Discard, []string{"-coverprofile-list", writeList("broken.txt", filepath.Join(dir, "util.out"), broken)}).Run()
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || parseErr.File != broken {
+			t.Errorf("Expected ParseError naming %s, got: %v", broken, err)
+		}
+
+		// A # inside a path is part of it, e.g. a directory per CI build number
+		buildDir := filepath.Join(dir, "build", "#1")
+		if err := os.MkdirAll(buildDir, 0755); err != nil {
+			t.Fatalf("Failed to create build directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(buildDir, "coverage.out"), []byte(profiles["util.out"]), 0644); err != nil {
+			t.Fatalf("Failed to write coverage file: %v", err)
+		}
+		buf.Reset()
+		if err := NewCLI(&buf, []string{"-coverprofile-list", writeList("builds.txt", filepath.Join(buildDir, "coverage.out")), "-format", "summary"}).Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := "coverage: 50.0% (2/4 statements)\n"; buf.String() != want {
+			t.Errorf("Profile under a # directory = %q, want %q", buf.String(), want)
+		}
+
+		err = NewCLI(io.Discard, []string{"-coverprofile-list", writeList("empty.txt", "# nothing yet", "")}).Run()
+		if !errors.Is(err, ErrEmptyProfileList) {
+			t.Errorf("Expected ErrEmptyProfileList, got: %v", err)
+		}
+	})
+
+	t.Run("group by package", func(t *testing.T) {
+		// Local file names are reported under the import path of the module in the working directory
+		profile := filepath.Join(t.TempDir(), "local.out")
+		content := "mode: set\n./pkg/gocov/a.go:1.1,2.1 1 1\ngithub.com/blck-snwmn/gocov/pkg/gocov/b.go:1.1,2.1 1 0\n"
+		if err := os.WriteFile(profile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write coverage file: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if err := NewCLI(&buf, []string{"-coverprofile", profile, "-by", "package"}).Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), "github.com/blck-snwmn/gocov/pkg/gocov                       2          1    50.0%") {
+			t.Errorf("Both files should be grouped under the package\nGot: %s", buf.String())
+		}
+		if !strings.HasPrefix(buf.String(), "Package ") {
+			t.Errorf("The first column should be labelled Package\nGot: %s", buf.String())
+		}
+
+		buf.Reset()
+		if err := NewCLI(&buf, []string{"-coverprofile", profile, "-by", "dir"}).Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), "\npkg/gocov ") {
+			t.Errorf("Directories should be kept as written in the profile\nGot: %s", buf.String())
+		}
+	})
+
+	t.Run("explain without ErrOutput falls back to stderr", func(t *testing.T) {
+		cli := &CLI{Output: io.Discard, Args: []string{"-coverprofile", "testdata/coverage.out", "-explain"}}
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("explain ignore decisions", func(t *testing.T) {
+		var buf, errBuf bytes.Buffer
+		cli := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-ignore", "*/internal/*", "-explain", "-format", "json"})
+		cli.ErrOutput = &errBuf
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		want := `explain: kept github.com/example/project/cmd/server (matched no ignore pattern)
+explain: ignored github.com/example/project/internal/service (matched ignore pattern "*/internal/*" at "project/internal/service")
+explain: kept github.com/example/project/pkg/util (matched no ignore pattern)
+`
+		if errBuf.String() != want {
+			t.Errorf("Unexpected explanation\nGot: %s\nWant: %s", errBuf.String(), want)
+		}
+		if !json.Valid(buf.Bytes()) {
+			t.Errorf("Explanations should not be mixed into the JSON output\nGot: %s", buf.String())
+		}
+	})
+
+	t.Run("glob matching nothing", func(t *testing.T) {
+		pattern := filepath.Join(t.TempDir(), "*.out")
+		cli := NewCLI(io.Discard, []string{"-coverprofile", pattern})
+
+		err := cli.Run()
+		if !errors.Is(err, ErrNoProfileMatch) {
+			t.Fatalf("Expected ErrNoProfileMatch, got: %v", err)
+		}
+		if !strings.Contains(err.Error(), pattern) {
+			t.Errorf("Error should name the pattern, got: %v", err)
+		}
+	})
+
 	t.Run("successful run with table format", func(t *testing.T) {
 		var buf bytes.Buffer
 		cli := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out"})
@@ -86,8 +269,8 @@ func TestCLIRun(t *testing.T) {
 
 		// Verify JSON output
 		var result struct {
-			Results []CoverageResult `json:"results"`
-			Total   CoverageResult   `json:"total"`
+			Results []gocov.CoverageResult `json:"results"`
+			Total   gocov.CoverageResult   `json:"total"`
 		}
 		if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
 			t.Fatalf("Failed to parse JSON output: %v", err)
@@ -101,6 +284,311 @@ func TestCLIRun(t *testing.T) {
 		}
 	})
 
+	t.Run("compact JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-format", "json", "-json-compact"})
+
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if strings.Count(buf.String(), "\n") != 1 {
+			t.Errorf("Expected single-line JSON\nGot: %s", buf.String())
+		}
+		if !json.Valid(buf.Bytes()) {
+			t.Errorf("Expected valid JSON\nGot: %s", buf.String())
+		}
+	})
+
+	t.Run("JSON metadata", func(t *testing.T) {
+		var buf bytes.Buffer
+		args := []string{"-coverprofile", "testdata/coverage.out", "-format", "json", "-metadata"}
+		cli := NewCLI(&buf, args)
+
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var report gocov.JSONReport
+		if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to parse JSON: %v\nGot: %s", err, buf.String())
+		}
+		if report.Metadata == nil {
+			t.Fatalf("Expected metadata in JSON output\nGot: %s", buf.String())
+		}
+		if _, err := time.Parse(time.RFC3339, report.Metadata.GeneratedAt); err != nil {
+			t.Errorf("generated_at should be RFC 3339: %v", err)
+		}
+		if !reflect.DeepEqual(report.Metadata.Command, append([]string{"gocov"}, args...)) {
+			t.Errorf("command = %v", report.Metadata.Command)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		for _, args := range [][]string{nil, {"-level", "4", "-verbose"}, {"-format", "json"}} {
+			var want, got bytes.Buffer
+			base := append([]string{"-coverprofile", "testdata/coverage.out"}, args...)
+			if err := NewCLI(&want, base).Run(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if err := NewCLI(&got, append(base, "-stream")).Run(); err != nil {
+				t.Fatalf("Unexpected error with -stream: %v", err)
+			}
+			if got.String() != want.String() {
+				t.Errorf("-stream output differs for %v\nGot: %s\nWant: %s", args, got.String(), want.String())
+			}
+		}
+
+		var buf bytes.Buffer
+		err := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-stream", "-diff-file", "testdata/coverage.out"}).Run()
+		if !errors.Is(err, ErrStreamConflict) {
+			t.Errorf("Expected ErrStreamConflict, got %v", err)
+		}
+		err = NewCLI(&buf, []string{"-coverprofile", "testdata/invalid.out", "-stream"}).Run()
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("Expected ParseError, got %T (%v)", err, err)
+		}
+	})
+
+	t.Run("uncovered functions", func(t *testing.T) {
+		dir := t.TempDir()
+		sourcePath := filepath.Join(dir, "a.go")
+		if err := os.WriteFile(sourcePath, []byte("package a\n\nfunc Used() {\n\t_ = 1\n}\n\nfunc Unused() {\n\t_ = 2\n}\n"), 0644); err != nil {
+			t.Fatalf("Failed to write source file: %v", err)
+		}
+		profile := filepath.Join(dir, "coverage.out")
+		content := "mode: set\n" + sourcePath + ":3.13,5.2 1 1\n" + sourcePath + ":7.15,9.2 1 0\n"
+		if err := os.WriteFile(profile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write coverage file: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if err := NewCLI(&buf, []string{"-coverprofile", profile, "-uncovered-funcs"}).Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := sourcePath + ":7: Unused (1 statements)\n"; buf.String() != want {
+			t.Errorf("Unexpected list\nGot: %q\nWant: %q", buf.String(), want)
+		}
+
+		buf.Reset()
+		if err := NewCLI(&buf, []string{"-coverprofile", profile, "-uncovered-funcs", "-format", "json"}).Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var funcs []gocov.UncoveredFunc
+		if err := json.Unmarshal(buf.Bytes(), &funcs); err != nil {
+			t.Fatalf("Failed to parse JSON: %v\nGot: %s", err, buf.String())
+		}
+		if len(funcs) != 1 || funcs[0].Name != "Unused" || funcs[0].Line != 7 {
+			t.Errorf("Unexpected JSON: %+v", funcs)
+		}
+	})
+
+	t.Run("single output for diff mode and uncovered functions", func(t *testing.T) {
+		report := filepath.Join(t.TempDir(), "report.json")
+		for _, args := range [][]string{
+			{"-uncovered-funcs", "-format", "json", "-format", "table"},
+			{"-uncovered-funcs", "-format", "json:" + report},
+			{"-diff-file", "testdata/coverage.out", "-format", "json:" + report, "-format", "table"},
+		} {
+			err := NewCLI(io.Discard, append([]string{"-coverprofile", "testdata/coverage.out"}, args...)).Run()
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Field != "format" {
+				t.Errorf("%v: error = %v, want ValidationError for format", args, err)
+			}
+		}
+		if _, err := os.Stat(report); !os.IsNotExist(err) {
+			t.Errorf("No output file should be written, stat error = %v", err)
+		}
+	})
+
+	t.Run("column width", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-width", "24"})
+
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), "\ngithub.com/example/pr...          7          6    85.7%\n") {
+			t.Errorf("Expected directories truncated to 24 columns\nGot: %s", buf.String())
+		}
+
+		buf.Reset()
+		if err := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-format", "tree", "-width", "20"}).Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), "\ngithub.com/exampl...         21         16    76.2%\n") {
+			t.Errorf("Expected tree directories truncated to 20 columns\nGot: %s", buf.String())
+		}
+
+		err := NewCLI(io.Discard, []string{"-coverprofile", "testdata/coverage.out", "-width", "5"}).Run()
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("Expected ValidationError for a too narrow width, got: %v", err)
+		}
+	})
+
+	t.Run("TSV with header", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-format", "tsv", "-header"})
+
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if len(lines) != 5 || lines[0] != "directory\tstatements\tcovered\tcoverage" || lines[4] != "TOTAL\t21\t16\t76.2" {
+			t.Errorf("Unexpected TSV output\nGot: %s", buf.String())
+		}
+	})
+
+	t.Run("source root ignore directives", func(t *testing.T) {
+		root := t.TempDir()
+		files := map[string]string{
+			"go.mod":           "module example.com/m\n",
+			"server/server.go": "package server\n\nfunc run(err error) {\n\tif err != nil { //gocov:ignore\n\t\tpanic(err)\n\t}\n}\n",
+			"coverage.out":     "mode: set\nexample.com/m/server/server.go:3.22,4.16 1 1\nexample.com/m/server/server.go:4.16,6.3 1 0\n",
+		}
+		for name, content := range files {
+			path := filepath.Join(root, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("Failed to create directory: %v", err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", name, err)
+			}
+		}
+		profile := filepath.Join(root, "coverage.out")
+
+		var buf bytes.Buffer
+		if err := NewCLI(&buf, []string{"-coverprofile", profile, "-format", "summary"}).Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := "coverage: 50.0% (1/2 statements)\n"; buf.String() != want {
+			t.Errorf("Without -source-root the directive should be ignored\nGot: %q, want %q", buf.String(), want)
+		}
+
+		buf.Reset()
+		if err := NewCLI(&buf, []string{"-coverprofile", profile, "-format", "summary", "-source-root", root}).Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := "coverage: 100.0% (1/1 statements)\n"; buf.String() != want {
+			t.Errorf("Output = %q, want %q", buf.String(), want)
+		}
+	})
+
+	t.Run("ignore file", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-format", "summary", "-ignore-file", "main.go"}).Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		// cmd/server/main.go holds 5 statements, 4 of them covered
+		if want := "coverage: 75.0% (12/16 statements)\n"; buf.String() != want {
+			t.Errorf("Output = %q, want %q", buf.String(), want)
+		}
+	})
+
+	t.Run("excluded directories in JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-format", "json", "-min", "75"}).Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var report gocov.JSONReport
+		if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v", err)
+		}
+
+		statements := 0
+		for _, rows := range [][]gocov.CoverageResult{report.Results, report.Excluded} {
+			for _, row := range rows {
+				statements += row.Statements
+			}
+		}
+		if len(report.Excluded) == 0 || statements != report.Total.Statements {
+			t.Errorf("results and excluded should together cover the total\nGot: %s", buf.String())
+		}
+		for _, row := range report.Excluded {
+			if row.Coverage >= 75 {
+				t.Errorf("%s passes the filter but is listed as excluded", row.Directory)
+			}
+		}
+	})
+
+	t.Run("round", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-format", "summary", "-round", "2"}).Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := "coverage: 76.19% (16/21 statements)\n"; buf.String() != want {
+			t.Errorf("Output = %q, want %q", buf.String(), want)
+		}
+
+		// 76.19% is shown as 76.2% by default, so it passes a threshold of 76.2 unless more decimals are kept
+		if err := NewCLI(io.Discard, []string{"-coverprofile", "testdata/coverage.out", "-threshold", "76.2"}).Run(); err != nil {
+			t.Errorf("Expected 76.2%% to pass a threshold of 76.2, got: %v", err)
+		}
+		err := NewCLI(io.Discard, []string{"-coverprofile", "testdata/coverage.out", "-round", "2", "-threshold", "76.2"}).Run()
+		var thresholdErr *ThresholdError
+		if !errors.As(err, &thresholdErr) || !strings.Contains(err.Error(), "coverage 76.19% is below") {
+			t.Errorf("Expected a ThresholdError showing 76.19%%, got: %v", err)
+		}
+
+		// -threshold-precision -1 compares the unrounded 76.190476...% while still displaying 76.2%
+		buf.Reset()
+		err = NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-quiet", "-threshold-precision", "-1", "-threshold", "76.2"}).Run()
+		if !errors.As(err, &thresholdErr) || !strings.Contains(err.Error(), "coverage 76.19047619047619% is below") {
+			t.Errorf("Expected a ThresholdError with the unrounded coverage, got: %v", err)
+		}
+		if !strings.Contains(buf.String(), "76.2%") {
+			t.Errorf("Display should keep -round\nGot: %s", buf.String())
+		}
+	})
+
+	t.Run("tree format", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-format", "tree"}).Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, want := range []string{
+			"\ngithub.com/example/project                                 21         16    76.2%\n",
+			"\n  internal/service                                          7          6    85.7%\n",
+		} {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("Output should contain %q\nGot: %s", want, buf.String())
+			}
+		}
+	})
+
+	t.Run("multiple formats", func(t *testing.T) {
+		jsonPath := filepath.Join(t.TempDir(), "coverage.json")
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-format", "table", "-format", "json:" + jsonPath})
+
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), "TOTAL") || strings.Contains(buf.String(), `"results"`) {
+			t.Errorf("Stdout should only contain the table\nGot: %s", buf.String())
+		}
+
+		data, err := os.ReadFile(jsonPath)
+		if err != nil {
+			t.Fatalf("Failed to read JSON output file: %v", err)
+		}
+		var report gocov.JSONReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			t.Fatalf("Failed to parse JSON output file: %v", err)
+		}
+		if report.Total.Statements == 0 {
+			t.Error("Expected total statements in JSON output file")
+		}
+	})
+
+	t.Run("unwritable format destination", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "missing", "coverage.json")
+		cli := NewCLI(&bytes.Buffer{}, []string{"-coverprofile", "testdata/coverage.out", "-format", "json:" + path})
+		if err := cli.Run(); err == nil {
+			t.Error("Expected error for an output file in a missing directory")
+		}
+	})
+
 	t.Run("with coverage filters", func(t *testing.T) {
 		var buf bytes.Buffer
 		cli := NewCLI(&buf, []string{
@@ -120,21 +608,276 @@ func TestCLIRun(t *testing.T) {
 		}
 	})
 
-	t.Run("with ignore patterns", func(t *testing.T) {
+	t.Run("below and above shorthands", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-below", "80"}).Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if strings.Contains(buf.String(), "internal/service") || !strings.Contains(buf.String(), "pkg/util") {
+			t.Errorf("-below 80 should only show directories under 80%%\nGot: %s", buf.String())
+		}
+
+		buf.Reset()
+		if err := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-above", "80", "-below", "90"}).Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), "internal/service") || strings.Contains(buf.String(), "pkg/util") {
+			t.Errorf("-above 80 -below 90 should only show directories between 80%% and 90%%\nGot: %s", buf.String())
+		}
+
+		// Agreeing values are accepted, conflicting ones are not
+		if err := NewCLI(io.Discard, []string{"-coverprofile", "testdata/coverage.out", "-below", "80", "-max", "80"}).Run(); err != nil {
+			t.Errorf("Unexpected error for matching -below and -max: %v", err)
+		}
+		err := NewCLI(io.Discard, []string{"-coverprofile", "testdata/coverage.out", "-below", "80", "-max", "90"}).Run()
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("Expected ValidationError for conflicting -below and -max, got: %v", err)
+		}
+	})
+
+	t.Run("with ignore patterns", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{
+			"-coverprofile", "testdata/coverage.out",
+			"-ignore", "*/internal/*,*/vendor/*",
+		})
+
+		err := cli.Run()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		output := buf.String()
+		if strings.Contains(output, "internal/service") {
+			t.Error("Output should not contain ignored directories")
+		}
+	})
+
+	t.Run("with verbose flag", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{
+			"-coverprofile", "testdata/coverage.out",
+			"-verbose",
+		})
+
+		err := cli.Run()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		output := buf.String()
+		if !strings.Contains(output, "Uncovered lines in github.com/example/project/pkg/util/helper.go: 16-18") {
+			t.Errorf("Output should contain uncovered line ranges in verbose mode\nGot: %s", output)
+		}
+		if !strings.Contains(output, "Coverage mode: set") {
+			t.Errorf("Output should contain the coverage mode in verbose mode\nGot: %s", output)
+		}
+	})
+
+	t.Run("with trim-prefix flag", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{
+			"-coverprofile", "testdata/coverage.out",
+			"-trim-prefix", "github.com/example/project",
+		})
+
+		err := cli.Run()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		output := buf.String()
+		if strings.Contains(output, "github.com/example/project") {
+			t.Errorf("Output should not contain the trimmed prefix\nGot: %s", output)
+		}
+		if !strings.Contains(output, "pkg/util") {
+			t.Errorf("Output should contain module-relative directories\nGot: %s", output)
+		}
+	})
+
+	t.Run("with stats flag", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{
+			"-coverprofile", "testdata/coverage.out",
+			"-stats",
+		})
+
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		output := buf.String()
+		if !strings.HasSuffix(output, "analyzed 6 files across 3 directories\n") {
+			t.Errorf("Output should end with the stats line\nGot: %s", output)
+		}
+	})
+
+	t.Run("with histogram flag", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{
+			"-coverprofile", "testdata/coverage.out",
+			"-histogram",
+			"-histogram-bands", "72,90",
+		})
+
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		output := buf.String()
+		want := "Coverage histogram:\n  0-72%      2 dirs\n  72-90%     1 dirs\n  90-100%    0 dirs\n"
+		if !strings.HasSuffix(output, want) {
+			t.Errorf("Output should end with the histogram\nGot: %s", output)
+		}
+	})
+
+	t.Run("with stats flag and json format", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{
+			"-coverprofile", "testdata/coverage.out",
+			"-format", "json",
+			"-stats",
+		})
+
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var result struct {
+			Stats *gocov.Stats `json:"stats"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v", err)
+		}
+		if result.Stats == nil || *result.Stats != (gocov.Stats{Files: 6, Directories: 3}) {
+			t.Errorf("Unexpected stats: %+v", result.Stats)
+		}
+	})
+
+	t.Run("with quiet flag", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{
+			"-coverprofile", "testdata/coverage.out",
+			"-quiet",
+		})
+
+		err := cli.Run()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		output := buf.String()
+		if strings.Contains(output, "Directory") || strings.Contains(output, "pkg/util") {
+			t.Errorf("Quiet output should not contain per-directory results\nGot: %s", output)
+		}
+		if !strings.Contains(output, "TOTAL") {
+			t.Errorf("Quiet output should contain 'TOTAL' line\nGot: %s", output)
+		}
+	})
+
+	t.Run("quiet flag still checks threshold", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{
+			"-coverprofile", "testdata/coverage.out",
+			"-quiet",
+			"-threshold", "90",
+		})
+
+		err := cli.Run()
+		var thresholdErr *ThresholdError
+		if !errors.As(err, &thresholdErr) {
+			t.Errorf("Expected ThresholdError, got: %v", err)
+		}
+	})
+
+	t.Run("with weighted flag", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{
+			"-coverprofile", "testdata/coverage.out",
+			"-format", "json",
+			"-weighted",
+		})
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var result struct {
+			Results []gocov.CoverageResult `json:"results"`
+			Total   gocov.CoverageResult   `json:"total"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v", err)
+		}
+
+		// In set mode every covered block counts once: testdata/coverage.out has 11 covered blocks
+		if result.Total.Executions == nil || *result.Total.Executions != 11 {
+			t.Errorf("Expected 11 total executions, got %v", result.Total.Executions)
+		}
+		if result.Total.Hits != nil {
+			t.Error("Hits should stay hidden without -show-hits")
+		}
+		for _, r := range result.Results {
+			if r.Executions == nil {
+				t.Errorf("Expected executions for %s", r.Directory)
+			}
+		}
+	})
+
+	t.Run("with show-hits flag", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{
+			"-coverprofile", "testdata/coverage.out",
+			"-format", "json",
+			"-show-hits",
+		})
+
+		err := cli.Run()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var result struct {
+			Results []gocov.CoverageResult `json:"results"`
+			Total   gocov.CoverageResult   `json:"total"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v", err)
+		}
+
+		// In set mode hits equal covered statements
+		if result.Total.Hits == nil || *result.Total.Hits != result.Total.Covered {
+			t.Errorf("Expected total hits to equal covered statements (%d), got %v", result.Total.Covered, result.Total.Hits)
+		}
+		for _, r := range result.Results {
+			if r.Hits == nil {
+				t.Errorf("Expected hits for %s", r.Directory)
+			}
+		}
+	})
+
+	t.Run("with lines flag", func(t *testing.T) {
 		var buf bytes.Buffer
-		cli := NewCLI(&buf, []string{
-			"-coverprofile", "testdata/coverage.out",
-			"-ignore", "*/internal/*,*/vendor/*",
-		})
+		cli := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-format", "json", "-lines"})
 
-		err := cli.Run()
-		if err != nil {
+		if err := cli.Run(); err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
 
-		output := buf.String()
-		if strings.Contains(output, "internal/service") {
-			t.Error("Output should not contain ignored directories")
+		var report gocov.JSONReport
+		if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v", err)
+		}
+		if report.Total.LineCount == nil || report.Total.LineCovered == nil {
+			t.Fatalf("Expected line metrics in total, got %+v", report.Total)
+		}
+		if *report.Total.LineCovered > *report.Total.LineCount || *report.Total.LineCount == 0 {
+			t.Errorf("Unexpected line metrics: %d/%d", *report.Total.LineCovered, *report.Total.LineCount)
+		}
+		for _, r := range report.Results {
+			if r.LineCount == nil {
+				t.Errorf("Expected line metrics for %s", r.Directory)
+			}
 		}
 	})
 
@@ -189,6 +932,20 @@ func TestCLIRun(t *testing.T) {
 		}
 	})
 
+	t.Run("invalid workers", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{
+			"-coverprofile", "testdata/coverage.out",
+			"-workers", "-2",
+		})
+
+		err := cli.Run()
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("Expected ValidationError, got: %v", err)
+		}
+	})
+
 	t.Run("min greater than max", func(t *testing.T) {
 		var buf bytes.Buffer
 		cli := NewCLI(&buf, []string{
@@ -208,6 +965,318 @@ func TestCLIRun(t *testing.T) {
 	})
 }
 
+func TestCLIAllowMissing(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.out")
+
+	t.Run("missing profile fails by default", func(t *testing.T) {
+		err := NewCLI(io.Discard, []string{"-coverprofile", missing}).Run()
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("Expected ParseError, got: %v", err)
+		}
+	})
+
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("missing profile passes with allow-missing (stream %v)", stream), func(t *testing.T) {
+			var buf, errBuf bytes.Buffer
+			cli := NewCLI(&buf, []string{"-coverprofile", missing, "-allow-missing", fmt.Sprintf("-stream=%v", stream)})
+			cli.ErrOutput = &errBuf
+			if err := cli.Run(); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("Expected no report, got: %s", buf.String())
+			}
+			if !strings.Contains(errBuf.String(), "notice: skipping coverage report") || !strings.Contains(errBuf.String(), missing) {
+				t.Errorf("Expected a notice naming the profile, got: %q", errBuf.String())
+			}
+		})
+	}
+
+	t.Run("corrupt profile still fails with allow-missing", func(t *testing.T) {
+		corrupt := filepath.Join(t.TempDir(), "corrupt.out")
+		if err := os.WriteFile(corrupt, []byte("not a coverage profile\n"), 0644); err != nil {
+			t.Fatalf("Failed to write coverage file: %v", err)
+		}
+		err := NewCLI(io.Discard, []string{"-coverprofile", corrupt, "-allow-missing"}).Run()
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("Expected ParseError, got: %v", err)
+		}
+	})
+}
+
+func TestCLIFailOnEmpty(t *testing.T) {
+	emptyProfile := filepath.Join(t.TempDir(), "empty.out")
+	if err := os.WriteFile(emptyProfile, []byte("mode: set\n"), 0644); err != nil {
+		t.Fatalf("Failed to write coverage file: %v", err)
+	}
+
+	t.Run("empty profile passes by default", func(t *testing.T) {
+		cli := NewCLI(io.Discard, []string{"-coverprofile", emptyProfile})
+		if err := cli.Run(); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("empty profile fails with fail-on-empty", func(t *testing.T) {
+		cli := NewCLI(io.Discard, []string{"-coverprofile", emptyProfile, "-fail-on-empty"})
+		err := cli.Run()
+		if !errors.Is(err, ErrEmptyProfile) {
+			t.Errorf("Expected ErrEmptyProfile, got: %v", err)
+		}
+	})
+
+	t.Run("all directories ignored fails with fail-on-empty", func(t *testing.T) {
+		cli := NewCLI(io.Discard, []string{"-coverprofile", "testdata/coverage.out", "-ignore", "*", "-fail-on-empty"})
+		err := cli.Run()
+		if !errors.Is(err, ErrEmptyProfile) {
+			t.Errorf("Expected ErrEmptyProfile, got: %v", err)
+		}
+	})
+
+	t.Run("non-empty profile passes with fail-on-empty", func(t *testing.T) {
+		cli := NewCLI(io.Discard, []string{"-coverprofile", "testdata/coverage.out", "-fail-on-empty"})
+		if err := cli.Run(); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+}
+
+func TestCLIValidateOnly(t *testing.T) {
+	dir := t.TempDir()
+	emptyProfile := filepath.Join(dir, "empty.out")
+	if err := os.WriteFile(emptyProfile, []byte("mode: set\n"), 0644); err != nil {
+		t.Fatalf("Failed to write coverage file: %v", err)
+	}
+	malformedProfile := filepath.Join(dir, "malformed.out")
+	malformed := "mode: set\na.go:5.1,3.2 1 1\na.go:6.1,7.2 1 3\nb.go:1.1,2.1 1 0\n"
+	if err := os.WriteFile(malformedProfile, []byte(malformed), 0644); err != nil {
+		t.Fatalf("Failed to write coverage file: %v", err)
+	}
+	truncatedProfile := filepath.Join(dir, "truncated.out")
+	if err := os.WriteFile(truncatedProfile, []byte("mode: set\na.go:1.1,2.1 1 1\na.go:3.1,4"), 0644); err != nil {
+		t.Fatalf("Failed to write coverage file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "valid profile",
+			args: []string{"-coverprofile", "testdata/coverage.out"},
+			want: "testdata/coverage.out: 6 profiles, 16 blocks, mode set\n",
+		},
+		{
+			name: "empty profile",
+			args: []string{"-coverprofile", emptyProfile},
+			want: emptyProfile + ": 0 profiles, 0 blocks\n",
+		},
+		{
+			name: "malformed blocks",
+			args: []string{"-coverprofile", malformedProfile},
+			want: "a.go:5.1,3.2: block ends before it starts\n" +
+				"a.go:6.1,7.2: count 3 in set mode\n" +
+				malformedProfile + ": 2 profiles, 3 blocks, mode set, 2 malformed\n",
+			wantErr: ErrMalformedProfile,
+		},
+		{
+			name:    "stream conflict",
+			args:    []string{"-coverprofile", "testdata/coverage.out", "-stream"},
+			wantErr: ErrStreamConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := NewCLI(&buf, append(tt.args, "-validate-only")).Run()
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.want != "" && buf.String() != tt.want {
+				t.Errorf("output = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+
+	t.Run("truncated profile", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := NewCLI(&buf, []string{"-coverprofile", truncatedProfile, "-validate-only"}).Run()
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || parseErr.File != truncatedProfile {
+			t.Errorf("error = %v, want ParseError for %s", err, truncatedProfile)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("output = %q, want none", buf.String())
+		}
+	})
+}
+
+func TestCLIAssumeMode(t *testing.T) {
+	data, err := os.ReadFile("testdata/coverage.out")
+	if err != nil {
+		t.Fatalf("Failed to read coverage file: %v", err)
+	}
+	headerless := filepath.Join(t.TempDir(), "headerless.out")
+	body := strings.TrimPrefix(string(data), "mode: set\n")
+	if err := os.WriteFile(headerless, []byte(body), 0644); err != nil {
+		t.Fatalf("Failed to write coverage file: %v", err)
+	}
+
+	var want bytes.Buffer
+	if err := NewCLI(&want, []string{"-coverprofile", "testdata/coverage.out", "-format", "summary"}).Run(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("header required by default", func(t *testing.T) {
+		err := NewCLI(io.Discard, []string{"-coverprofile", headerless}).Run()
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("error = %v, want ParseError", err)
+		}
+	})
+
+	for _, extra := range [][]string{nil, {"-stream"}} {
+		t.Run(fmt.Sprintf("headerless profile with %v", extra), func(t *testing.T) {
+			var buf bytes.Buffer
+			args := append([]string{"-coverprofile", headerless, "-format", "summary", "-assume-mode", "set"}, extra...)
+			if err := NewCLI(&buf, args).Run(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if buf.String() != want.String() {
+				t.Errorf("output = %q, want %q", buf.String(), want.String())
+			}
+		})
+	}
+
+	t.Run("existing header wins", func(t *testing.T) {
+		var buf bytes.Buffer
+		args := []string{"-coverprofile", "testdata/coverage.out", "-assume-mode", "atomic", "-validate-only"}
+		if err := NewCLI(&buf, args).Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.HasSuffix(buf.String(), "mode set\n") {
+			t.Errorf("output = %q, want mode set", buf.String())
+		}
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		err := NewCLI(io.Discard, []string{"-coverprofile", headerless, "-assume-mode", "func"}).Run()
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("error = %v, want ValidationError", err)
+		}
+	})
+}
+
+func TestCLIInit(t *testing.T) {
+	tempDir := t.TempDir()
+
+	originalWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer os.Chdir(originalWd)
+
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	t.Run("creates config file", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{"init"})
+
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), ".gocov.yml") {
+			t.Errorf("Output should mention the written path\nGot: %s", buf.String())
+		}
+		if _, err := os.Stat(".gocov.yml"); err != nil {
+			t.Errorf("Expected .gocov.yml to be created: %v", err)
+		}
+	})
+
+	t.Run("refuses to overwrite", func(t *testing.T) {
+		cli := NewCLI(io.Discard, []string{"init"})
+
+		err := cli.Run()
+		if !errors.Is(err, ErrConfigExists) {
+			t.Errorf("Expected ErrConfigExists, got: %v", err)
+		}
+	})
+
+	t.Run("overwrites with force", func(t *testing.T) {
+		cli := NewCLI(io.Discard, []string{"init", "-force"})
+
+		if err := cli.Run(); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+}
+
+func TestCLICheckConfig(t *testing.T) {
+	t.Run("valid config", func(t *testing.T) {
+		configFile := filepath.Join(t.TempDir(), ".gocov.yml")
+		if err := os.WriteFile(configFile, []byte(GenerateDefaultConfig()), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		var buf bytes.Buffer
+		cli := NewCLI(&buf, []string{"-check-config", "-config", configFile})
+		if err := cli.Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), "is valid") {
+			t.Errorf("Output should report success\nGot: %s", buf.String())
+		}
+	})
+
+	t.Run("invalid threshold", func(t *testing.T) {
+		configFile := filepath.Join(t.TempDir(), ".gocov.yml")
+		if err := os.WriteFile(configFile, []byte("format: table\nthreshold: 120\n"), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		cli := NewCLI(io.Discard, []string{"-check-config", "-config", configFile})
+		err := cli.Run()
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected ValidationError, got: %v", err)
+		}
+		if validationErr.Field != "threshold" {
+			t.Errorf("Expected threshold field error, got %s", validationErr.Field)
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		configFile := filepath.Join(t.TempDir(), ".gocov.yml")
+		if err := os.WriteFile(configFile, []byte("format: xml\n"), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		cli := NewCLI(io.Discard, []string{"-check-config", "-config", configFile})
+		var validationErr *ValidationError
+		if err := cli.Run(); !errors.As(err, &validationErr) {
+			t.Errorf("Expected ValidationError, got: %v", err)
+		}
+	})
+
+	t.Run("missing config file", func(t *testing.T) {
+		cli := NewCLI(io.Discard, []string{"-check-config", "-config", filepath.Join(t.TempDir(), "missing.yml")})
+		if err := cli.Run(); !errors.Is(err, ErrConfigNotFound) {
+			t.Errorf("Expected ErrConfigNotFound, got: %v", err)
+		}
+	})
+}
+
 func TestCLILoadConfiguration(t *testing.T) {
 	t.Run("load config file", func(t *testing.T) {
 		cli := NewCLI(io.Discard, []string{})
@@ -237,6 +1306,32 @@ func TestCLILoadConfiguration(t *testing.T) {
 		}
 	})
 
+	t.Run("environment overrides config file", func(t *testing.T) {
+		configFile := filepath.Join(t.TempDir(), ".gocov.yml")
+		if err := os.WriteFile(configFile, []byte("format: table\nlevel: 2\nthreshold: 50\n"), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		t.Setenv("GOCOV_THRESHOLD", "80")
+		t.Setenv("GOCOV_IGNORE", "*/env/*")
+
+		cli := NewCLI(io.Discard, []string{})
+		config, err := cli.loadConfiguration(configFile, "*/flag/*")
+		if err != nil {
+			t.Fatalf("Failed to load configuration: %v", err)
+		}
+
+		if config.Level != 2 {
+			t.Errorf("Expected level from config file, got %d", config.Level)
+		}
+		if config.Threshold != 80 {
+			t.Errorf("Expected threshold from environment, got %v", config.Threshold)
+		}
+		// Command line flags win over environment variables
+		if len(config.Ignore) != 1 || config.Ignore[0] != "*/flag/*" {
+			t.Errorf("Expected ignore patterns from command line, got %v", config.Ignore)
+		}
+	})
+
 	t.Run("ignore patterns from command line", func(t *testing.T) {
 		cli := NewCLI(io.Discard, []string{})
 		config, err := cli.loadConfiguration("", "*/test/*, */vendor/*")
@@ -256,8 +1351,91 @@ func TestCLILoadConfiguration(t *testing.T) {
 	})
 }
 
+func TestCLILoadConfigurationIgnoreComments(t *testing.T) {
+	t.Run("comment-only entry from command line", func(t *testing.T) {
+		cli := NewCLI(io.Discard, []string{})
+		config, err := cli.loadConfiguration("", "# nothing ignored")
+		if err != nil {
+			t.Fatalf("Failed to load configuration: %v", err)
+		}
+		if len(config.Ignore) != 0 {
+			t.Errorf("Expected no ignore patterns, got %q", config.Ignore)
+		}
+	})
+
+	t.Run("mixed entries from command line", func(t *testing.T) {
+		cli := NewCLI(io.Discard, []string{})
+		config, err := cli.loadConfiguration("", "*/vendor/*, # third party, , */gen/* # generated")
+		if err != nil {
+			t.Fatalf("Failed to load configuration: %v", err)
+		}
+		want := []string{"*/vendor/*", "*/gen/*"}
+		if !reflect.DeepEqual(config.Ignore, want) {
+			t.Errorf("Expected ignore patterns %q, got %q", want, config.Ignore)
+		}
+	})
+
+	t.Run("inline comments in YAML", func(t *testing.T) {
+		configFile := filepath.Join(t.TempDir(), ".gocov.yml")
+		content := `format: table
+ignore:
+  - "*/vendor/*" # third party
+  - "*/gen/* # generated"
+  - "# disabled"
+`
+		if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		cli := NewCLI(io.Discard, []string{})
+		config, err := cli.loadConfiguration(configFile, "")
+		if err != nil {
+			t.Fatalf("Failed to load configuration: %v", err)
+		}
+		want := []string{"*/vendor/*", "*/gen/*"}
+		if !reflect.DeepEqual(config.Ignore, want) {
+			t.Errorf("Expected ignore patterns %q, got %q", want, config.Ignore)
+		}
+	})
+}
+
+func TestCLILoadConfigurationIgnoreFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, IgnoreFileName), []byte("# generated code\n*/gen/*\n*/mocks/*\n"), 0644); err != nil {
+		t.Fatalf("Failed to write ignore file: %v", err)
+	}
+	configFile := filepath.Join(dir, ".gocov.yml")
+	if err := os.WriteFile(configFile, []byte("format: table\nignore:\n  - \"*/vendor/*\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Chdir(dir)
+
+	tests := []struct {
+		name       string
+		configFile string
+		ignoreDirs string
+		want       []string
+	}{
+		{"ignore file only", filepath.Join(dir, "missing.yml"), "", []string{"*/gen/*", "*/mocks/*"}},
+		{"with configuration", configFile, "", []string{"*/vendor/*", "*/gen/*", "*/mocks/*"}},
+		{"with command line", configFile, "*/internal/*", []string{"*/internal/*", "*/gen/*", "*/mocks/*"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewCLI(io.Discard, nil).loadConfiguration(tt.configFile, tt.ignoreDirs)
+			if err != nil {
+				t.Fatalf("Failed to load configuration: %v", err)
+			}
+			if !reflect.DeepEqual(config.Ignore, tt.want) {
+				t.Errorf("Expected ignore patterns %q, got %q", tt.want, config.Ignore)
+			}
+		})
+	}
+}
+
 func TestCLIDisplayResults(t *testing.T) {
-	coverageByDir := map[string]*DirCoverage{
+	coverageByDir := map[string]*gocov.DirCoverage{
 		"pkg/util": {
 			Dir:         "pkg/util",
 			StmtCount:   10,
@@ -280,9 +1458,9 @@ func TestCLIDisplayResults(t *testing.T) {
 		r, w, _ := os.Pipe()
 		os.Stdout = w
 
-		formatter := &TableFormatter{writer: w}
+		formatter := gocov.NewTableFormatter(w, false)
 		cli := &CLI{Output: w}
-		_, err := cli.displayResults(coverageByDir, 0.0, 100.0, formatter)
+		_, err := cli.displayResults(coverageByDir, 0.0, 100.0, 0, 0, false, false, false, nil, formatter)
 		if err != nil {
 			t.Fatalf("displayResults failed: %v", err)
 		}
@@ -333,9 +1511,9 @@ func TestCLIDisplayResults(t *testing.T) {
 		r, w, _ := os.Pipe()
 		os.Stdout = w
 
-		formatter := &TableFormatter{writer: w}
+		formatter := gocov.NewTableFormatter(w, false)
 		cli := &CLI{Output: w}
-		_, err := cli.displayResults(coverageByDir, 50.0, 100.0, formatter)
+		_, err := cli.displayResults(coverageByDir, 50.0, 100.0, 0, 0, false, false, false, nil, formatter)
 		if err != nil {
 			t.Fatalf("displayResults failed: %v", err)
 		}
@@ -371,9 +1549,9 @@ func TestCLIDisplayResults(t *testing.T) {
 		r, w, _ := os.Pipe()
 		os.Stdout = w
 
-		formatter := &TableFormatter{writer: w}
+		formatter := gocov.NewTableFormatter(w, false)
 		cli := &CLI{Output: w}
-		_, err := cli.displayResults(coverageByDir, 0.0, 60.0, formatter)
+		_, err := cli.displayResults(coverageByDir, 0.0, 60.0, 0, 0, false, false, false, nil, formatter)
 		if err != nil {
 			t.Fatalf("displayResults failed: %v", err)
 		}
@@ -409,9 +1587,9 @@ func TestCLIDisplayResults(t *testing.T) {
 		r, w, _ := os.Pipe()
 		os.Stdout = w
 
-		formatter := &TableFormatter{writer: w}
+		formatter := gocov.NewTableFormatter(w, false)
 		cli := &CLI{Output: w}
-		_, err := cli.displayResults(coverageByDir, 40.0, 70.0, formatter)
+		_, err := cli.displayResults(coverageByDir, 40.0, 70.0, 0, 0, false, false, false, nil, formatter)
 		if err != nil {
 			t.Fatalf("displayResults failed: %v", err)
 		}
@@ -441,4 +1619,135 @@ func TestCLIDisplayResults(t *testing.T) {
 			t.Error("Output should contain 'FILTERED TOTAL' line")
 		}
 	})
+
+	t.Run("min statements filter", func(t *testing.T) {
+		var buf bytes.Buffer
+		formatter := gocov.NewTableFormatter(&buf, false)
+		cli := &CLI{Output: &buf}
+		totalCoverage, err := cli.displayResults(coverageByDir, 0.0, 100.0, 15, 0, false, false, false, nil, formatter)
+		if err != nil {
+			t.Fatalf("displayResults failed: %v", err)
+		}
+
+		output := buf.String()
+		if strings.Contains(output, "pkg/util") {
+			t.Error("Output should NOT contain 'pkg/util' (10 statements)")
+		}
+		if !strings.Contains(output, "cmd/server") || !strings.Contains(output, "internal/api") {
+			t.Error("Output should contain directories with at least 15 statements")
+		}
+		if !strings.Contains(output, "FILTERED TOTAL") {
+			t.Error("Output should contain 'FILTERED TOTAL' line")
+		}
+		// TOTAL still covers every directory: 23/45
+		if want := gocov.CalculateCoverage(45, 23); totalCoverage != want {
+			t.Errorf("Total coverage = %.1f%%, want %.1f%%", totalCoverage, want)
+		}
+	})
+
+	t.Run("top directories", func(t *testing.T) {
+		var buf bytes.Buffer
+		formatter := gocov.NewJSONFormatter(&buf, false)
+		cli := &CLI{Output: &buf}
+		if _, err := cli.displayResults(coverageByDir, 0.0, 70.0, 0, 1, false, false, false, nil, formatter); err != nil {
+			t.Fatalf("displayResults failed: %v", err)
+		}
+
+		var report gocov.JSONReport
+		if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v", err)
+		}
+		if len(report.Results) != 1 || report.Results[0].Directory != "internal/api" {
+			t.Errorf("Expected only internal/api (33.3%%), got %+v", report.Results)
+		}
+		// FILTERED TOTAL covers cmd/server and internal/api: 15/35, TOTAL every directory: 23/45
+		if report.FilteredTotal == nil || report.FilteredTotal.Statements != 35 || report.FilteredTotal.Covered != 15 {
+			t.Errorf("Expected filtered total of 15/35 statements, got %+v", report.FilteredTotal)
+		}
+		if report.Total.Statements != 45 || report.Total.Covered != 23 {
+			t.Errorf("Expected total of 23/45 statements, got %+v", report.Total)
+		}
+	})
+
+	t.Run("top sorts worst first", func(t *testing.T) {
+		var buf bytes.Buffer
+		formatter := gocov.NewTSVFormatter(&buf, false)
+		cli := &CLI{Output: &buf}
+		if _, err := cli.displayResults(coverageByDir, 0.0, 100.0, 0, 2, false, false, false, nil, formatter); err != nil {
+			t.Fatalf("displayResults failed: %v", err)
+		}
+		want := "internal/api\t15\t5\t33.3\ncmd/server\t20\t10\t50.0\nTOTAL\t45\t23\t51.1\n"
+		if buf.String() != want {
+			t.Errorf("Unexpected output\nGot: %q\nWant: %q", buf.String(), want)
+		}
+	})
+
+	t.Run("top keeps worst first in JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-format", "json", "-top", "3"}).Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		var report gocov.JSONReport
+		if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v", err)
+		}
+		// internal/service (85.7%) sorts between the two 71.4% directories by name
+		var got []float64
+		for _, result := range report.Results {
+			got = append(got, result.Coverage)
+		}
+		if !sort.Float64sAreSorted(got) || len(got) != 3 {
+			t.Errorf("Expected three directories worst first, got %+v", report.Results)
+		}
+	})
+}
+
+func TestParseFormatOutput(t *testing.T) {
+	tests := []struct {
+		spec string
+		want formatOutput
+	}{
+		{"table", formatOutput{format: "table"}},
+		{"json:coverage.json", formatOutput{format: "json", path: "coverage.json"}},
+		{"html:out/report.html", formatOutput{format: "html", path: "out/report.html"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			if got := parseFormatOutput(tt.spec); got != tt.want {
+				t.Errorf("parseFormatOutput(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevelFlag(t *testing.T) {
+	tests := []struct {
+		value    string
+		want     levelFlag
+		wantErr  bool
+		wantText string
+	}{
+		{value: "3", want: levelFlag{value: 3}, wantText: "3"},
+		{value: "-1", want: levelFlag{value: -1}, wantText: "-1"},
+		{value: "auto", want: levelFlag{auto: true}, wantText: "auto"},
+		{value: "deep", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var got levelFlag
+			err := got.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("levelFlag.Set(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want || got.String() != tt.wantText {
+				t.Errorf("levelFlag.Set(%q) = %+v (%s), want %+v (%s)", tt.value, got, got.String(), tt.want, tt.wantText)
+			}
+		})
+	}
 }
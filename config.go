@@ -2,26 +2,119 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/BurntSushi/toml"
+	"github.com/blck-snwmn/gocov/pkg/gocov"
 	"gopkg.in/yaml.v3"
 )
 
 // Config は設定ファイルの構造を表す
 type Config struct {
-	Level      int            `yaml:"level"`
-	Coverage   CoverageConfig `yaml:"coverage"`
-	Format     string         `yaml:"format"`
-	Ignore     []string       `yaml:"ignore"`
-	Concurrent bool           `yaml:"concurrent"`
-	Threshold  float64        `yaml:"threshold"`
+	Level         int            `yaml:"level" toml:"level"`
+	Coverage      CoverageConfig `yaml:"coverage" toml:"coverage"`
+	Format        string         `yaml:"format" toml:"format"`
+	Ignore        []string       `yaml:"ignore" toml:"ignore"`
+	Include       []string       `yaml:"include" toml:"include"`
+	Concurrent    bool           `yaml:"concurrent" toml:"concurrent"`
+	Threshold     float64        `yaml:"threshold" toml:"threshold"`
+	DiffThreshold float64        `yaml:"diff_threshold" toml:"diff_threshold"`
+	Verbose       bool           `yaml:"verbose" toml:"verbose"`
+	Quiet         bool           `yaml:"quiet" toml:"quiet"`
+	ShowHits      bool           `yaml:"show_hits" toml:"show_hits"`
+	Weighted      bool           `yaml:"weighted" toml:"weighted"`
+	Workers       int            `yaml:"workers" toml:"workers"`
+	FailOnEmpty   bool           `yaml:"fail_on_empty" toml:"fail_on_empty"`
+	AllowMissing  bool           `yaml:"allow_missing" toml:"allow_missing"`
+	MinStatements int            `yaml:"min_statements" toml:"min_statements"`
+	Top           int            `yaml:"top" toml:"top"`
+	ExcludeTests  bool           `yaml:"exclude_tests" toml:"exclude_tests"`
+	TrimPrefix    string         `yaml:"trim_prefix" toml:"trim_prefix"`
+	Stats         bool           `yaml:"stats" toml:"stats"`
+	// IgnoreFiles は集計から除外するファイルのglobパターン（スラッシュを含まなければファイル名、含めばパスの末尾に一致し、**は任意の階層）
+	IgnoreFiles []string `yaml:"ignore_files" toml:"ignore_files"`
+	// DiffBaseBranches は-diff autoでmerge-baseを探すブランチ候補（優先順）
+	DiffBaseBranches []string `yaml:"diff_base_branches" toml:"diff_base_branches"`
+	// DiffFileThreshold はdiffモードで変更ファイルごとに要求する最低カバレッジ
+	DiffFileThreshold float64 `yaml:"diff_file_threshold" toml:"diff_file_threshold"`
+	// DiffInclude はdiffモードで対象にする変更行（"added"は新規行のみ、"modified"は削除行を置き換えた行とDiffIncludeContextの行も含む）
+	DiffInclude string `yaml:"diff_include" toml:"diff_include"`
+	// DiffExtensions はdiffモードで対象にするファイルの拡張子（未指定時は.go）
+	DiffExtensions []string `yaml:"diff_extensions" toml:"diff_extensions"`
+	// DiffStripPrefix はプロファイルと照合する前に変更ファイルのパスから取り除くプレフィックス
+	DiffStripPrefix string `yaml:"diff_strip_prefix" toml:"diff_strip_prefix"`
+	// DiffBaseProfile はdiffのベースで記録したカバレッジプロファイル（ベースで網羅されていた変更行の未網羅化を報告する）
+	DiffBaseProfile string `yaml:"diff_base_profile" toml:"diff_base_profile"`
+	// GitTimeout はdiffモードで実行するgitコマンドのタイムアウト（"30s"のような形式、0はDefaultGitTimeout）
+	GitTimeout time.Duration `yaml:"git_timeout" toml:"git_timeout"`
+	// IgnoreGenerated は"Code generated ... DO NOT EDIT."マーカーを持つ生成ファイルを集計から除外する
+	IgnoreGenerated bool `yaml:"ignore_generated" toml:"ignore_generated"`
+	// JSONCompact はJSON出力をインデントせず1行で出力する
+	JSONCompact bool `yaml:"json_compact" toml:"json_compact"`
+	// Metadata はJSON出力に生成日時と実行コマンドを含める
+	Metadata bool `yaml:"metadata" toml:"metadata"`
+	// Header はTSV出力の先頭にヘッダー行を出力する
+	Header bool `yaml:"header" toml:"header"`
+	// NoTotal はTOTAL行とFILTERED TOTAL行を出力しない（しきい値チェックは行う）
+	NoTotal bool `yaml:"no_total" toml:"no_total"`
+	// SourceThresholds はソースファイル中の//gocov:thresholdコメントをディレクトリごとのしきい値として適用する
+	SourceThresholds bool `yaml:"source_thresholds" toml:"source_thresholds"`
+	// SourceRoot はプロファイルされたソースを読むモジュールのディレクトリ（指定時は//gocov:ignore行のブロックを除外する）
+	SourceRoot string `yaml:"source_root" toml:"source_root"`
+	// Width はテーブル出力のディレクトリ列（diffモードではファイル列）の幅（0は端末幅に合わせる）
+	Width int `yaml:"width" toml:"width"`
+	// ASCII はテーブル出力の合否列を✓/✗ではなくPASS/FAILで表示する
+	ASCII bool `yaml:"ascii" toml:"ascii"`
+	// Color はテーブル出力の合否列に✓/✗を使う条件（"auto"は端末のみ、"always"、"never"はPASS/FAIL）
+	Color string `yaml:"color" toml:"color"`
+	// Round はカバレッジを表示し、しきい値と比較する小数点以下の桁数（未設定はgocov.DefaultPrecision）
+	Round *int `yaml:"round" toml:"round"`
+	// ThresholdPrecision はしきい値と比較する前にカバレッジを丸める小数点以下の桁数（-1は丸めない、未設定はround）
+	ThresholdPrecision *int `yaml:"threshold_precision" toml:"threshold_precision"`
+	// Stream はカバレッジプロファイルを全体を読み込まずに1行ずつ集計する（巨大なプロファイル向け）
+	Stream bool `yaml:"stream" toml:"stream"`
+	// AssumeMode はmode:ヘッダーのないカバレッジプロファイルに仮定するモード（"set"、"count"、"atomic"、空はヘッダー必須）
+	AssumeMode string `yaml:"assume_mode" toml:"assume_mode"`
+	// Lines は行単位のカバレッジも出力する
+	Lines bool `yaml:"lines" toml:"lines"`
+	// DiffSort はdiffモードでのファイルの並び順（"file"または"uncovered"）
+	DiffSort string `yaml:"diff_sort" toml:"diff_sort"`
+	// ThresholdMode は合計カバレッジの判定方法（"absolute"はthreshold、"baseline"はbaselineとの比較）
+	ThresholdMode string `yaml:"threshold_mode" toml:"threshold_mode"`
+	// Baseline はthreshold_modeがbaselineのときに比較する過去のJSONレポートのパス
+	Baseline string `yaml:"baseline" toml:"baseline"`
+	// BaselineTolerance はbaselineから許容するカバレッジの低下幅（パーセントポイント）
+	BaselineTolerance float64 `yaml:"baseline_tolerance" toml:"baseline_tolerance"`
+	// History は実行ごとに合計カバレッジと日時を追記する履歴ファイルのパス（空なら記録しない）
+	History string `yaml:"history" toml:"history"`
+	// HistorySparkline は履歴の直近何回分をスパークラインで表示するか（0で表示しない）
+	HistorySparkline int `yaml:"history_sparkline" toml:"history_sparkline"`
+	// Histogram はカバレッジ帯ごとのディレクトリ数を表の下に出力する
+	Histogram bool `yaml:"histogram" toml:"histogram"`
+	// HistogramBands はヒストグラムの帯の境界（昇順、未指定時は50,80）
+	HistogramBands []float64 `yaml:"histogram_bands" toml:"histogram_bands"`
+	// DiffIncludeContext はdiffモードで変更箇所の前後何行の未変更行を"context"として含めるか（DiffIncludeが"added"の場合は含めない）
+	DiffIncludeContext int `yaml:"diff_include_context" toml:"diff_include_context"`
+	// FailUnderPerDir はすべてのディレクトリに要求する最低カバレッジ（0で無効）
+	FailUnderPerDir float64 `yaml:"fail_under_per_dir" toml:"fail_under_per_dir"`
+	// By は集計の単位（"dir"はプロファイルに書かれたディレクトリ、"package"はGoのインポートパス）
+	By string `yaml:"by" toml:"by"`
+	// Levels はディレクトリのglobパターンごとの集計レベル（最初に一致したルールを使い、なければlevel）
+	// キーはpatternとlevel
+	Levels []gocov.LevelRule `yaml:"levels" toml:"levels"`
+	// Thresholds はディレクトリのglobパターンごとの最低カバレッジ
+	Thresholds map[string]float64 `yaml:"thresholds" toml:"thresholds"`
 }
 
 // CoverageConfig はカバレッジ率フィルタリングの設定
 type CoverageConfig struct {
-	Min float64 `yaml:"min"`
-	Max float64 `yaml:"max"`
+	Min float64 `yaml:"min" toml:"min"`
+	Max float64 `yaml:"max" toml:"max"`
 }
 
 // DefaultConfig はデフォルトの設定を返す
@@ -39,19 +132,43 @@ func DefaultConfig() *Config {
 	}
 }
 
+// configFileNames は探索する設定ファイル名（優先順）
+var configFileNames = []string{".gocov.yml", ".gocov.toml"}
+
 // LoadConfig は設定ファイルを読み込む
+// 拡張子が.tomlの場合はTOML、それ以外はYAMLとして解析する
 // ファイルが存在しない場合はnilを返す
 func LoadConfig(filename string) (*Config, error) {
-	data, err := os.ReadFile(filename)
+	f, err := os.Open(filename)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
+	defer f.Close()
+
+	if strings.ToLower(filepath.Ext(filename)) == ".toml" {
+		return loadConfigFromReader(f, toml.Unmarshal)
+	}
+	return LoadConfigFromReader(f)
+}
+
+// LoadConfigFromReader はrからYAML形式の設定を読み込み、LoadConfigと同じ検証を行う
+// 埋め込みの文字列やファイル以外から設定を与える場合に使用する
+func LoadConfigFromReader(r io.Reader) (*Config, error) {
+	return loadConfigFromReader(r, yaml.Unmarshal)
+}
+
+// loadConfigFromReader はrの内容をunmarshalで解析して検証する
+func loadConfigFromReader(r io.Reader, unmarshal func([]byte, any) error) (*Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
 
 	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	if err := unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
@@ -62,15 +179,33 @@ func LoadConfig(filename string) (*Config, error) {
 	if err := ValidateFormat(config.Format); err != nil {
 		return nil, err
 	}
+	if err := ValidateDirectoryThresholds(config.Thresholds); err != nil {
+		return nil, err
+	}
+	if err := ValidateLevelRules(config.Levels); err != nil {
+		return nil, err
+	}
 
 	return &config, nil
 }
 
+// IgnoreFileName は無視するパターンを.gitignoreのように1行ずつ書くファイルの名前
+const IgnoreFileName = ".gocovignore"
+
 // FindConfigFile は設定ファイルを探す
-// カレントディレクトリから親ディレクトリに向かって.gocov.ymlを探す
+// カレントディレクトリから親ディレクトリに向かって.gocov.ymlまたは.gocov.tomlを探す
 func FindConfigFile() string {
-	configName := ".gocov.yml"
+	return findUpwards(configFileNames)
+}
+
+// FindIgnoreFile は.gocovignoreをFindConfigFileと同じくカレントディレクトリから親ディレクトリに向かって探す
+func FindIgnoreFile() string {
+	return findUpwards([]string{IgnoreFileName})
+}
 
+// findUpwards はカレントディレクトリから親ディレクトリに向かってnamesのいずれかを探し、最初に見つかったパスを返す
+// 同じディレクトリに複数ある場合はnamesの順で優先する
+func findUpwards(names []string) string {
 	// カレントディレクトリから開始
 	dir, err := os.Getwd()
 	if err != nil {
@@ -78,9 +213,11 @@ func FindConfigFile() string {
 	}
 
 	for {
-		configPath := filepath.Join(dir, configName)
-		if _, err := os.Stat(configPath); err == nil {
-			return configPath
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
 		}
 
 		// 親ディレクトリへ
@@ -95,6 +232,199 @@ func FindConfigFile() string {
 	return ""
 }
 
+// defaultConfigTemplate はgocov initで生成する設定ファイルのテンプレート
+const defaultConfigTemplate = `# gocov configuration file
+
+# Directory level for aggregation
+# 0: leaf directories (default)
+# N (N > 0): aggregate by the first N path elements
+# -1: aggregate everything at the top level
+level: %d
+
+# Coverage percentage filter for displayed directories
+coverage:
+  # Minimum coverage to display (0-100)
+  min: %v
+  # Maximum coverage to display (0-100)
+  max: %v
+
+# Output format (table, json, html, summary, tsv, or tree)
+format: %s
+
+# Directory patterns to ignore (wildcards supported)
+# ignore:
+#   - "*/vendor/*"
+#   - "*/test/*"
+
+# Use concurrent processing for large coverage files
+concurrent: %t
+
+# Minimum total coverage required to pass (0 disables the check)
+threshold: %v
+
+# Per-directory minimum coverage keyed by glob pattern
+# thresholds:
+#   "pkg/*": 90
+#   "cmd/*": 50
+`
+
+// GenerateDefaultConfig はDefaultConfigの値でコメント付きの設定ファイル内容を生成する
+func GenerateDefaultConfig() string {
+	config := DefaultConfig()
+	return fmt.Sprintf(defaultConfigTemplate,
+		config.Level,
+		config.Coverage.Min,
+		config.Coverage.Max,
+		config.Format,
+		config.Concurrent,
+		config.Threshold,
+	)
+}
+
+// WriteDefaultConfig はデフォルトの設定ファイルを書き出す
+// forceがfalseの場合、既存のファイルは上書きしない
+func WriteDefaultConfig(filename string, force bool) error {
+	if !force {
+		if _, err := os.Stat(filename); err == nil {
+			return NewConfigError("path", filename, ErrConfigExists)
+		}
+	}
+
+	if err := os.WriteFile(filename, []byte(GenerateDefaultConfig()), 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// ApplyEnvOverrides は環境変数(GOCOV_*)で設定を上書きする
+// 優先順位: コマンドライン引数 > 環境変数 > 設定ファイル > デフォルト
+func (c *Config) ApplyEnvOverrides() error {
+	if v, ok := os.LookupEnv("GOCOV_LEVEL"); ok {
+		level, err := strconv.Atoi(v)
+		if err != nil {
+			return NewConfigError("GOCOV_LEVEL", v, err)
+		}
+		c.Level = level
+	}
+	if v, ok := os.LookupEnv("GOCOV_MIN"); ok {
+		minCov, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return NewConfigError("GOCOV_MIN", v, err)
+		}
+		c.Coverage.Min = minCov
+	}
+	if v, ok := os.LookupEnv("GOCOV_MAX"); ok {
+		maxCov, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return NewConfigError("GOCOV_MAX", v, err)
+		}
+		c.Coverage.Max = maxCov
+	}
+	if v, ok := os.LookupEnv("GOCOV_FORMAT"); ok {
+		c.Format = v
+	}
+	if v, ok := os.LookupEnv("GOCOV_THRESHOLD"); ok {
+		threshold, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return NewConfigError("GOCOV_THRESHOLD", v, err)
+		}
+		c.Threshold = threshold
+	}
+	if v, ok := os.LookupEnv("GOCOV_IGNORE"); ok {
+		c.Ignore = SplitPatterns(v)
+	}
+	return nil
+}
+
+// SplitPatterns はカンマ区切りのパターン文字列を分割する
+func SplitPatterns(s string) []string {
+	patterns := strings.Split(s, ",")
+	for i := range patterns {
+		patterns[i] = strings.TrimSpace(patterns[i])
+	}
+	return patterns
+}
+
+// LoadIgnoreFile は.gocovignore形式のファイルから無視するパターンを読み込む
+// 1行に1パターンで、空行と"#"以降のコメントは無視する
+func LoadIgnoreFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ignore file: %w", err)
+	}
+	return StripPatternComments(strings.Split(string(data), "\n")), nil
+}
+
+// StripPatternComments はパターンから"#"以降のコメントを取り除き、空になったエントリを捨てる
+func StripPatternComments(patterns []string) []string {
+	stripped := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern, _, _ = strings.Cut(pattern, "#")
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			stripped = append(stripped, pattern)
+		}
+	}
+	return stripped
+}
+
+// ParseHistogramBands はカンマ区切りの帯の境界をパースする
+func ParseHistogramBands(s string) ([]float64, error) {
+	var bands []float64
+	for _, part := range SplitPatterns(s) {
+		band, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, NewConfigError("histogram_bands", s, err)
+		}
+		bands = append(bands, band)
+	}
+	return bands, nil
+}
+
+// EffectiveHistogramBands はヒストグラムで使用する帯の境界を返す
+// HistogramBandsが未設定の場合はgocov.DefaultHistogramBandsにフォールバックする
+func (c *Config) EffectiveHistogramBands() []float64 {
+	if len(c.HistogramBands) > 0 {
+		return c.HistogramBands
+	}
+	return gocov.DefaultHistogramBands
+}
+
+// EffectiveRound はカバレッジの表示としきい値の比較に使う小数点以下の桁数を返す
+// Roundが未設定の場合はgocov.DefaultPrecisionにフォールバックする
+func (c *Config) EffectiveRound() int {
+	if c.Round != nil {
+		return *c.Round
+	}
+	return gocov.DefaultPrecision
+}
+
+// EffectiveThresholdPrecision はしきい値の比較に使う小数点以下の桁数を返す（gocov.RawPrecisionは丸めない）
+// ThresholdPrecisionが未設定の場合は表示と同じEffectiveRoundにフォールバックする
+func (c *Config) EffectiveThresholdPrecision() int {
+	if c.ThresholdPrecision != nil {
+		return *c.ThresholdPrecision
+	}
+	return c.EffectiveRound()
+}
+
+// EffectiveGitTimeout はdiffモードのgitコマンドに使用するタイムアウトを返す
+// GitTimeoutが未設定の場合はDefaultGitTimeoutにフォールバックする
+func (c *Config) EffectiveGitTimeout() time.Duration {
+	if c.GitTimeout > 0 {
+		return c.GitTimeout
+	}
+	return DefaultGitTimeout
+}
+
+// EffectiveDiffThreshold はdiffモードで使用する閾値を返す
+// DiffThresholdが未設定の場合はThresholdにフォールバックする
+func (c *Config) EffectiveDiffThreshold() float64 {
+	if c.DiffThreshold > 0 {
+		return c.DiffThreshold
+	}
+	return c.Threshold
+}
+
 // MergeWithFlags はコマンドライン引数で設定を上書きする
 func (c *Config) MergeWithFlags(level *int, minCov, maxCov *float64, format *string, ignorePatterns []string, concurrent *bool, threshold *float64) {
 	if level != nil && *level != 0 {
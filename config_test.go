@@ -2,9 +2,15 @@ package main
 
 import (
 	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/blck-snwmn/gocov/pkg/gocov"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -114,6 +120,114 @@ coverage:
 			t.Error("Expected nil config on error")
 		}
 	})
+
+	t.Run("valid toml config", func(t *testing.T) {
+		tempDir := t.TempDir()
+		configFile := filepath.Join(tempDir, ".gocov.toml")
+
+		configContent := `
+level = 2
+format = "json"
+ignore = ["*/vendor/*", "*/test/*"]
+threshold = 75
+
+[coverage]
+min = 50
+max = 90
+
+[thresholds]
+"pkg/*" = 90
+
+[[levels]]
+pattern = "cmd"
+level = 2
+`
+		if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		config, err := LoadConfig(configFile)
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+
+		if config.Level != 2 {
+			t.Errorf("Expected level to be 2, got %d", config.Level)
+		}
+		if config.Coverage.Min != 50 || config.Coverage.Max != 90 {
+			t.Errorf("Expected coverage 50-90, got %v-%v", config.Coverage.Min, config.Coverage.Max)
+		}
+		if config.Format != "json" {
+			t.Errorf("Expected format to be 'json', got %s", config.Format)
+		}
+		if len(config.Ignore) != 2 {
+			t.Errorf("Expected 2 ignore patterns, got %d", len(config.Ignore))
+		}
+		if config.Threshold != 75 {
+			t.Errorf("Expected threshold to be 75, got %v", config.Threshold)
+		}
+		if config.Thresholds["pkg/*"] != 90 {
+			t.Errorf("Expected pkg/* threshold to be 90, got %v", config.Thresholds)
+		}
+		if !reflect.DeepEqual(config.Levels, []gocov.LevelRule{{Pattern: "cmd", Level: 2}}) {
+			t.Errorf("Expected one level rule for cmd, got %+v", config.Levels)
+		}
+	})
+
+	t.Run("yaml level rules", func(t *testing.T) {
+		configFile := filepath.Join(t.TempDir(), ".gocov.yml")
+		configContent := `format: table
+levels:
+  - pattern: "*/cmd/*"
+    level: 2
+  - pattern: "*/internal/*"
+    level: 4
+`
+		if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		config, err := LoadConfig(configFile)
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		want := []gocov.LevelRule{{Pattern: "*/cmd/*", Level: 2}, {Pattern: "*/internal/*", Level: 4}}
+		if !reflect.DeepEqual(config.Levels, want) {
+			t.Errorf("Expected level rules %+v, got %+v", want, config.Levels)
+		}
+	})
+
+	t.Run("toml unmarshal error", func(t *testing.T) {
+		tempDir := t.TempDir()
+		configFile := filepath.Join(tempDir, ".gocov.toml")
+
+		if err := os.WriteFile(configFile, []byte("level = [this is not valid"), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		config, err := LoadConfig(configFile)
+		if err == nil {
+			t.Error("Expected error for invalid TOML")
+		}
+		if config != nil {
+			t.Error("Expected nil config on error")
+		}
+	})
+
+	t.Run("toml config is validated", func(t *testing.T) {
+		tempDir := t.TempDir()
+		configFile := filepath.Join(tempDir, ".gocov.toml")
+
+		if err := os.WriteFile(configFile, []byte("format = \"xml\""), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		_, err := LoadConfig(configFile)
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("Expected ValidationError, got %v", err)
+		}
+	})
 }
 
 func TestLoadConfigValidation(t *testing.T) {
@@ -167,6 +281,23 @@ func TestLoadConfigValidation(t *testing.T) {
 			configYAML:  `format: xml`,
 			wantErrType: &ValidationError{},
 		},
+		{
+			name: "invalid directory threshold",
+			configYAML: `format: table
+thresholds:
+  "pkg/*": 150
+`,
+			wantErrType: &ValidationError{},
+		},
+		{
+			name: "invalid level rule",
+			configYAML: `format: table
+levels:
+  - pattern: "cmd"
+    level: -2
+`,
+			wantErrType: &ValidationError{},
+		},
 	}
 
 	for _, tt := range tests {
@@ -201,6 +332,32 @@ func TestLoadConfigNonExistent(t *testing.T) {
 	}
 }
 
+func TestLoadConfigFromReader(t *testing.T) {
+	t.Run("valid config", func(t *testing.T) {
+		config, err := LoadConfigFromReader(strings.NewReader("level: 2\nformat: json\nthreshold: 75\n"))
+		if err != nil {
+			t.Fatalf("LoadConfigFromReader() unexpected error: %v", err)
+		}
+		if config.Level != 2 || config.Format != "json" || config.Threshold != 75 {
+			t.Errorf("LoadConfigFromReader() = level %d, format %q, threshold %v, want 2, json, 75", config.Level, config.Format, config.Threshold)
+		}
+	})
+
+	t.Run("validation error", func(t *testing.T) {
+		_, err := LoadConfigFromReader(strings.NewReader("format: xml\n"))
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("LoadConfigFromReader() error = %v, want ValidationError", err)
+		}
+	})
+
+	t.Run("parse error", func(t *testing.T) {
+		if _, err := LoadConfigFromReader(strings.NewReader("level: [not valid\n")); err == nil {
+			t.Error("LoadConfigFromReader() expected error for invalid YAML")
+		}
+	})
+}
+
 func TestMergeWithFlags(t *testing.T) {
 	config := DefaultConfig()
 
@@ -270,6 +427,297 @@ func TestMergeWithFlags(t *testing.T) {
 	}
 }
 
+func TestApplyEnvOverrides(t *testing.T) {
+	t.Run("all variables", func(t *testing.T) {
+		t.Setenv("GOCOV_LEVEL", "3")
+		t.Setenv("GOCOV_MIN", "10")
+		t.Setenv("GOCOV_MAX", "90")
+		t.Setenv("GOCOV_FORMAT", "json")
+		t.Setenv("GOCOV_THRESHOLD", "80")
+		t.Setenv("GOCOV_IGNORE", "*/vendor/*, */mock/*")
+
+		config := DefaultConfig()
+		if err := config.ApplyEnvOverrides(); err != nil {
+			t.Fatalf("ApplyEnvOverrides failed: %v", err)
+		}
+
+		if config.Level != 3 {
+			t.Errorf("Expected level 3, got %d", config.Level)
+		}
+		if config.Coverage.Min != 10 || config.Coverage.Max != 90 {
+			t.Errorf("Expected coverage 10-90, got %v-%v", config.Coverage.Min, config.Coverage.Max)
+		}
+		if config.Format != "json" {
+			t.Errorf("Expected format json, got %s", config.Format)
+		}
+		if config.Threshold != 80 {
+			t.Errorf("Expected threshold 80, got %v", config.Threshold)
+		}
+		if len(config.Ignore) != 2 || config.Ignore[1] != "*/mock/*" {
+			t.Errorf("Expected 2 trimmed ignore patterns, got %v", config.Ignore)
+		}
+	})
+
+	t.Run("unset variables keep config values", func(t *testing.T) {
+		config := &Config{Level: 2, Format: "table", Threshold: 50}
+		if err := config.ApplyEnvOverrides(); err != nil {
+			t.Fatalf("ApplyEnvOverrides failed: %v", err)
+		}
+		if config.Level != 2 || config.Format != "table" || config.Threshold != 50 {
+			t.Errorf("Config should be unchanged, got %+v", config)
+		}
+	})
+
+	t.Run("invalid number", func(t *testing.T) {
+		t.Setenv("GOCOV_THRESHOLD", "high")
+
+		err := DefaultConfig().ApplyEnvOverrides()
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) {
+			t.Fatalf("Expected ConfigError, got %v", err)
+		}
+		if configErr.Field != "GOCOV_THRESHOLD" {
+			t.Errorf("Expected GOCOV_THRESHOLD field, got %s", configErr.Field)
+		}
+	})
+}
+
+func TestEffectiveDiffThreshold(t *testing.T) {
+	tests := []struct {
+		name          string
+		threshold     float64
+		diffThreshold float64
+		want          float64
+	}{
+		{"diff threshold set", 60, 80, 80},
+		{"fall back to threshold", 60, 0, 60},
+		{"neither set", 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Threshold: tt.threshold, DiffThreshold: tt.diffThreshold}
+			if got := config.EffectiveDiffThreshold(); got != tt.want {
+				t.Errorf("EffectiveDiffThreshold() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitTimeoutConfig(t *testing.T) {
+	config := &Config{}
+	if got := config.EffectiveGitTimeout(); got != DefaultGitTimeout {
+		t.Errorf("EffectiveGitTimeout() = %v, want %v", got, DefaultGitTimeout)
+	}
+
+	for name, content := range map[string]string{
+		".gocov.yml":  "format: table\ngit_timeout: 2m\n",
+		".gocov.toml": "format = \"table\"\ngit_timeout = \"2m\"\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to create test config file: %v", err)
+			}
+			config, err := LoadConfig(configFile)
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+			if got := config.EffectiveGitTimeout(); got != 2*time.Minute {
+				t.Errorf("EffectiveGitTimeout() = %v, want 2m", got)
+			}
+		})
+	}
+}
+
+func TestStripPatternComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+	}{
+		{"no comments", []string{"*/vendor/*", "*/gen/*"}, []string{"*/vendor/*", "*/gen/*"}},
+		{"comment-only entry", []string{"# third party"}, []string{}},
+		{"mixed entries", []string{"*/vendor/*", " # third party", "", "*/gen/* # generated"}, []string{"*/vendor/*", "*/gen/*"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripPatternComments(tt.patterns); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("StripPatternComments(%q) = %q, want %q", tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadIgnoreFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"one pattern per line", "*/vendor/*\n*/gen/*\n", []string{"*/vendor/*", "*/gen/*"}},
+		{"comments and blank lines", "# third party\n*/vendor/*\n\n*/gen/* # generated\n", []string{"*/vendor/*", "*/gen/*"}},
+		{"CRLF line endings", "*/vendor/*\r\n*/gen/*\r\n", []string{"*/vendor/*", "*/gen/*"}},
+		{"empty", "", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), IgnoreFileName)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write ignore file: %v", err)
+			}
+			got, err := LoadIgnoreFile(path)
+			if err != nil {
+				t.Fatalf("LoadIgnoreFile failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadIgnoreFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := LoadIgnoreFile(filepath.Join(t.TempDir(), IgnoreFileName)); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("LoadIgnoreFile() error = %v, want not-exist error", err)
+		}
+	})
+}
+
+func TestFindIgnoreFile(t *testing.T) {
+	tempDir := t.TempDir()
+	subDir := filepath.Join(tempDir, "sub", "directory")
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	ignoreFile := filepath.Join(tempDir, IgnoreFileName)
+	if err := os.WriteFile(ignoreFile, []byte("*/vendor/*\n"), 0644); err != nil {
+		t.Fatalf("Failed to write ignore file: %v", err)
+	}
+
+	t.Chdir(subDir)
+	got := FindIgnoreFile()
+	// The temporary directory may be reached through a symlink, as on macOS
+	if filepath.Base(got) != IgnoreFileName || filepath.Base(filepath.Dir(got)) != filepath.Base(tempDir) {
+		t.Errorf("FindIgnoreFile() = %q, want %q", got, ignoreFile)
+	}
+}
+
+func TestParseHistogramBands(t *testing.T) {
+	bands, err := ParseHistogramBands("50, 80,95.5")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(bands, []float64{50, 80, 95.5}) {
+		t.Errorf("ParseHistogramBands() = %v", bands)
+	}
+
+	if _, err := ParseHistogramBands("50,high"); err == nil {
+		t.Error("Expected an error for a non-numeric band")
+	}
+}
+
+func TestEffectiveHistogramBands(t *testing.T) {
+	config := &Config{}
+	if got := config.EffectiveHistogramBands(); !reflect.DeepEqual(got, gocov.DefaultHistogramBands) {
+		t.Errorf("EffectiveHistogramBands() = %v, want defaults", got)
+	}
+	config.HistogramBands = []float64{60}
+	if got := config.EffectiveHistogramBands(); !reflect.DeepEqual(got, []float64{60}) {
+		t.Errorf("EffectiveHistogramBands() = %v, want [60]", got)
+	}
+}
+
+func TestGenerateDefaultConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, ".gocov.yml")
+	if err := os.WriteFile(configFile, []byte(GenerateDefaultConfig()), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	// The generated file should load back to the default values; ignore is left commented out
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("Generated config should be valid: %v", err)
+	}
+	want := DefaultConfig()
+	want.Ignore = nil
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("Generated config = %+v, want %+v", config, want)
+	}
+
+	t.Run("examples can be uncommented", func(t *testing.T) {
+		var lines []string
+		for _, line := range strings.Split(GenerateDefaultConfig(), "\n") {
+			if strings.HasPrefix(line, "# ignore:") || strings.HasPrefix(line, "# thresholds:") || strings.HasPrefix(line, "#   ") {
+				line = strings.TrimPrefix(line, "# ")
+			}
+			lines = append(lines, line)
+		}
+		uncommented := filepath.Join(tempDir, "uncommented.yml")
+		if err := os.WriteFile(uncommented, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		config, err := LoadConfig(uncommented)
+		if err != nil {
+			t.Fatalf("Uncommented config should be valid: %v", err)
+		}
+		if want := []string{"*/vendor/*", "*/test/*"}; !reflect.DeepEqual(config.Ignore, want) {
+			t.Errorf("Ignore = %v, want %v", config.Ignore, want)
+		}
+		if len(config.Thresholds) != 2 {
+			t.Errorf("Thresholds = %v, want 2 patterns", config.Thresholds)
+		}
+	})
+}
+
+func TestWriteDefaultConfig(t *testing.T) {
+	t.Run("write new file", func(t *testing.T) {
+		configFile := filepath.Join(t.TempDir(), ".gocov.yml")
+		if err := WriteDefaultConfig(configFile, false); err != nil {
+			t.Fatalf("WriteDefaultConfig failed: %v", err)
+		}
+		if _, err := os.Stat(configFile); err != nil {
+			t.Errorf("Expected config file to be created: %v", err)
+		}
+	})
+
+	t.Run("refuse to overwrite", func(t *testing.T) {
+		configFile := filepath.Join(t.TempDir(), ".gocov.yml")
+		if err := os.WriteFile(configFile, []byte("level: 3\n"), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		err := WriteDefaultConfig(configFile, false)
+		if !errors.Is(err, ErrConfigExists) {
+			t.Errorf("Expected ErrConfigExists, got %v", err)
+		}
+
+		data, _ := os.ReadFile(configFile)
+		if string(data) != "level: 3\n" {
+			t.Error("Existing config file should not be modified")
+		}
+	})
+
+	t.Run("overwrite with force", func(t *testing.T) {
+		configFile := filepath.Join(t.TempDir(), ".gocov.yml")
+		if err := os.WriteFile(configFile, []byte("level: 3\n"), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		if err := WriteDefaultConfig(configFile, true); err != nil {
+			t.Fatalf("WriteDefaultConfig failed: %v", err)
+		}
+
+		data, _ := os.ReadFile(configFile)
+		if string(data) != GenerateDefaultConfig() {
+			t.Error("Existing config file should be overwritten")
+		}
+	})
+}
+
 func TestFindConfigFile(t *testing.T) {
 	t.Run("find in parent directory", func(t *testing.T) {
 		// Create a temporary directory structure
@@ -308,6 +756,53 @@ func TestFindConfigFile(t *testing.T) {
 		}
 	})
 
+	t.Run("find toml config", func(t *testing.T) {
+		tempDir := t.TempDir()
+		configFile := filepath.Join(tempDir, ".gocov.toml")
+		if err := os.WriteFile(configFile, []byte("level = 1"), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+
+		originalWd, err := os.Getwd()
+		if err != nil {
+			t.Fatalf("Failed to get current directory: %v", err)
+		}
+		defer os.Chdir(originalWd)
+
+		if err := os.Chdir(tempDir); err != nil {
+			t.Fatalf("Failed to change directory: %v", err)
+		}
+
+		found := FindConfigFile()
+		if filepath.Base(found) != ".gocov.toml" {
+			t.Errorf("Expected to find .gocov.toml, got %s", found)
+		}
+	})
+
+	t.Run("yaml takes precedence over toml", func(t *testing.T) {
+		tempDir := t.TempDir()
+		for _, name := range []string{".gocov.yml", ".gocov.toml"} {
+			if err := os.WriteFile(filepath.Join(tempDir, name), []byte(""), 0644); err != nil {
+				t.Fatalf("Failed to create test config file: %v", err)
+			}
+		}
+
+		originalWd, err := os.Getwd()
+		if err != nil {
+			t.Fatalf("Failed to get current directory: %v", err)
+		}
+		defer os.Chdir(originalWd)
+
+		if err := os.Chdir(tempDir); err != nil {
+			t.Fatalf("Failed to change directory: %v", err)
+		}
+
+		found := FindConfigFile()
+		if filepath.Base(found) != ".gocov.yml" {
+			t.Errorf("Expected to find .gocov.yml, got %s", found)
+		}
+	})
+
 	t.Run("no config file found", func(t *testing.T) {
 		// Create a temporary directory without config file
 		tempDir := t.TempDir()
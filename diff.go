@@ -2,14 +2,22 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os/exec"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// DefaultGitTimeout bounds each git invocation in diff mode unless -git-timeout says otherwise
+const DefaultGitTimeout = 30 * time.Second
+
 // executeGitDiffCommand executes git diff with appropriate flags based on baseRef
-func executeGitDiffCommand(baseRef string, extraArgs ...string) *exec.Cmd {
+// The command is killed when ctx is done
+func executeGitDiffCommand(ctx context.Context, baseRef string, extraArgs ...string) *exec.Cmd {
 	args := []string{"diff"}
 
 	switch baseRef {
@@ -18,46 +26,92 @@ func executeGitDiffCommand(baseRef string, extraArgs ...string) *exec.Cmd {
 	case "working", "unstaged":
 		// No additional flags needed for working directory diff
 	default:
-		args = append(args, baseRef, "HEAD")
+		if from, to, ok := parseDiffRange(baseRef); ok {
+			args = append(args, from, to)
+		} else {
+			args = append(args, baseRef, "HEAD")
+		}
 	}
 
 	args = append(args, extraArgs...)
-	return exec.Command("git", args...)
+	return exec.CommandContext(ctx, "git", args...)
+}
+
+// runGit returns the output of cmd, started with ctx, reporting ErrGitTimeout when ctx expired first
+func runGit(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	output, err := cmd.Output()
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%w: %s", ErrGitTimeout, strings.Join(cmd.Args, " "))
+	}
+	return output, err
+}
+
+// parseDiffRange parses a revision range like "v1.2.0..v1.3.0"
+// An omitted side defaults to HEAD, matching git's own range semantics
+func parseDiffRange(baseRef string) (from, to string, ok bool) {
+	from, to, found := strings.Cut(baseRef, "..")
+	if !found || strings.HasPrefix(to, ".") {
+		// Not a range, or a symmetric "A...B" range which is not supported
+		return "", "", false
+	}
+
+	if from == "" {
+		from = "HEAD"
+	}
+	if to == "" {
+		to = "HEAD"
+	}
+	return from, to, true
 }
 
 // DiffLine represents a changed line in a file
 type DiffLine struct {
 	File       string
 	LineNum    int
-	ChangeType string // "added" or "modified"
+	ChangeType string // "added", "modified" when the line replaces deleted lines, or "context" for an unchanged line near a change
+	// BaseLineNum is the line in the base version the line corresponds to, or 0 for a brand-new line.
+	// A line replacing deleted lines corresponds to the deleted line at the same position, a context line to itself
+	BaseLineNum int
 }
 
 // GitDiff represents the diff information
 type GitDiff struct {
 	BaseRef string
 	Lines   []DiffLine
+	// Renames maps the new path of a renamed file to its old path
+	Renames map[string]string
+	// StripPrefix is removed from changed file paths when they are matched against coverage profiles.
+	// Results keep the repository-relative paths of Lines
+	StripPrefix string
+}
+
+// NewGitDiffFromLines builds a GitDiff from changed lines obtained elsewhere, e.g. from a code host API,
+// so CalculateDiffCoverage can be used without running git. lines is copied and Renames starts empty
+func NewGitDiffFromLines(baseRef string, lines []DiffLine) *GitDiff {
+	return &GitDiff{
+		BaseRef: baseRef,
+		Lines:   append([]DiffLine{}, lines...),
+		Renames: make(map[string]string),
+	}
 }
 
 // GetGitDiff retrieves the diff between the base reference and HEAD
-func GetGitDiff(baseRef string) (*GitDiff, error) {
+// git is stopped when ctx is done, e.g. on a timeout
+func GetGitDiff(ctx context.Context, baseRef string) (*GitDiff, error) {
 	if baseRef == "" {
 		baseRef = "HEAD~1"
 	}
 
-	cmd := executeGitDiffCommand(baseRef, "--unified=0")
+	cmd := executeGitDiffCommand(ctx, baseRef, "--unified=0")
 
-	output, err := cmd.Output()
+	output, err := runGit(ctx, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get git diff: %w", err)
 	}
 
-	diff := &GitDiff{
-		BaseRef: baseRef,
-		Lines:   []DiffLine{}, // Let Go handle the allocation based on actual data
-	}
-
 	// Parse the diff output
 	lines := strings.Split(string(output), "\n")
+	var diffLines []DiffLine
 	var currentFile string
 
 	for _, line := range lines {
@@ -74,7 +128,7 @@ func GetGitDiff(baseRef string) (*GitDiff, error) {
 				// Process added lines in this hunk
 				addedLines := getAddedLinesFromHunk(lines, line, hunkInfo)
 				for _, lineNum := range addedLines {
-					diff.Lines = append(diff.Lines, DiffLine{
+					diffLines = append(diffLines, DiffLine{
 						File:       currentFile,
 						LineNum:    lineNum,
 						ChangeType: "added",
@@ -84,7 +138,7 @@ func GetGitDiff(baseRef string) (*GitDiff, error) {
 		}
 	}
 
-	return diff, nil
+	return NewGitDiffFromLines(baseRef, diffLines), nil
 }
 
 // HunkInfo represents the information from a hunk header
@@ -179,55 +233,188 @@ func getAddedLinesFromHunk(allLines []string, hunkHeader string, info *HunkInfo)
 }
 
 // GetGitDiffWithContext gets diff with more sophisticated parsing
-func GetGitDiffWithContext(baseRef string) (*GitDiff, error) {
+// Only files ending in one of extensions are considered (.go when empty)
+// contextLines unchanged lines around each change are included as "context" (see parseFileDiff)
+// When baseRef is empty the merge base with the first existing baseBranches entry is used.
+// git is stopped when ctx is done; a timeout fails the whole diff rather than skipping files
+func GetGitDiffWithContext(ctx context.Context, baseRef string, extensions []string, contextLines int, baseBranches ...string) (*GitDiff, error) {
 	if baseRef == "" {
-		// Try to find the merge base with main/master
-		mergeBase, err := getMergeBase()
-		if err == nil {
+		// Try to find the merge base with the candidate branches
+		mergeBase, err := getMergeBase(ctx, baseBranches...)
+		switch {
+		case err == nil:
 			baseRef = mergeBase
-		} else {
+		case errors.Is(err, ErrGitTimeout):
+			return nil, err
+		default:
 			baseRef = "HEAD~1"
 		}
 	}
 
 	// Use git diff with name-status to get changed files first
-	cmd := executeGitDiffCommand(baseRef, "--name-only")
+	cmd := executeGitDiffCommand(ctx, baseRef, "--name-status")
 
-	output, err := cmd.Output()
+	output, err := runGit(ctx, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get changed files: %w", err)
 	}
 
-	changedFiles := strings.Split(strings.TrimSpace(string(output)), "\n")
-	diff := &GitDiff{
-		BaseRef: baseRef,
-		Lines:   []DiffLine{}, // Let Go handle the allocation based on actual data
-	}
+	changedFiles, renames := parseNameStatus(string(output))
+	var diffLines []DiffLine
 
 	// For each changed file, get detailed line changes
 	for _, file := range changedFiles {
-		if file == "" || !strings.HasSuffix(file, ".go") {
+		if file == "" || !hasDiffExtension(file, extensions) {
 			continue
 		}
 
-		// Get diff for specific file
-		cmd := executeGitDiffCommand(baseRef, "--", file)
+		// Get diff for specific file, widening git's default of 3 context lines when needed
+		args := []string{"--", file}
+		if contextLines > gitDefaultContextLines {
+			args = append([]string{fmt.Sprintf("--unified=%d", contextLines)}, args...)
+		}
+		cmd := executeGitDiffCommand(ctx, baseRef, args...)
 
-		fileDiff, err := cmd.Output()
+		fileDiff, err := runGit(ctx, cmd)
+		if errors.Is(err, ErrGitTimeout) {
+			return nil, err
+		}
 		if err != nil {
 			continue
 		}
 
 		// Parse the file diff
-		lines := parseFileDiff(file, string(fileDiff))
-		diff.Lines = append(diff.Lines, lines...)
+		diffLines = append(diffLines, parseFileDiff(file, string(fileDiff), contextLines)...)
 	}
 
+	diff := NewGitDiffFromLines(baseRef, diffLines)
+	diff.Renames = renames
+	return diff, nil
+}
+
+// trimDiffPath removes prefix, taken as a directory, from path
+func trimDiffPath(path, prefix string) string {
+	if prefix == "" {
+		return path
+	}
+	return strings.TrimPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+// gitDefaultContextLines is the number of context lines git diff prints around changes by default
+const gitDefaultContextLines = 3
+
+// defaultDiffExtensions are the file extensions considered in diff mode when none are configured
+var defaultDiffExtensions = []string{".go"}
+
+// hasDiffExtension reports whether file ends with one of extensions, or defaultDiffExtensions when empty
+func hasDiffExtension(file string, extensions []string) bool {
+	if len(extensions) == 0 {
+		extensions = defaultDiffExtensions
+	}
+	for _, ext := range extensions {
+		if ext != "" && strings.HasSuffix(file, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterDiffLines keeps only the lines whose ChangeType is one of changeTypes
+func FilterDiffLines(lines []DiffLine, changeTypes ...string) []DiffLine {
+	filtered := make([]DiffLine, 0, len(lines))
+	for _, line := range lines {
+		for _, changeType := range changeTypes {
+			if line.ChangeType == changeType {
+				filtered = append(filtered, line)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// parseNameStatus parses "git diff --name-status" output
+// It returns the current path of every changed file and a map of renamed files (new path to old path)
+func parseNameStatus(output string) ([]string, map[string]string) {
+	var files []string
+	renames := make(map[string]string)
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+
+		// Renames and copies look like "R100\told.go\tnew.go"
+		if len(fields) >= 3 && (strings.HasPrefix(fields[0], "R") || strings.HasPrefix(fields[0], "C")) {
+			files = append(files, fields[2])
+			if strings.HasPrefix(fields[0], "R") {
+				renames[fields[2]] = fields[1]
+			}
+			continue
+		}
+		files = append(files, fields[1])
+	}
+
+	return files, renames
+}
+
+// ParseUnifiedDiff builds a GitDiff from a unified diff such as the output of "git diff" or a PR patch
+// Only changed lines in files ending in one of extensions (.go when omitted) are recorded, matching GetGitDiffWithContext.
+// contextLines can only include the context lines present in the patch
+func ParseUnifiedDiff(r io.Reader, contextLines int, extensions ...string) (*GitDiff, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read diff: %w", err)
+	}
+
+	diff := NewGitDiffFromLines("patch", nil)
+
+	var currentFile, renameFrom string
+	var fileContent strings.Builder
+	flush := func() {
+		if currentFile != "" && hasDiffExtension(currentFile, extensions) {
+			diff.Lines = append(diff.Lines, parseFileDiff(currentFile, fileContent.String(), contextLines)...)
+		}
+		fileContent.Reset()
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			currentFile, renameFrom = "", ""
+			continue
+		case strings.HasPrefix(line, "rename from "):
+			renameFrom = strings.TrimPrefix(line, "rename from ")
+			continue
+		case strings.HasPrefix(line, "rename to "):
+			if renameFrom != "" {
+				diff.Renames[strings.TrimPrefix(line, "rename to ")] = renameFrom
+			}
+			continue
+		case strings.HasPrefix(line, "+++ "):
+			flush()
+			// Deleted files have no new side
+			currentFile = ""
+			name, _, _ := strings.Cut(strings.TrimPrefix(line, "+++ "), "\t")
+			if name != "/dev/null" {
+				currentFile = strings.TrimPrefix(name, "b/")
+			}
+			continue
+		}
+		fileContent.WriteString(line)
+		fileContent.WriteString("\n")
+	}
+	flush()
+
 	return diff, nil
 }
 
 // parseFileDiff parses diff output for a single file
-func parseFileDiff(filename string, diffContent string) []DiffLine {
+// Context lines within contextLines lines of an addition or deletion are recorded as "context",
+// so the unchanged parts of partially edited code are checked too
+func parseFileDiff(filename string, diffContent string, contextLines int) []DiffLine {
 	// Count lines first to get a better capacity estimate
 	lineCount := strings.Count(diffContent, "\n")
 	// Assume roughly 1/3 of lines might be additions (rest are context, deletions, headers)
@@ -235,8 +422,29 @@ func parseFileDiff(filename string, diffContent string) []DiffLine {
 	result := make([]DiffLine, 0, estimatedCapacity)
 	scanner := bufio.NewScanner(strings.NewReader(diffContent))
 
-	var currentNewLine int
+	var currentNewLine, currentOldLine int
 	inHunk := false
+	// afterDeletion is true while additions directly follow deleted lines
+	afterDeletion := false
+	// deleted holds the base lines of the deletions not yet paired with a replacing addition
+	var deleted []int
+	// hunk holds the current hunk's lines in order; context lines have an empty ChangeType
+	var hunk []DiffLine
+	// nearChange holds the new-file line ranges within contextLines of a change
+	var nearChange [][2]int
+
+	flushHunk := func() {
+		for _, line := range hunk {
+			if line.ChangeType == "" {
+				if !inRanges(line.LineNum, nearChange) {
+					continue
+				}
+				line.ChangeType = "context"
+			}
+			result = append(result, line)
+		}
+		hunk, nearChange = hunk[:0], nearChange[:0]
+	}
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -245,8 +453,11 @@ func parseFileDiff(filename string, diffContent string) []DiffLine {
 		if strings.HasPrefix(line, "@@") {
 			info := parseHunkHeader(line)
 			if info != nil {
-				currentNewLine = info.NewStart
+				flushHunk()
+				currentNewLine, currentOldLine = info.NewStart, info.OldStart
 				inHunk = true
+				afterDeletion = false
+				deleted = deleted[:0]
 			}
 			continue
 		}
@@ -257,38 +468,81 @@ func parseFileDiff(filename string, diffContent string) []DiffLine {
 
 		// Added line
 		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
-			result = append(result, DiffLine{
-				File:       filename,
-				LineNum:    currentNewLine,
-				ChangeType: "added",
+			changeType := "added"
+			baseLine := 0
+			if afterDeletion {
+				changeType = "modified"
+				if len(deleted) > 0 {
+					baseLine, deleted = deleted[0], deleted[1:]
+				}
+			}
+			hunk = append(hunk, DiffLine{
+				File:        filename,
+				LineNum:     currentNewLine,
+				ChangeType:  changeType,
+				BaseLineNum: baseLine,
 			})
+			if contextLines > 0 {
+				nearChange = append(nearChange, [2]int{currentNewLine - contextLines, currentNewLine + contextLines})
+			}
 			currentNewLine++
 		} else if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
 			// Deleted line, don't increment line number
+			// It sits between currentNewLine-1 and currentNewLine, so both are one line away
+			afterDeletion = true
+			deleted = append(deleted, currentOldLine)
+			currentOldLine++
+			if contextLines > 0 {
+				nearChange = append(nearChange, [2]int{currentNewLine - contextLines, currentNewLine - 1 + contextLines})
+			}
 		} else if !strings.HasPrefix(line, "\\") {
 			// Context line
+			if contextLines > 0 {
+				hunk = append(hunk, DiffLine{File: filename, LineNum: currentNewLine, BaseLineNum: currentOldLine})
+			}
 			currentNewLine++
+			currentOldLine++
+			afterDeletion = false
+			deleted = deleted[:0]
 		}
 	}
+	flushHunk()
 
 	return result
 }
 
-// getMergeBase tries to find the merge base with main or master branch
-func getMergeBase() (string, error) {
-	// Try main branch first
-	cmd := exec.Command("git", "merge-base", "HEAD", "main")
-	output, err := cmd.Output()
-	if err == nil {
-		return strings.TrimSpace(string(output)), nil
+// inRanges reports whether n lies in one of the inclusive ranges
+func inRanges(n int, ranges [][2]int) bool {
+	for _, r := range ranges {
+		if n >= r[0] && n <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultBaseBranches are the branches probed for a merge base when none are configured
+var defaultBaseBranches = []string{"main", "master"}
+
+// getMergeBase returns the merge base of HEAD with the first branch that exists
+// Without arguments it tries defaultBaseBranches in order; git is stopped when ctx is done
+func getMergeBase(ctx context.Context, branches ...string) (string, error) {
+	if len(branches) == 0 {
+		branches = defaultBaseBranches
 	}
 
-	// Try master branch
-	cmd = exec.Command("git", "merge-base", "HEAD", "master")
-	output, err = cmd.Output()
-	if err == nil {
-		return strings.TrimSpace(string(output)), nil
+	for _, branch := range branches {
+		if branch == "" {
+			continue
+		}
+		output, err := runGit(ctx, exec.CommandContext(ctx, "git", "merge-base", "HEAD", branch))
+		if err == nil {
+			return strings.TrimSpace(string(output)), nil
+		}
+		if errors.Is(err, ErrGitTimeout) {
+			return "", err
+		}
 	}
 
-	return "", fmt.Errorf("could not find merge base")
+	return "", fmt.Errorf("could not find merge base with any of %s", strings.Join(branches, ", "))
 }
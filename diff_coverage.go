@@ -1,79 +1,128 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/blck-snwmn/gocov/pkg/gocov"
 	"golang.org/x/tools/cover"
 )
 
 // DiffCoverageResult represents coverage for changed lines
 type DiffCoverageResult struct {
-	File           string
-	TotalLines     int
-	CoveredLines   int
-	UncoveredLines []int
-	Coverage       float64
+	File           string  `json:"file"`
+	TotalLines     int     `json:"total_lines"`
+	CoveredLines   int     `json:"covered_lines"`
+	UncoveredLines []int   `json:"uncovered_lines"`
+	Coverage       float64 `json:"coverage"`
+	// FileCoverage is the statement coverage of the whole file, valid only when ProfileMatched is true
+	FileCoverage   float64 `json:"file_coverage"`
+	ProfileMatched bool    `json:"profile_matched"`
+	// CoveredLineNumbers lists the changed lines that are covered; CoveredLines is their count
+	CoveredLineNumbers []int `json:"covered_line_numbers"`
+	// RegressedLines lists the uncovered changed lines whose base line was covered in the base profile
+	RegressedLines []int `json:"regressed_lines,omitempty"`
 }
 
 // DiffCoverageSummary represents the overall diff coverage
 type DiffCoverageSummary struct {
-	Results      []DiffCoverageResult
-	TotalLines   int
-	CoveredLines int
-	Coverage     float64
+	Results      []DiffCoverageResult `json:"results"`
+	TotalLines   int                  `json:"total_lines"`
+	CoveredLines int                  `json:"covered_lines"`
+	Coverage     float64              `json:"coverage"`
+	// RegressedLines counts the changed lines that were covered at the base and no longer are
+	RegressedLines int `json:"regressed_lines,omitempty"`
 }
 
 // CalculateDiffCoverage calculates coverage for changed lines
-func CalculateDiffCoverage(profiles []*cover.Profile, diff *GitDiff) *DiffCoverageSummary {
+// baseProfiles is the coverage at the base commit, or nil; with it, uncovered changed lines whose
+// base line (see DiffLine.BaseLineNum) was covered are reported as regressed. Results are sorted by file name
+func CalculateDiffCoverage(profiles []*cover.Profile, diff *GitDiff, baseProfiles []*cover.Profile) *DiffCoverageSummary {
 	// Group diff lines by file
 	fileChanges := make(map[string][]int)
+	// baseLines maps each changed line with a counterpart at the base to its base line
+	baseLines := make(map[string]map[int]int)
 	for _, line := range diff.Lines {
 		fileChanges[line.File] = append(fileChanges[line.File], line.LineNum)
+		if line.BaseLineNum > 0 {
+			if baseLines[line.File] == nil {
+				baseLines[line.File] = make(map[int]int)
+			}
+			baseLines[line.File][line.LineNum] = line.BaseLineNum
+		}
 	}
 
-	// Create a map for quick profile lookup
-	profileMap := make(map[string]*cover.Profile)
-	for _, profile := range profiles {
-		// Normalize the file path
-		normalizedPath := normalizeFilePath(profile.FileName)
-		profileMap[normalizedPath] = profile
+	// Index the profiles once so each changed file only compares against profiles with its file name
+	index := newProfileIndex(profiles, diff.StripPrefix)
+	var baseIndex *profileIndex
+	if baseProfiles != nil {
+		baseIndex = newProfileIndex(baseProfiles, diff.StripPrefix)
 	}
 
 	var results []DiffCoverageResult
 	totalLines := 0
 	totalCovered := 0
+	totalRegressed := 0
+
+	// Visit files in name order so results are deterministic
+	files := make([]string, 0, len(fileChanges))
+	for file := range fileChanges {
+		files = append(files, file)
+	}
+	sort.Strings(files)
 
 	// Calculate coverage for each changed file
-	for file, changedLines := range fileChanges {
-		// Try to find matching profile
-		profile := FindMatchingProfile(profiles, file)
+	for _, file := range files {
+		changedLines := fileChanges[file]
+		// Try to find matching profile, falling back to the pre-rename path
+		profile := index.find(file)
+		oldFile, renamed := diff.Renames[file]
+		if profile == nil && renamed {
+			profile = index.find(oldFile)
+		}
+		// The base profile knows a renamed file by its old path
+		var baseProfile *cover.Profile
+		if baseIndex != nil {
+			if renamed {
+				baseProfile = baseIndex.find(oldFile)
+			}
+			if baseProfile == nil {
+				baseProfile = baseIndex.find(file)
+			}
+		}
 
 		if profile == nil {
 			// File not in coverage profile (maybe not tested at all)
+			regressed := regressedLines(baseProfile, changedLines, baseLines[file])
 			results = append(results, DiffCoverageResult{
 				File:           file,
 				TotalLines:     len(changedLines),
 				CoveredLines:   0,
 				UncoveredLines: changedLines,
 				Coverage:       0.0,
+				RegressedLines: regressed,
 			})
 			totalLines += len(changedLines)
+			totalRegressed += len(regressed)
 			continue
 		}
 
 		// Check coverage for each changed line
 		coveredCount := 0
-		var uncoveredLines []int
+		var coveredLines, uncoveredLines []int
 
 		for _, lineNum := range changedLines {
 			if isLineCovered(profile, lineNum) {
 				coveredCount++
+				coveredLines = append(coveredLines, lineNum)
 			} else {
 				uncoveredLines = append(uncoveredLines, lineNum)
 			}
 		}
+		regressed := regressedLines(baseProfile, uncoveredLines, baseLines[file])
 
 		coverage := 0.0
 		if len(changedLines) > 0 {
@@ -81,15 +130,20 @@ func CalculateDiffCoverage(profiles []*cover.Profile, diff *GitDiff) *DiffCovera
 		}
 
 		results = append(results, DiffCoverageResult{
-			File:           file,
-			TotalLines:     len(changedLines),
-			CoveredLines:   coveredCount,
-			UncoveredLines: uncoveredLines,
-			Coverage:       coverage,
+			File:               file,
+			TotalLines:         len(changedLines),
+			CoveredLines:       coveredCount,
+			UncoveredLines:     uncoveredLines,
+			CoveredLineNumbers: coveredLines,
+			Coverage:           coverage,
+			FileCoverage:       calculateFileCoverage(profile),
+			ProfileMatched:     true,
+			RegressedLines:     regressed,
 		})
 
 		totalLines += len(changedLines)
 		totalCovered += coveredCount
+		totalRegressed += len(regressed)
 	}
 
 	// Calculate overall coverage
@@ -99,13 +153,67 @@ func CalculateDiffCoverage(profiles []*cover.Profile, diff *GitDiff) *DiffCovera
 	}
 
 	return &DiffCoverageSummary{
-		Results:      results,
-		TotalLines:   totalLines,
-		CoveredLines: totalCovered,
-		Coverage:     overallCoverage,
+		Results:        results,
+		TotalLines:     totalLines,
+		CoveredLines:   totalCovered,
+		Coverage:       overallCoverage,
+		RegressedLines: totalRegressed,
 	}
 }
 
+// regressedLines returns the uncovered lines whose base line in baseLines is covered in baseProfile
+func regressedLines(baseProfile *cover.Profile, uncovered []int, baseLines map[int]int) []int {
+	if baseProfile == nil {
+		return nil
+	}
+	var regressed []int
+	for _, lineNum := range uncovered {
+		if baseLine, ok := baseLines[lineNum]; ok && isLineCovered(baseProfile, baseLine) {
+			regressed = append(regressed, lineNum)
+		}
+	}
+	return regressed
+}
+
+// NoProfileMatched reports whether there were changed files but none of them matched a profile
+// This usually means the diff and the profile use different path schemes
+func NoProfileMatched(summary *DiffCoverageSummary) bool {
+	if len(summary.Results) == 0 {
+		return false
+	}
+	for _, result := range summary.Results {
+		if result.ProfileMatched {
+			return false
+		}
+	}
+	return true
+}
+
+// SortDiffResults orders summary results in place
+// "uncovered" puts files with the most uncovered changed lines first; anything else sorts by file name
+func SortDiffResults(summary *DiffCoverageSummary, by string) {
+	sort.SliceStable(summary.Results, func(i, j int) bool {
+		a, b := summary.Results[i], summary.Results[j]
+		if by == "uncovered" && len(a.UncoveredLines) != len(b.UncoveredLines) {
+			return len(a.UncoveredLines) > len(b.UncoveredLines)
+		}
+		return a.File < b.File
+	})
+}
+
+// calculateFileCoverage calculates the statement coverage of an entire profile
+func calculateFileCoverage(profile *cover.Profile) float64 {
+	stmtCount := 0
+	stmtCovered := 0
+	for _, block := range profile.Blocks {
+		stmtCount += block.NumStmt
+		if block.Count > 0 {
+			stmtCovered += block.NumStmt
+		}
+	}
+	return gocov.CalculateCoverage(stmtCount, stmtCovered)
+}
+
 // isLineCovered checks if a specific line is covered
 func isLineCovered(profile *cover.Profile, lineNum int) bool {
 	for _, block := range profile.Blocks {
@@ -116,50 +224,74 @@ func isLineCovered(profile *cover.Profile, lineNum int) bool {
 	return false
 }
 
-// normalizeFilePath normalizes file paths for comparison
-func normalizeFilePath(path string) string {
-	// Remove leading ./ if present
-	path = strings.TrimPrefix(path, "./")
+// FindMatchingProfile tries to find a profile that matches the given file
+// The profile sharing the longest run of trailing path segments with file wins.
+// A match on the file name alone is only accepted when exactly one profile has that name,
+// so files like util.go in different packages are never confused.
+// Ties go to the profile with the fewest path segments besides the shared suffix,
+// then to the lexically smallest name, so the result does not depend on profile order
+func FindMatchingProfile(profiles []*cover.Profile, file string) *cover.Profile {
+	return newProfileIndex(profiles, "").find(file)
+}
+
+// profileIndex looks up profiles like FindMatchingProfile, but is built once for all changed files
+// so that each lookup only compares against the profiles sharing the file name
+type profileIndex struct {
+	// byName holds the first profile with each exact file name
+	byName map[string]*cover.Profile
+	// byBase holds the profiles with each last path segment, in profile order
+	byBase map[string][]*cover.Profile
+	// stripPrefix is removed from changed file paths before they are looked up
+	stripPrefix string
+}
 
-	// Try to make it absolute if it's not already
-	if !filepath.IsAbs(path) {
-		// For relative paths, we'll keep them as is
-		// The coverage profile might have full package paths
-		return path
+// newProfileIndex indexes profiles by file name and last path segment
+// Changed files are looked up with stripPrefix removed, see GitDiff.StripPrefix
+func newProfileIndex(profiles []*cover.Profile, stripPrefix string) *profileIndex {
+	index := &profileIndex{
+		stripPrefix: stripPrefix,
+		byName:      make(map[string]*cover.Profile, len(profiles)),
+		byBase:      make(map[string][]*cover.Profile, len(profiles)),
+	}
+	for _, profile := range profiles {
+		if _, ok := index.byName[profile.FileName]; !ok {
+			index.byName[profile.FileName] = profile
+		}
+		base := lastSegment(filepath.ToSlash(profile.FileName))
+		index.byBase[base] = append(index.byBase[base], profile)
 	}
+	return index
+}
 
-	return path
+// lastSegment returns the part of a slash-separated path after its last slash
+func lastSegment(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
 }
 
-// FindMatchingProfile tries to find a profile that matches the given file
-func FindMatchingProfile(profiles []*cover.Profile, file string) *cover.Profile {
+// find returns the profile matching file, see FindMatchingProfile
+// Profiles with a different last segment share no trailing segments with file, so only those with the same one are compared
+func (idx *profileIndex) find(file string) *cover.Profile {
+	file = trimDiffPath(file, idx.stripPrefix)
+
 	// Direct match
-	for _, profile := range profiles {
-		if profile.FileName == file {
-			return profile
-		}
+	if profile, ok := idx.byName[file]; ok {
+		return profile
 	}
 
-	// Try to find the best match (longest suffix match)
+	fileParts := strings.Split(filepath.ToSlash(file), "/")
+
 	var bestMatch *cover.Profile
 	bestMatchLen := 0
+	var baseMatches []*cover.Profile
 
-	for _, profile := range profiles {
-		// Check if the profile filename ends with our file
-		if strings.HasSuffix(profile.FileName, file) {
-			if len(file) > bestMatchLen {
-				bestMatch = profile
-				bestMatchLen = len(file)
-			}
+	for _, profile := range idx.byBase[fileParts[len(fileParts)-1]] {
+		matchLen := commonSuffixSegments(strings.Split(filepath.ToSlash(profile.FileName), "/"), fileParts)
+		if matchLen == 1 {
+			baseMatches = append(baseMatches, profile)
 		}
-
-		// Check if the profile filename ends with /file
-		if strings.HasSuffix(profile.FileName, "/"+file) {
-			matchLen := len(file) + 1
-			if matchLen > bestMatchLen {
-				bestMatch = profile
-				bestMatchLen = matchLen
-			}
+		if matchLen > 1 && (matchLen > bestMatchLen || matchLen == bestMatchLen && closerProfile(profile, bestMatch)) {
+			bestMatch = profile
+			bestMatchLen = matchLen
 		}
 	}
 
@@ -167,18 +299,68 @@ func FindMatchingProfile(profiles []*cover.Profile, file string) *cover.Profile
 		return bestMatch
 	}
 
-	// Fallback: check if our file ends with the profile filename
-	for _, profile := range profiles {
-		if strings.HasSuffix(file, filepath.Base(profile.FileName)) {
-			return profile
-		}
+	// Fallback: the file name alone, if it is unambiguous
+	if len(baseMatches) == 1 {
+		return baseMatches[0]
 	}
 
 	return nil
 }
 
+// closerProfile reports whether a should be preferred over b when both share
+// the same number of trailing path segments with the changed file
+func closerProfile(a, b *cover.Profile) bool {
+	aLen := strings.Count(filepath.ToSlash(a.FileName), "/")
+	bLen := strings.Count(filepath.ToSlash(b.FileName), "/")
+	if aLen != bLen {
+		return aLen < bLen
+	}
+	return a.FileName < b.FileName
+}
+
+// commonSuffixSegments counts the trailing path segments shared by a and b
+func commonSuffixSegments(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return n
+}
+
+// CheckDiffFileThresholds returns every changed file whose diff coverage, rounded to places, is below threshold
+func CheckDiffFileThresholds(summary *DiffCoverageSummary, threshold float64, places int) []gocov.ThresholdViolation {
+	if threshold <= 0 {
+		return nil
+	}
+
+	var violations []gocov.ThresholdViolation
+	for _, result := range summary.Results {
+		if result.TotalLines == 0 || !gocov.BelowThreshold(result.Coverage, threshold, places) {
+			continue
+		}
+		violations = append(violations, gocov.ThresholdViolation{
+			Directory: result.File,
+			Threshold: threshold,
+			Actual:    result.Coverage,
+		})
+	}
+	sort.Slice(violations, func(i, j int) bool {
+		return violations[i].Directory < violations[j].Directory
+	})
+	return violations
+}
+
+// diffColumnsWidth is the width of the diff table without the file column
+const diffColumnsWidth = 40
+
 // FormatDiffCoverage formats the diff coverage results for display
-func FormatDiffCoverage(summary *DiffCoverageSummary) string {
+// width is the file column width; 0 uses gocov.DefaultColumnWidth. Coverage is shown with places decimal places
+func FormatDiffCoverage(summary *DiffCoverageSummary, width, places int) string {
+	if width <= 0 {
+		width = gocov.DefaultColumnWidth
+	}
+	tableWidth := width + diffColumnsWidth
+
 	// Pre-allocate with estimated capacity based on results
 	// Header + each result (~200 chars) + footer
 	estimatedSize := 200 + len(summary.Results)*200 + 100
@@ -186,41 +368,139 @@ func FormatDiffCoverage(summary *DiffCoverageSummary) string {
 	output.Grow(estimatedSize)
 
 	output.WriteString("Diff Coverage Report:\n")
-	output.WriteString(strings.Repeat("=", 80) + "\n")
-	output.WriteString(fmt.Sprintf("%-50s %10s %10s %8s\n", "File", "Lines", "Covered", "Coverage"))
-	output.WriteString(strings.Repeat("-", 80) + "\n")
+	output.WriteString(strings.Repeat("=", tableWidth) + "\n")
+	output.WriteString(fmt.Sprintf("%-*s %10s %10s %8s %8s\n", width, "File", "Lines", "Covered", "Coverage", "File Cov"))
+	output.WriteString(strings.Repeat("-", tableWidth) + "\n")
 
 	for _, result := range summary.Results {
-		output.WriteString(fmt.Sprintf("%-50s %10d %10d %7.1f%%\n",
-			truncateString(result.File, 50),
+		// Files without a matching profile have no overall coverage
+		fileCoverage := "n/a"
+		if result.ProfileMatched {
+			fileCoverage = gocov.FormatCoverage(result.FileCoverage, places) + "%"
+		}
+
+		output.WriteString(fmt.Sprintf("%-*s %10d %10d %7s%% %8s\n",
+			width,
+			gocov.TruncateString(result.File, width),
 			result.TotalLines,
 			result.CoveredLines,
-			result.Coverage))
+			gocov.FormatCoverage(result.Coverage, places),
+			fileCoverage))
 
-		// Show uncovered lines if any
-		if len(result.UncoveredLines) > 0 && len(result.UncoveredLines) <= 10 {
-			uncoveredStr := fmt.Sprintf("  Uncovered lines: %v", result.UncoveredLines)
-			output.WriteString(uncoveredStr + "\n")
-		} else if len(result.UncoveredLines) > 10 {
-			output.WriteString(fmt.Sprintf("  Uncovered lines: %v... (%d more)\n",
-				result.UncoveredLines[:10], len(result.UncoveredLines)-10))
-		}
+		// Show covered and uncovered lines if any
+		writeLineList(&output, "Covered lines", result.CoveredLineNumbers)
+		writeLineList(&output, "Uncovered lines", result.UncoveredLines)
+		writeLineList(&output, "Regressed lines", result.RegressedLines)
 	}
 
-	output.WriteString(strings.Repeat("-", 80) + "\n")
-	output.WriteString(fmt.Sprintf("%-50s %10d %10d %7.1f%%\n",
+	output.WriteString(strings.Repeat("-", tableWidth) + "\n")
+	output.WriteString(FormatDiffCoverageTotal(summary, width, places))
+
+	return output.String()
+}
+
+// writeLineList writes a labeled list of line numbers, truncated after 10 entries
+func writeLineList(output *strings.Builder, label string, lines []int) {
+	if len(lines) > 0 && len(lines) <= 10 {
+		output.WriteString(fmt.Sprintf("  %s: %v\n", label, lines))
+	} else if len(lines) > 10 {
+		output.WriteString(fmt.Sprintf("  %s: %v... (%d more)\n", label, lines[:10], len(lines)-10))
+	}
+}
+
+// FormatDiffCoverageTotal formats only the TOTAL DIFF line (used by -quiet)
+// width is the file column width; 0 uses gocov.DefaultColumnWidth. Coverage is shown with places decimal places
+func FormatDiffCoverageTotal(summary *DiffCoverageSummary, width, places int) string {
+	if width <= 0 {
+		width = gocov.DefaultColumnWidth
+	}
+	return fmt.Sprintf("%-*s %10d %10d %7s%%\n",
+		width,
 		"TOTAL DIFF",
 		summary.TotalLines,
 		summary.CoveredLines,
-		summary.Coverage))
+		gocov.FormatCoverage(summary.Coverage, places))
+}
 
-	return output.String()
+// FormatDiffCoverageJSON formats the diff coverage results as JSON
+// With quiet set only the version and the totals are emitted, matching quiet JSON outside diff mode
+func FormatDiffCoverageJSON(summary *DiffCoverageSummary, indent, quiet bool) (string, error) {
+	var output any
+	if quiet {
+		output = struct {
+			Version        string  `json:"version"`
+			TotalLines     int     `json:"total_lines"`
+			CoveredLines   int     `json:"covered_lines"`
+			Coverage       float64 `json:"coverage"`
+			RegressedLines int     `json:"regressed_lines,omitempty"`
+		}{
+			Version:        gocov.JSONSchemaVersion,
+			TotalLines:     summary.TotalLines,
+			CoveredLines:   summary.CoveredLines,
+			Coverage:       summary.Coverage,
+			RegressedLines: summary.RegressedLines,
+		}
+	} else {
+		// Always emit arrays rather than null so consumers can iterate safely
+		results := make([]DiffCoverageResult, len(summary.Results))
+		for i, result := range summary.Results {
+			if result.UncoveredLines == nil {
+				result.UncoveredLines = []int{}
+			}
+			if result.CoveredLineNumbers == nil {
+				result.CoveredLineNumbers = []int{}
+			}
+			results[i] = result
+		}
+
+		output = struct {
+			Version        string               `json:"version"`
+			Results        []DiffCoverageResult `json:"results"`
+			TotalLines     int                  `json:"total_lines"`
+			CoveredLines   int                  `json:"covered_lines"`
+			Coverage       float64              `json:"coverage"`
+			RegressedLines int                  `json:"regressed_lines,omitempty"`
+		}{
+			Version:        gocov.JSONSchemaVersion,
+			Results:        results,
+			TotalLines:     summary.TotalLines,
+			CoveredLines:   summary.CoveredLines,
+			Coverage:       summary.Coverage,
+			RegressedLines: summary.RegressedLines,
+		}
+	}
+
+	var data []byte
+	var err error
+	if indent {
+		data, err = json.MarshalIndent(output, "", "  ")
+	} else {
+		data, err = json.Marshal(output)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to encode diff coverage: %w", err)
+	}
+	return string(data) + "\n", nil
 }
 
-// truncateString truncates a string to the specified length
-func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
+// FormatGitHubAnnotations formats uncovered diff lines as GitHub Actions workflow commands
+// File paths come from git diff output and are therefore repository-relative
+func FormatGitHubAnnotations(summary *DiffCoverageSummary) string {
+	var output strings.Builder
+
+	for _, result := range summary.Results {
+		regressed := make(map[int]bool, len(result.RegressedLines))
+		for _, lineNum := range result.RegressedLines {
+			regressed[lineNum] = true
+		}
+		for _, lineNum := range result.UncoveredLines {
+			message := "line not covered"
+			if regressed[lineNum] {
+				message = "line no longer covered (covered at the base)"
+			}
+			output.WriteString(fmt.Sprintf("::warning file=%s,line=%d::%s\n", result.File, lineNum, message))
+		}
 	}
-	return s[:maxLen-3] + "..."
+
+	return output.String()
 }
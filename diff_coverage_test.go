@@ -1,9 +1,13 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/blck-snwmn/gocov/pkg/gocov"
 	"golang.org/x/tools/cover"
 )
 
@@ -107,6 +111,121 @@ func TestFindMatchingProfile(t *testing.T) {
 	}
 }
 
+func TestFindMatchingProfileCollidingBasenames(t *testing.T) {
+	profiles := []*cover.Profile{
+		{FileName: "github.com/example/project/internal/db/util.go"},
+		{FileName: "github.com/example/project/internal/http/util.go"},
+		{FileName: "github.com/example/project/cmd/server/main.go"},
+		{FileName: "github.com/example/project/pkg/xmain.go"},
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		wantFile string
+	}{
+		{
+			name:     "longest suffix picks the right package",
+			file:     "internal/http/util.go",
+			wantFile: "github.com/example/project/internal/http/util.go",
+		},
+		{
+			name:     "repo prefix differs but package matches",
+			file:     "services/api/internal/db/util.go",
+			wantFile: "github.com/example/project/internal/db/util.go",
+		},
+		{
+			name:     "ambiguous basename is not matched",
+			file:     "internal/cache/util.go",
+			wantFile: "",
+		},
+		{
+			name:     "unique basename is still matched",
+			file:     "cmd/api/main.go",
+			wantFile: "github.com/example/project/cmd/server/main.go",
+		},
+		{
+			name:     "suffix inside a segment is not a match",
+			file:     "main.go",
+			wantFile: "github.com/example/project/cmd/server/main.go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FindMatchingProfile(profiles, tt.file)
+			gotFile := ""
+			if got != nil {
+				gotFile = got.FileName
+			}
+			if gotFile != tt.wantFile {
+				t.Errorf("FindMatchingProfile(%q) = %q, want %q", tt.file, gotFile, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestFindMatchingProfileTies(t *testing.T) {
+	user := []*cover.Profile{
+		{FileName: "github.com/example/project/internal/admin/user.go"},
+		{FileName: "github.com/example/project/internal/service/user.go"},
+	}
+	vendored := []*cover.Profile{
+		{FileName: "github.com/example/project/vendor/github.com/lib/service/user.go"},
+		{FileName: "github.com/example/project/service/user.go"},
+	}
+
+	tests := []struct {
+		name     string
+		profiles []*cover.Profile
+		file     string
+		wantFile string
+	}{
+		{
+			name:     "directory picks the package",
+			profiles: user,
+			file:     "service/user.go",
+			wantFile: "github.com/example/project/internal/service/user.go",
+		},
+		{
+			name:     "other package",
+			profiles: user,
+			file:     "internal/admin/user.go",
+			wantFile: "github.com/example/project/internal/admin/user.go",
+		},
+		{
+			name:     "tie prefers the shorter path",
+			profiles: vendored,
+			file:     "service/user.go",
+			wantFile: "github.com/example/project/service/user.go",
+		},
+		{
+			name: "tie on equal length prefers the smaller name",
+			profiles: []*cover.Profile{
+				{FileName: "github.com/example/b/service/user.go"},
+				{FileName: "github.com/example/a/service/user.go"},
+			},
+			file:     "service/user.go",
+			wantFile: "github.com/example/a/service/user.go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reversed := make([]*cover.Profile, len(tt.profiles))
+			for i, p := range tt.profiles {
+				reversed[len(tt.profiles)-1-i] = p
+			}
+			for _, profiles := range [][]*cover.Profile{tt.profiles, reversed} {
+				got := FindMatchingProfile(profiles, tt.file)
+				if got == nil || got.FileName != tt.wantFile {
+					t.Errorf("FindMatchingProfile(%q) = %v, want %q", tt.file, got, tt.wantFile)
+				}
+			}
+		})
+	}
+}
+
 func TestCalculateDiffCoverage(t *testing.T) {
 	// Create test profiles
 	profiles := []*cover.Profile{
@@ -140,7 +259,7 @@ func TestCalculateDiffCoverage(t *testing.T) {
 		},
 	}
 
-	summary := CalculateDiffCoverage(profiles, diff)
+	summary := CalculateDiffCoverage(profiles, diff, nil)
 
 	// Verify total statistics
 	if summary.TotalLines != 5 {
@@ -153,10 +272,17 @@ func TestCalculateDiffCoverage(t *testing.T) {
 		t.Errorf("Coverage = %.1f%%, want 40.0%%", summary.Coverage)
 	}
 
-	// Verify we have results for all files
+	// Verify we have results for all files, sorted by file name
 	if len(summary.Results) != 3 {
 		t.Errorf("Results count = %d, want 3", len(summary.Results))
 	}
+	var files []string
+	for _, result := range summary.Results {
+		files = append(files, result.File)
+	}
+	if want := []string{"main.go", "newfile.go", "service/user.go"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("Result order = %v, want %v", files, want)
+	}
 
 	// Check specific file results
 	for _, result := range summary.Results {
@@ -165,18 +291,194 @@ func TestCalculateDiffCoverage(t *testing.T) {
 			if result.TotalLines != 2 || result.CoveredLines != 1 {
 				t.Errorf("main.go: got %d/%d lines, want 2/1", result.CoveredLines, result.TotalLines)
 			}
+			if !reflect.DeepEqual(result.CoveredLineNumbers, []int{15}) || !reflect.DeepEqual(result.UncoveredLines, []int{35}) {
+				t.Errorf("main.go: covered %v, uncovered %v, want [15] and [35]", result.CoveredLineNumbers, result.UncoveredLines)
+			}
 		case "service/user.go":
 			if result.TotalLines != 2 || result.CoveredLines != 1 {
 				t.Errorf("service/user.go: got %d/%d lines, want 2/1", result.CoveredLines, result.TotalLines)
 			}
+			if !reflect.DeepEqual(result.CoveredLineNumbers, []int{10}) {
+				t.Errorf("service/user.go: covered %v, want [10]", result.CoveredLineNumbers)
+			}
 		case "newfile.go":
 			if result.TotalLines != 1 || result.CoveredLines != 0 {
 				t.Errorf("newfile.go: got %d/%d lines, want 1/0", result.CoveredLines, result.TotalLines)
 			}
+			if result.ProfileMatched {
+				t.Error("newfile.go: ProfileMatched should be false")
+			}
+			if len(result.CoveredLineNumbers) != 0 {
+				t.Errorf("newfile.go: covered %v, want none", result.CoveredLineNumbers)
+			}
+		default:
+			if !result.ProfileMatched {
+				t.Errorf("%s: ProfileMatched should be true", result.File)
+			}
 		}
 	}
 }
 
+func TestCalculateDiffCoverageRenamedFile(t *testing.T) {
+	profiles := []*cover.Profile{
+		{
+			FileName: "github.com/example/project/pkg/old/handler.go",
+			Mode:     "set",
+			Blocks: []cover.ProfileBlock{
+				{StartLine: 10, EndLine: 20, NumStmt: 2, Count: 1},
+			},
+		},
+	}
+
+	files, renames := parseNameStatus("R100\tpkg/old/handler.go\tpkg/api/handler_v2.go\n")
+	diff := &GitDiff{
+		Lines:   []DiffLine{{File: files[0], LineNum: 12, ChangeType: "added"}},
+		Renames: renames,
+	}
+
+	summary := CalculateDiffCoverage(profiles, diff, nil)
+	if len(summary.Results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(summary.Results))
+	}
+	result := summary.Results[0]
+	if result.File != "pkg/api/handler_v2.go" {
+		t.Errorf("Result should use the new file name, got %q", result.File)
+	}
+	if !result.ProfileMatched || result.CoveredLines != 1 {
+		t.Errorf("Renamed file should match the profile recorded under its old path: %+v", result)
+	}
+}
+
+func TestCalculateDiffCoverageBaseProfile(t *testing.T) {
+	profiles := []*cover.Profile{
+		{
+			FileName: "github.com/example/project/pkg/api/handler.go",
+			Mode:     "set",
+			Blocks: []cover.ProfileBlock{
+				{StartLine: 1, EndLine: 9, NumStmt: 2, Count: 1},
+				{StartLine: 10, EndLine: 20, NumStmt: 2, Count: 0},
+			},
+		},
+	}
+	// The base covered everything, and knew the file by its pre-rename path
+	baseProfiles := []*cover.Profile{
+		{
+			FileName: "github.com/example/project/pkg/old/handler.go",
+			Mode:     "set",
+			Blocks: []cover.ProfileBlock{
+				{StartLine: 1, EndLine: 30, NumStmt: 5, Count: 1},
+			},
+		},
+	}
+
+	diff := &GitDiff{
+		Lines: []DiffLine{
+			// Still covered
+			{File: "pkg/api/handler.go", LineNum: 5, ChangeType: "modified", BaseLineNum: 5},
+			// Replaces a covered base line but is no longer covered
+			{File: "pkg/api/handler.go", LineNum: 12, ChangeType: "modified", BaseLineNum: 11},
+			// Unchanged context line that lost its coverage
+			{File: "pkg/api/handler.go", LineNum: 14, ChangeType: "modified", BaseLineNum: 13},
+			// Brand new and uncovered, which is not a regression
+			{File: "pkg/api/handler.go", LineNum: 15, ChangeType: "added"},
+		},
+		Renames: map[string]string{"pkg/api/handler.go": "pkg/old/handler.go"},
+	}
+
+	summary := CalculateDiffCoverage(profiles, diff, baseProfiles)
+	if len(summary.Results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(summary.Results))
+	}
+	result := summary.Results[0]
+	if !reflect.DeepEqual(result.UncoveredLines, []int{12, 14, 15}) {
+		t.Errorf("UncoveredLines = %v, want [12 14 15]", result.UncoveredLines)
+	}
+	if !reflect.DeepEqual(result.RegressedLines, []int{12, 14}) {
+		t.Errorf("RegressedLines = %v, want [12 14]", result.RegressedLines)
+	}
+	if summary.RegressedLines != 2 {
+		t.Errorf("summary.RegressedLines = %d, want 2", summary.RegressedLines)
+	}
+
+	if summary := CalculateDiffCoverage(profiles, diff, nil); summary.RegressedLines != 0 || summary.Results[0].RegressedLines != nil {
+		t.Errorf("Without base profiles nothing should be reported as regressed: %+v", summary)
+	}
+}
+
+func TestSortDiffResults(t *testing.T) {
+	newSummary := func() *DiffCoverageSummary {
+		return &DiffCoverageSummary{Results: []DiffCoverageResult{
+			{File: "c.go", UncoveredLines: []int{1}},
+			{File: "a.go", UncoveredLines: []int{1, 2, 3}},
+			{File: "d.go", UncoveredLines: []int{1, 2, 3}},
+			{File: "b.go"},
+		}}
+	}
+
+	tests := []struct {
+		by   string
+		want []string
+	}{
+		{"", []string{"a.go", "b.go", "c.go", "d.go"}},
+		{"file", []string{"a.go", "b.go", "c.go", "d.go"}},
+		{"uncovered", []string{"a.go", "d.go", "c.go", "b.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			summary := newSummary()
+			SortDiffResults(summary, tt.by)
+
+			var got []string
+			for _, result := range summary.Results {
+				got = append(got, result.File)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SortDiffResults(%q) = %v, want %v", tt.by, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoProfileMatched(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []DiffCoverageResult
+		want    bool
+	}{
+		{"no changes", nil, false},
+		{"all unmatched", []DiffCoverageResult{{File: "a.go"}, {File: "b.go"}}, true},
+		{"one matched", []DiffCoverageResult{{File: "a.go"}, {File: "b.go", ProfileMatched: true}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NoProfileMatched(&DiffCoverageSummary{Results: tt.results}); got != tt.want {
+				t.Errorf("NoProfileMatched() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateFileCoverage(t *testing.T) {
+	profile := &cover.Profile{
+		FileName: "main.go",
+		Mode:     "set",
+		Blocks: []cover.ProfileBlock{
+			{StartLine: 1, EndLine: 5, NumStmt: 3, Count: 1},
+			{StartLine: 6, EndLine: 10, NumStmt: 1, Count: 0},
+		},
+	}
+
+	if got := calculateFileCoverage(profile); got != 75.0 {
+		t.Errorf("calculateFileCoverage() = %.1f%%, want 75.0%%", got)
+	}
+
+	if got := calculateFileCoverage(&cover.Profile{FileName: "empty.go"}); got != 0.0 {
+		t.Errorf("calculateFileCoverage() for empty profile = %.1f%%, want 0.0%%", got)
+	}
+}
+
 func TestFormatDiffCoverage(t *testing.T) {
 	summary := &DiffCoverageSummary{
 		Results: []DiffCoverageResult{
@@ -186,6 +488,8 @@ func TestFormatDiffCoverage(t *testing.T) {
 				CoveredLines:   8,
 				UncoveredLines: []int{15, 16},
 				Coverage:       80.0,
+				FileCoverage:   62.5,
+				ProfileMatched: true,
 			},
 			{
 				File:           "very/long/path/to/file/that/should/be/truncated/service.go",
@@ -200,6 +504,8 @@ func TestFormatDiffCoverage(t *testing.T) {
 				CoveredLines:   20,
 				UncoveredLines: []int{},
 				Coverage:       100.0,
+				CoveredLineNumbers: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10,
+					11, 12, 13, 14, 15, 16, 17, 18, 19, 20},
 			},
 		},
 		TotalLines:   35,
@@ -207,7 +513,7 @@ func TestFormatDiffCoverage(t *testing.T) {
 		Coverage:     80.0,
 	}
 
-	output := FormatDiffCoverage(summary)
+	output := FormatDiffCoverage(summary, 0, gocov.DefaultPrecision)
 
 	// Check that output contains expected elements
 	expectedStrings := []string{
@@ -215,8 +521,11 @@ func TestFormatDiffCoverage(t *testing.T) {
 		"main.go",
 		"80.0%",
 		"Uncovered lines: [15 16]",
+		"Covered lines: [1 2 3 4 5 6 7 8 9 10]... (10 more)",
 		"very/long/path/to/file/that/should/be/truncated...",
-		"0.0%",
+		"0.0%      n/a",
+		"File Cov",
+		"62.5%",
 		"pkg/util.go",
 		"100.0%",
 		"TOTAL DIFF",
@@ -247,51 +556,238 @@ func TestFormatDiffCoverage(t *testing.T) {
 		Coverage:     25.0,
 	}
 
-	output2 := FormatDiffCoverage(manyUncovered)
+	output2 := FormatDiffCoverage(manyUncovered, 0, gocov.DefaultPrecision)
 	if !strings.Contains(output2, "... (5 more)") {
 		t.Error("FormatDiffCoverage() should truncate long uncovered lines list")
 	}
+
+	// A narrow file column truncates earlier and shrinks the rules
+	narrow := FormatDiffCoverage(summary, 20, gocov.DefaultPrecision)
+	if !strings.Contains(narrow, "\nvery/long/path/to...          5") || !strings.Contains(narrow, "\n"+strings.Repeat("=", 60)+"\n") {
+		t.Errorf("FormatDiffCoverage() with width 20 should truncate to 20 columns\nGot: %s", narrow)
+	}
+	// The rules span the rows exactly
+	for _, line := range strings.Split(narrow, "\n")[1:4] {
+		if len(line) != 20+diffColumnsWidth {
+			t.Errorf("Line %q is %d wide, want %d", line, len(line), 20+diffColumnsWidth)
+		}
+	}
+	if !strings.HasPrefix(FormatDiffCoverageTotal(summary, 20, gocov.DefaultPrecision), "TOTAL DIFF           ") {
+		t.Errorf("FormatDiffCoverageTotal() should pad to the width, got %q", FormatDiffCoverageTotal(summary, 20, gocov.DefaultPrecision))
+	}
 }
 
-func TestTruncateString(t *testing.T) {
-	tests := []struct {
-		name   string
-		input  string
-		maxLen int
-		want   string
-	}{
-		{
-			name:   "short string",
-			input:  "hello",
-			maxLen: 10,
-			want:   "hello",
-		},
-		{
-			name:   "exact length",
-			input:  "hello",
-			maxLen: 5,
-			want:   "hello",
+func TestFormatDiffCoverageJSON(t *testing.T) {
+	summary := &DiffCoverageSummary{
+		Results: []DiffCoverageResult{
+			{
+				File:           "main.go",
+				TotalLines:     3,
+				CoveredLines:   1,
+				UncoveredLines: []int{15, 16},
+				Coverage:       33.3,
+				FileCoverage:   50.0,
+				ProfileMatched: true,
+			},
+			{
+				File:         "pkg/util.go",
+				TotalLines:   2,
+				CoveredLines: 2,
+				Coverage:     100.0,
+			},
 		},
-		{
-			name:   "needs truncation",
-			input:  "this is a very long string",
-			maxLen: 10,
-			want:   "this is...",
+		TotalLines:   5,
+		CoveredLines: 3,
+		Coverage:     60.0,
+	}
+
+	output, err := FormatDiffCoverageJSON(summary, true, false)
+	if err != nil {
+		t.Fatalf("FormatDiffCoverageJSON failed: %v", err)
+	}
+
+	var got struct {
+		Version      string               `json:"version"`
+		Results      []DiffCoverageResult `json:"results"`
+		TotalLines   int                  `json:"total_lines"`
+		CoveredLines int                  `json:"covered_lines"`
+		Coverage     float64              `json:"coverage"`
+	}
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+
+	if got.Version != gocov.JSONSchemaVersion {
+		t.Errorf("version = %q, want %q", got.Version, gocov.JSONSchemaVersion)
+	}
+	if got.TotalLines != 5 || got.CoveredLines != 3 || got.Coverage != 60.0 {
+		t.Errorf("Unexpected totals: %+v", got)
+	}
+	if len(got.Results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(got.Results))
+	}
+	if !reflect.DeepEqual(got.Results[0].UncoveredLines, []int{15, 16}) {
+		t.Errorf("Unexpected uncovered lines: %v", got.Results[0].UncoveredLines)
+	}
+	if !strings.Contains(output, `"uncovered_lines": []`) {
+		t.Error("Files without uncovered lines should emit an empty uncovered_lines array")
+	}
+}
+
+func TestCheckDiffFileThresholds(t *testing.T) {
+	summary := &DiffCoverageSummary{
+		Results: []DiffCoverageResult{
+			{File: "pkg/util.go", TotalLines: 4, CoveredLines: 2, Coverage: 50.0},
+			{File: "main.go", TotalLines: 10, CoveredLines: 6, Coverage: 60.0},
+			{File: "cmd/server.go", TotalLines: 5, CoveredLines: 5, Coverage: 100.0},
 		},
-		{
-			name:   "very short max",
-			input:  "hello",
-			maxLen: 3,
-			want:   "...",
+		TotalLines:   19,
+		CoveredLines: 13,
+		Coverage:     68.4,
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		if violations := CheckDiffFileThresholds(summary, 0, gocov.DefaultPrecision); len(violations) != 0 {
+			t.Errorf("Expected no violations, got %v", violations)
+		}
+	})
+
+	t.Run("files below threshold", func(t *testing.T) {
+		violations := CheckDiffFileThresholds(summary, 70, gocov.DefaultPrecision)
+		if len(violations) != 2 {
+			t.Fatalf("Expected 2 violations, got %v", violations)
+		}
+		if violations[0].Directory != "main.go" || violations[1].Directory != "pkg/util.go" {
+			t.Errorf("Unexpected violations: %+v", violations)
+		}
+		if violations[1].Actual != 50.0 || violations[1].Threshold != 70 {
+			t.Errorf("Unexpected violation values: %+v", violations[1])
+		}
+
+		err := NewThresholdErrorWithViolations(0, summary.Coverage, violations, gocov.DefaultPrecision)
+		if !strings.Contains(err.Error(), "pkg/util.go coverage 50.0% is below threshold 70.0%") {
+			t.Errorf("Error should name the offending file, got %q", err.Error())
+		}
+	})
+
+	t.Run("all files pass", func(t *testing.T) {
+		if violations := CheckDiffFileThresholds(summary, 50, gocov.DefaultPrecision); len(violations) != 0 {
+			t.Errorf("Expected no violations, got %v", violations)
+		}
+	})
+}
+
+func TestFormatGitHubAnnotations(t *testing.T) {
+	summary := &DiffCoverageSummary{
+		Results: []DiffCoverageResult{
+			{
+				File:           "main.go",
+				TotalLines:     3,
+				CoveredLines:   1,
+				UncoveredLines: []int{15, 16},
+				Coverage:       33.3,
+			},
+			{
+				File:           "pkg/util.go",
+				TotalLines:     2,
+				CoveredLines:   2,
+				UncoveredLines: nil,
+				Coverage:       100.0,
+			},
 		},
+		TotalLines:   5,
+		CoveredLines: 3,
+		Coverage:     60.0,
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := truncateString(tt.input, tt.maxLen)
-			if got != tt.want {
-				t.Errorf("truncateString(%q, %d) = %q, want %q", tt.input, tt.maxLen, got, tt.want)
+	got := FormatGitHubAnnotations(summary)
+	want := "::warning file=main.go,line=15::line not covered\n" +
+		"::warning file=main.go,line=16::line not covered\n"
+	if got != want {
+		t.Errorf("FormatGitHubAnnotations() = %q, want %q", got, want)
+	}
+
+	empty := FormatGitHubAnnotations(&DiffCoverageSummary{})
+	if empty != "" {
+		t.Errorf("FormatGitHubAnnotations() with no results = %q, want empty", empty)
+	}
+}
+
+// benchmarkDiff returns n profiles spread over packages that reuse the same file names, and a diff changing every tenth file
+func benchmarkDiff(n int) ([]*cover.Profile, *GitDiff) {
+	profiles := make([]*cover.Profile, 0, n)
+	var lines []DiffLine
+	for i := 0; i < n; i++ {
+		file := fmt.Sprintf("pkg/module%d/sub%d/file%d.go", i/100, i%10, i%20)
+		profiles = append(profiles, &cover.Profile{
+			FileName: "github.com/example/project/" + file,
+			Mode:     "set",
+			Blocks: []cover.ProfileBlock{
+				{StartLine: 1, StartCol: 1, EndLine: 10, EndCol: 2, NumStmt: 5, Count: 1},
+				{StartLine: 11, StartCol: 1, EndLine: 20, EndCol: 2, NumStmt: 5, Count: 0},
+			},
+		})
+		if i%10 == 0 {
+			for line := 5; line <= 15; line++ {
+				lines = append(lines, DiffLine{File: file, LineNum: line})
+			}
+		}
+	}
+	return profiles, NewGitDiffFromLines("main", lines)
+}
+
+// BenchmarkCalculateDiffCoverage compares the indexed lookup with scanning every profile for each changed file
+func BenchmarkCalculateDiffCoverage(b *testing.B) {
+	for _, n := range []int{1000, 5000} {
+		profiles, diff := benchmarkDiff(n)
+
+		b.Run(fmt.Sprintf("Indexed%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = CalculateDiffCoverage(profiles, diff, nil)
+			}
+		})
+		files := make(map[string]bool)
+		for _, line := range diff.Lines {
+			files[line.File] = true
+		}
+		b.Run(fmt.Sprintf("Scan%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for file := range files {
+					_ = FindMatchingProfile(profiles, file)
+				}
 			}
 		})
 	}
 }
+
+func TestFormatDiffCoverageJSONQuiet(t *testing.T) {
+	summary := &DiffCoverageSummary{
+		Results: []DiffCoverageResult{
+			{File: "main.go", TotalLines: 3, CoveredLines: 1, UncoveredLines: []int{15, 16}, Coverage: 33.3},
+		},
+		TotalLines:     3,
+		CoveredLines:   1,
+		Coverage:       33.3,
+		RegressedLines: 1,
+	}
+
+	output, err := FormatDiffCoverageJSON(summary, false, true)
+	if err != nil {
+		t.Fatalf("FormatDiffCoverageJSON failed: %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	want := map[string]any{
+		"version":         gocov.JSONSchemaVersion,
+		"total_lines":     float64(3),
+		"covered_lines":   float64(1),
+		"coverage":        33.3,
+		"regressed_lines": float64(1),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("quiet output = %v, want %v", got, want)
+	}
+}
@@ -1,7 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"golang.org/x/tools/cover"
 )
 
 func TestParseHunkHeader(t *testing.T) {
@@ -68,6 +76,61 @@ func TestParseHunkHeader(t *testing.T) {
 	}
 }
 
+func TestParseDiffRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseRef  string
+		wantFrom string
+		wantTo   string
+		wantOK   bool
+	}{
+		{"tag range", "v1.2.0..v1.3.0", "v1.2.0", "v1.3.0", true},
+		{"commit range", "abc123..def456", "abc123", "def456", true},
+		{"relative refs", "HEAD~3..HEAD~1", "HEAD~3", "HEAD~1", true},
+		{"open end", "main..", "main", "HEAD", true},
+		{"open start", "..feature", "HEAD", "feature", true},
+		{"single ref", "main", "", "", false},
+		{"relative single ref", "HEAD~1", "", "", false},
+		{"symmetric range", "main...feature", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, ok := parseDiffRange(tt.baseRef)
+			if ok != tt.wantOK {
+				t.Fatalf("parseDiffRange(%q) ok = %v, want %v", tt.baseRef, ok, tt.wantOK)
+			}
+			if from != tt.wantFrom || to != tt.wantTo {
+				t.Errorf("parseDiffRange(%q) = (%q, %q), want (%q, %q)", tt.baseRef, from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestExecuteGitDiffCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseRef   string
+		extraArgs []string
+		wantArgs  []string
+	}{
+		{"single ref", "main", nil, []string{"git", "diff", "main", "HEAD"}},
+		{"range", "v1.2.0..v1.3.0", []string{"--name-only"}, []string{"git", "diff", "v1.2.0", "v1.3.0", "--name-only"}},
+		{"staged", "staged", nil, []string{"git", "diff", "--cached"}},
+		{"cached", "cached", nil, []string{"git", "diff", "--cached"}},
+		{"working", "working", []string{"--", "main.go"}, []string{"git", "diff", "--", "main.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := executeGitDiffCommand(context.Background(), tt.baseRef, tt.extraArgs...)
+			if !reflect.DeepEqual(cmd.Args, tt.wantArgs) {
+				t.Errorf("executeGitDiffCommand(%q) args = %v, want %v", tt.baseRef, cmd.Args, tt.wantArgs)
+			}
+		})
+	}
+}
+
 func TestGetAddedLinesFromHunk(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -193,21 +256,37 @@ func TestParseFileDiff(t *testing.T) {
  	done()
  }`,
 			want: []DiffLine{
-				{File: "test.go", LineNum: 6, ChangeType: "added"},
+				{File: "test.go", LineNum: 6, ChangeType: "modified", BaseLineNum: 6},
+			},
+		},
+		{
+			name:     "modified lines followed by additions after context",
+			filename: "mixed.go",
+			diffContent: `@@ -1,4 +1,5 @@
+-	old()
++	replaced()
++	alsoReplaced()
+ 	keep()
++	brandNew()
+ }`,
+			want: []DiffLine{
+				{File: "mixed.go", LineNum: 1, ChangeType: "modified", BaseLineNum: 1},
+				{File: "mixed.go", LineNum: 2, ChangeType: "modified"},
+				{File: "mixed.go", LineNum: 4, ChangeType: "added"},
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := parseFileDiff(tt.filename, tt.diffContent)
+			got := parseFileDiff(tt.filename, tt.diffContent, 0)
 			if len(got) != len(tt.want) {
 				t.Errorf("parseFileDiff() returned %d lines, want %d", len(got), len(tt.want))
 				return
 			}
 			for i, line := range got {
 				if line.File != tt.want[i].File || line.LineNum != tt.want[i].LineNum ||
-					line.ChangeType != tt.want[i].ChangeType {
+					line.ChangeType != tt.want[i].ChangeType || line.BaseLineNum != tt.want[i].BaseLineNum {
 					t.Errorf("parseFileDiff()[%d] = %v, want %v", i, line, tt.want[i])
 				}
 			}
@@ -215,39 +294,298 @@ func TestParseFileDiff(t *testing.T) {
 	}
 }
 
-func TestNormalizeFilePath(t *testing.T) {
+func TestParseFileDiffContext(t *testing.T) {
+	diffContent := `@@ -1,9 +1,9 @@
+ func a() {
+ 	one()
+ 	two()
++	added()
+ 	three()
+ 	four()
+ 	five()
+-	removed()
+ 	six()
+ }`
+
 	tests := []struct {
-		name string
-		path string
-		want string
+		name         string
+		contextLines int
+		want         []DiffLine
 	}{
 		{
-			name: "relative path",
-			path: "main.go",
-			want: "main.go",
-		},
-		{
-			name: "relative with dot",
-			path: "./main.go",
-			want: "main.go",
+			name:         "no context",
+			contextLines: 0,
+			want: []DiffLine{
+				{File: "ctx.go", LineNum: 4, ChangeType: "added"},
+			},
 		},
 		{
-			name: "absolute path",
-			path: "/home/user/project/main.go",
-			want: "/home/user/project/main.go",
+			name:         "one line",
+			contextLines: 1,
+			want: []DiffLine{
+				{File: "ctx.go", LineNum: 3, ChangeType: "context", BaseLineNum: 3},
+				{File: "ctx.go", LineNum: 4, ChangeType: "added"},
+				{File: "ctx.go", LineNum: 5, ChangeType: "context", BaseLineNum: 4},
+				{File: "ctx.go", LineNum: 7, ChangeType: "context", BaseLineNum: 6},
+				{File: "ctx.go", LineNum: 8, ChangeType: "context", BaseLineNum: 8},
+			},
 		},
 		{
-			name: "nested relative",
-			path: "internal/service/user.go",
-			want: "internal/service/user.go",
+			name:         "two lines",
+			contextLines: 2,
+			want: []DiffLine{
+				{File: "ctx.go", LineNum: 2, ChangeType: "context", BaseLineNum: 2},
+				{File: "ctx.go", LineNum: 3, ChangeType: "context", BaseLineNum: 3},
+				{File: "ctx.go", LineNum: 4, ChangeType: "added"},
+				{File: "ctx.go", LineNum: 5, ChangeType: "context", BaseLineNum: 4},
+				{File: "ctx.go", LineNum: 6, ChangeType: "context", BaseLineNum: 5},
+				{File: "ctx.go", LineNum: 7, ChangeType: "context", BaseLineNum: 6},
+				{File: "ctx.go", LineNum: 8, ChangeType: "context", BaseLineNum: 8},
+				{File: "ctx.go", LineNum: 9, ChangeType: "context", BaseLineNum: 9},
+			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := normalizeFilePath(tt.path)
-			if got != tt.want {
-				t.Errorf("normalizeFilePath(%q) = %q, want %q", tt.path, got, tt.want)
+			got := parseFileDiff("ctx.go", diffContent, tt.contextLines)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFileDiff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("context stays within its hunk", func(t *testing.T) {
+		got := parseFileDiff("ctx.go", "@@ -1,2 +1,3 @@\n a()\n+b()\n@@ -10,2 +11,2 @@\n c()\n d()\n", 5)
+		want := []DiffLine{
+			{File: "ctx.go", LineNum: 1, ChangeType: "context", BaseLineNum: 1},
+			{File: "ctx.go", LineNum: 2, ChangeType: "added"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("parseFileDiff() = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestFilterDiffLines(t *testing.T) {
+	lines := []DiffLine{
+		{File: "a.go", LineNum: 1, ChangeType: "added"},
+		{File: "a.go", LineNum: 2, ChangeType: "modified"},
+		{File: "b.go", LineNum: 3, ChangeType: "added"},
+		{File: "b.go", LineNum: 4, ChangeType: "context", BaseLineNum: 3},
+	}
+
+	got := FilterDiffLines(lines, "added")
+	want := []DiffLine{lines[0], lines[2]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterDiffLines(added) = %v, want %v", got, want)
+	}
+
+	if got, want := FilterDiffLines(lines, "added", "modified"), lines[:3]; !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterDiffLines(added, modified) = %v, want %v", got, want)
+	}
+
+	if got, want := FilterDiffLines(lines, "context"), lines[3:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterDiffLines(context) = %v, want %v", got, want)
+	}
+}
+
+func TestParseNameStatus(t *testing.T) {
+	output := "M\tcli.go\nA\tpkg/new.go\nR100\tpkg/old/util.go\tpkg/util/util.go\nC075\tbase.go\tcopy.go\nD\tremoved.go\n"
+
+	files, renames := parseNameStatus(output)
+
+	wantFiles := []string{"cli.go", "pkg/new.go", "pkg/util/util.go", "copy.go", "removed.go"}
+	if !reflect.DeepEqual(files, wantFiles) {
+		t.Errorf("parseNameStatus() files = %v, want %v", files, wantFiles)
+	}
+
+	wantRenames := map[string]string{"pkg/util/util.go": "pkg/old/util.go"}
+	if !reflect.DeepEqual(renames, wantRenames) {
+		t.Errorf("parseNameStatus() renames = %v, want %v", renames, wantRenames)
+	}
+}
+
+func TestParseUnifiedDiff(t *testing.T) {
+	patch := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -10,2 +10,3 @@ func main() {
+ 	a := 1
++	b := 2
+ 	c := 3
+diff --git a/README.md b/README.md
+--- a/README.md
++++ b/README.md
+@@ -1 +1,2 @@
+ # title
++new line
+diff --git a/pkg/old/util.go b/pkg/util/util.go
+similarity index 90%
+rename from pkg/old/util.go
+rename to pkg/util/util.go
+--- a/pkg/old/util.go
++++ b/pkg/util/util.go
+@@ -5,0 +6,2 @@
++func added() {}
++func another() {}
+diff --git a/removed.go b/removed.go
+deleted file mode 100644
+--- a/removed.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+-package main
+-
+`
+
+	diff, err := ParseUnifiedDiff(strings.NewReader(patch), 0)
+	if err != nil {
+		t.Fatalf("ParseUnifiedDiff() unexpected error: %v", err)
+	}
+
+	want := []DiffLine{
+		{File: "main.go", LineNum: 11, ChangeType: "added"},
+		{File: "pkg/util/util.go", LineNum: 6, ChangeType: "added"},
+		{File: "pkg/util/util.go", LineNum: 7, ChangeType: "added"},
+	}
+	if !reflect.DeepEqual(diff.Lines, want) {
+		t.Errorf("ParseUnifiedDiff() lines = %+v, want %+v", diff.Lines, want)
+	}
+
+	wantRenames := map[string]string{"pkg/util/util.go": "pkg/old/util.go"}
+	if !reflect.DeepEqual(diff.Renames, wantRenames) {
+		t.Errorf("ParseUnifiedDiff() renames = %v, want %v", diff.Renames, wantRenames)
+	}
+}
+
+func TestParseUnifiedDiffPlainDiff(t *testing.T) {
+	// Output of "diff -u" has timestamps and no "diff --git" header
+	patch := "--- main.go.orig\t2024-01-01 00:00:00\n+++ main.go\t2024-01-02 00:00:00\n@@ -1 +1,2 @@\n package main\n+var x = 1\n"
+
+	diff, err := ParseUnifiedDiff(strings.NewReader(patch), 0)
+	if err != nil {
+		t.Fatalf("ParseUnifiedDiff() unexpected error: %v", err)
+	}
+
+	want := []DiffLine{{File: "main.go", LineNum: 2, ChangeType: "added"}}
+	if !reflect.DeepEqual(diff.Lines, want) {
+		t.Errorf("ParseUnifiedDiff() lines = %+v, want %+v", diff.Lines, want)
+	}
+}
+
+func TestParseUnifiedDiffExtensions(t *testing.T) {
+	patch := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1 +1,2 @@
+ package main
++var x = 1
+diff --git a/views/index.tmpl b/views/index.tmpl
+--- a/views/index.tmpl
++++ b/views/index.tmpl
+@@ -3,0 +4 @@
++{{ .Title }}
+diff --git a/query.sql b/query.sql
+--- a/query.sql
++++ b/query.sql
+@@ -1,0 +2 @@
++SELECT 1;
+`
+
+	diff, err := ParseUnifiedDiff(strings.NewReader(patch), 0, SplitPatterns(".go,.tmpl")...)
+	if err != nil {
+		t.Fatalf("ParseUnifiedDiff() unexpected error: %v", err)
+	}
+
+	want := []DiffLine{
+		{File: "main.go", LineNum: 2, ChangeType: "added"},
+		{File: "views/index.tmpl", LineNum: 4, ChangeType: "added"},
+	}
+	if !reflect.DeepEqual(diff.Lines, want) {
+		t.Errorf("ParseUnifiedDiff() lines = %+v, want %+v", diff.Lines, want)
+	}
+}
+
+func TestNewGitDiffFromLines(t *testing.T) {
+	lines := []DiffLine{
+		{File: "pkg/handler.go", LineNum: 3, ChangeType: "added"},
+		{File: "pkg/handler.go", LineNum: 9, ChangeType: "modified"},
+	}
+
+	diff := NewGitDiffFromLines("api", lines)
+	if diff.BaseRef != "api" || !reflect.DeepEqual(diff.Lines, lines) {
+		t.Errorf("NewGitDiffFromLines() = %+v", diff)
+	}
+	if diff.Renames == nil || len(diff.Renames) != 0 {
+		t.Errorf("NewGitDiffFromLines() renames = %v, want an empty map", diff.Renames)
+	}
+	lines[0].LineNum = 100
+	if diff.Lines[0].LineNum != 3 {
+		t.Error("NewGitDiffFromLines() should copy the lines")
+	}
+
+	if empty := NewGitDiffFromLines("api", nil); empty.Lines == nil {
+		t.Error("NewGitDiffFromLines() should not leave Lines nil")
+	}
+
+	// The result can be used for diff coverage without running git
+	profiles := []*cover.Profile{{
+		FileName: "github.com/example/project/pkg/handler.go",
+		Mode:     "set",
+		Blocks:   []cover.ProfileBlock{{StartLine: 1, EndLine: 5, NumStmt: 2, Count: 1}},
+	}}
+	summary := CalculateDiffCoverage(profiles, NewGitDiffFromLines("api", []DiffLine{{File: "pkg/handler.go", LineNum: 3, ChangeType: "added"}}), nil)
+	if summary.TotalLines != 1 || summary.CoveredLines != 1 {
+		t.Errorf("CalculateDiffCoverage() = %+v, want 1/1 lines covered", summary)
+	}
+}
+
+func TestCalculateDiffCoverageStripPrefix(t *testing.T) {
+	profiles := []*cover.Profile{
+		{FileName: "example.com/api/pkg/handler.go", Mode: "set", Blocks: []cover.ProfileBlock{{StartLine: 1, EndLine: 5, NumStmt: 1, Count: 1}}},
+		{FileName: "example.com/api/pkg/old.go", Mode: "set", Blocks: []cover.ProfileBlock{{StartLine: 1, EndLine: 5, NumStmt: 1, Count: 1}}},
+	}
+	diff := NewGitDiffFromLines("main", []DiffLine{
+		{File: "services/api/pkg/handler.go", LineNum: 3, ChangeType: "added"},
+		{File: "services/api/pkg/new.go", LineNum: 2, ChangeType: "added"},
+	})
+	diff.Renames["services/api/pkg/new.go"] = "services/api/pkg/old.go"
+	diff.StripPrefix = "services/api/"
+
+	summary := CalculateDiffCoverage(profiles, diff, nil)
+	var files []string
+	for _, result := range summary.Results {
+		files = append(files, result.File)
+		if !result.ProfileMatched || result.CoveredLines != 1 {
+			t.Errorf("%s: ProfileMatched = %v, CoveredLines = %d, want matched and covered", result.File, result.ProfileMatched, result.CoveredLines)
+		}
+	}
+	// Results keep the paths of the diff, which annotations need
+	want := []string{"services/api/pkg/handler.go", "services/api/pkg/new.go"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("result files = %q, want %q", files, want)
+	}
+}
+
+func TestHasDiffExtension(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		extensions []string
+		want       bool
+	}{
+		{"default go", "main.go", nil, true},
+		{"default skips templates", "index.tmpl", nil, false},
+		{"custom extension", "index.tmpl", []string{".go", ".tmpl"}, true},
+		{"go still matched", "main.go", []string{".go", ".tmpl"}, true},
+		{"unlisted extension", "query.sql", []string{".go", ".tmpl"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasDiffExtension(tt.file, tt.extensions); got != tt.want {
+				t.Errorf("hasDiffExtension(%q, %v) = %v, want %v", tt.file, tt.extensions, got, tt.want)
 			}
 		})
 	}
@@ -269,11 +607,65 @@ index abc123..def456 100644
 +	fmt.Println("Hello, World!")
  }`
 
+func TestGetMergeBase(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("Skipping git-dependent test - git not installed")
+	}
+	head, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		t.Skip("Skipping git-dependent test - not in a git repository")
+	}
+
+	t.Run("first existing branch wins", func(t *testing.T) {
+		got, err := getMergeBase(context.Background(), "gocov-no-such-branch", "HEAD")
+		if err != nil {
+			t.Fatalf("getMergeBase() unexpected error: %v", err)
+		}
+		if got != strings.TrimSpace(string(head)) {
+			t.Errorf("getMergeBase() = %q, want %q", got, strings.TrimSpace(string(head)))
+		}
+	})
+
+	t.Run("no branch exists", func(t *testing.T) {
+		_, err := getMergeBase(context.Background(), "gocov-no-such-branch", "gocov-other-missing-branch")
+		if err == nil || !strings.Contains(err.Error(), "gocov-no-such-branch, gocov-other-missing-branch") {
+			t.Errorf("getMergeBase() error = %v, want error naming the candidates", err)
+		}
+	})
+}
+
+func TestGitTimeout(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("Skipping git-dependent test - git not installed")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	t.Run("merge base", func(t *testing.T) {
+		if _, err := getMergeBase(ctx, "HEAD"); !errors.Is(err, ErrGitTimeout) {
+			t.Errorf("getMergeBase() error = %v, want ErrGitTimeout", err)
+		}
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		if _, err := GetGitDiff(ctx, "HEAD"); !errors.Is(err, ErrGitTimeout) {
+			t.Errorf("GetGitDiff() error = %v, want ErrGitTimeout", err)
+		}
+	})
+
+	t.Run("diff with context", func(t *testing.T) {
+		// The merge base lookup times out first instead of falling back to HEAD~1
+		if _, err := GetGitDiffWithContext(ctx, "", nil, 0); !errors.Is(err, ErrGitTimeout) {
+			t.Errorf("GetGitDiffWithContext() error = %v, want ErrGitTimeout", err)
+		}
+	})
+}
+
 // TestGetGitDiff tests git diff parsing
 // Note: This test requires manual mocking or will be skipped in environments without git
 func TestGetGitDiff(t *testing.T) {
 	// Skip if not in a git repository
-	if _, err := getMergeBase(); err != nil {
+	if _, err := getMergeBase(context.Background()); err != nil {
 		t.Skip("Skipping git-dependent test - not in a git repository")
 	}
 
@@ -302,11 +694,16 @@ func TestGetGitDiff(t *testing.T) {
 			baseRef: "working",
 			wantErr: false,
 		},
+		{
+			name:    "explicit range",
+			baseRef: "HEAD~1..HEAD",
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := GetGitDiff(tt.baseRef)
+			got, err := GetGitDiff(context.Background(), tt.baseRef)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetGitDiff() error = %v, wantErr %v", err, tt.wantErr)
 				return
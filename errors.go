@@ -3,15 +3,28 @@ package main
 import (
 	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/blck-snwmn/gocov/pkg/gocov"
+)
+
+// Exit codes
+const (
+	ExitSuccess        = 0
+	ExitError          = 1
+	ExitThresholdError = 2
 )
 
 // Error types
 var (
 	// Configuration errors
-	ErrNoInput        = errors.New("coverprofile is required")
-	ErrInvalidFormat  = errors.New("invalid output format")
-	ErrConfigNotFound = errors.New("configuration file not found")
-	ErrInvalidConfig  = errors.New("invalid configuration")
+	ErrNoInput          = errors.New("coverprofile is required")
+	ErrInvalidFormat    = errors.New("invalid output format")
+	ErrConfigNotFound   = errors.New("configuration file not found")
+	ErrInvalidConfig    = errors.New("invalid configuration")
+	ErrConfigExists     = errors.New("configuration file already exists (use -force to overwrite)")
+	ErrWatchNotTerminal = errors.New("watch mode requires output to an interactive terminal")
+	ErrStreamConflict   = errors.New("cannot be combined with -stream, which reads a single profile without keeping it")
 
 	// Validation errors
 	ErrInvalidMinCoverage = errors.New("min must be between 0 and 100")
@@ -19,7 +32,16 @@ var (
 	ErrMinGreaterThanMax  = errors.New("min cannot be greater than max")
 
 	// Parse errors
-	ErrParseCoverage = errors.New("failed to parse coverage profile")
+	ErrParseCoverage    = errors.New("failed to parse coverage profile")
+	ErrEmptyProfile     = errors.New("coverage profile contains no statements")
+	ErrMixedModes       = errors.New("coverage profiles use different modes")
+	ErrNoProfileMatch   = errors.New("no coverage profile matches the pattern")
+	ErrEmptyProfileList = errors.New("coverage profile list names no files")
+	ErrMalformedProfile = errors.New("coverage profile contains malformed blocks")
+	ErrBaselineNoTotal  = errors.New("baseline report has no total coverage (reports written with -no-total cannot be baselines)")
+
+	// Git errors
+	ErrGitTimeout = errors.New("git command timed out (raise -git-timeout if the repository is slow)")
 )
 
 // ConfigError represents a configuration-related error
@@ -90,18 +112,62 @@ func NewParseError(file string, err error) error {
 
 // ThresholdError represents a threshold check failure
 type ThresholdError struct {
-	Threshold float64
-	Actual    float64
+	Threshold  float64
+	Actual     float64
+	Violations []gocov.ThresholdViolation
+	// Precision is the number of decimal places coverage and thresholds are shown with in the message
+	Precision int
 }
 
 func (e *ThresholdError) Error() string {
-	return fmt.Sprintf("coverage %.1f%% is below threshold %.1f%%", e.Actual, e.Threshold)
+	if len(e.Violations) == 0 {
+		return fmt.Sprintf("coverage %s%% is below threshold %s%%", gocov.FormatCoverage(e.Actual, e.Precision), gocov.FormatCoverage(e.Threshold, e.Precision))
+	}
+
+	var parts []string
+	if e.Threshold > 0 && gocov.BelowThreshold(e.Actual, e.Threshold, e.Precision) {
+		parts = append(parts, fmt.Sprintf("coverage %s%% is below threshold %s%%", gocov.FormatCoverage(e.Actual, e.Precision), gocov.FormatCoverage(e.Threshold, e.Precision)))
+	}
+	for _, v := range e.Violations {
+		actual := gocov.FormatCoverage(v.Actual, e.Precision)
+		threshold := gocov.FormatCoverage(v.Threshold, e.Precision)
+		if v.Pattern == "" {
+			parts = append(parts, fmt.Sprintf("%s coverage %s%% is below threshold %s%%", v.Directory, actual, threshold))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s coverage %s%% is below threshold %s%% (%s)", v.Directory, actual, threshold, v.Pattern))
+	}
+	return strings.Join(parts, "; ")
 }
 
-// NewThresholdError creates a new ThresholdError
-func NewThresholdError(threshold, actual float64) error {
+// NewThresholdError creates a new ThresholdError showing coverage with precision decimal places
+func NewThresholdError(threshold, actual float64, precision int) error {
 	return &ThresholdError{
 		Threshold: threshold,
 		Actual:    actual,
+		Precision: precision,
+	}
+}
+
+// NewThresholdErrorWithViolations creates a new ThresholdError that also lists per-directory violations
+func NewThresholdErrorWithViolations(threshold, actual float64, violations []gocov.ThresholdViolation, precision int) error {
+	return &ThresholdError{
+		Threshold:  threshold,
+		Actual:     actual,
+		Violations: violations,
+		Precision:  precision,
+	}
+}
+
+// ExitCode returns the process exit code for an error returned by CLI.Run
+// Threshold failures use a distinct code so CI can tell them apart from tool errors
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitSuccess
+	}
+	var thresholdErr *ThresholdError
+	if errors.As(err, &thresholdErr) {
+		return ExitThresholdError
 	}
+	return ExitError
 }
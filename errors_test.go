@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/blck-snwmn/gocov/pkg/gocov"
 )
 
 func TestConfigError(t *testing.T) {
@@ -101,6 +103,7 @@ func TestErrorConstants(t *testing.T) {
 		{ErrInvalidFormat, "invalid output format"},
 		{ErrConfigNotFound, "configuration file not found"},
 		{ErrInvalidConfig, "invalid configuration"},
+		{ErrConfigExists, "configuration file already exists"},
 		{ErrInvalidMinCoverage, "min must be between 0 and 100"},
 		{ErrInvalidMaxCoverage, "max must be between 0 and 100"},
 		{ErrMinGreaterThanMax, "min cannot be greater than max"},
@@ -160,3 +163,25 @@ func TestErrorTypes(t *testing.T) {
 		}
 	})
 }
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil error", nil, ExitSuccess},
+		{"usage error", ErrNoInput, ExitError},
+		{"parse error", NewParseError("test.out", ErrParseCoverage), ExitError},
+		{"threshold error", NewThresholdError(80, 70, gocov.DefaultPrecision), ExitThresholdError},
+		{"wrapped threshold error", fmt.Errorf("wrapped: %w", NewThresholdError(80, 70, gocov.DefaultPrecision)), ExitThresholdError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
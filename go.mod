@@ -5,3 +5,7 @@ go 1.25.0
 require golang.org/x/tools v0.33.0
 
 require gopkg.in/yaml.v3 v3.0.1
+
+require github.com/BurntSushi/toml v1.5.0
+
+require golang.org/x/mod v0.24.0
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// HistoryEntry is the total coverage of one run recorded with -history
+type HistoryEntry struct {
+	// Time is when the run finished, in RFC 3339 format (UTC)
+	Time     string  `json:"time"`
+	Coverage float64 `json:"coverage"`
+}
+
+// LoadHistory reads the coverage history at path, oldest run first
+// A missing file is an empty history; a file that is not a JSON array of entries is a ParseError
+func LoadHistory(path string) ([]HistoryEntry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, NewConfigError("history", path, err)
+	}
+
+	var entries []HistoryEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, NewParseError(path, err)
+	}
+	return entries, nil
+}
+
+// RecordHistory appends coverage with the current time to the history at path and returns the updated history
+// A corrupt history file is left untouched so that no earlier runs are lost; the ParseError is returned instead
+func RecordHistory(path string, coverage float64) ([]HistoryEntry, error) {
+	entries, err := LoadHistory(path)
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, HistoryEntry{
+		Time:     time.Now().UTC().Format(time.RFC3339),
+		Coverage: coverage,
+	})
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return nil, NewConfigError("history", path, err)
+	}
+	return entries, nil
+}
+
+// HistorySparkline draws the coverage of the last n entries
+func HistorySparkline(entries []HistoryEntry, n int) string {
+	if len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	coverages := make([]float64, len(entries))
+	for i, entry := range entries {
+		coverages[i] = entry.Coverage
+	}
+	return RenderSparkline(coverages)
+}
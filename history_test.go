@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".gocov-history.json")
+
+	for _, coverage := range []float64{74, 76.2} {
+		if _, err := RecordHistory(path, coverage); err != nil {
+			t.Fatalf("RecordHistory(%v) failed: %v", coverage, err)
+		}
+	}
+	entries, err := LoadHistory(path)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Coverage != 74 || entries[1].Coverage != 76.2 {
+		t.Fatalf("LoadHistory() = %+v, want 74 then 76.2", entries)
+	}
+	if _, err := time.Parse(time.RFC3339, entries[1].Time); err != nil {
+		t.Errorf("Time %q is not RFC 3339: %v", entries[1].Time, err)
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		entries, err := LoadHistory(filepath.Join(t.TempDir(), "missing.json"))
+		if err != nil || len(entries) != 0 {
+			t.Errorf("LoadHistory() = %v, %v, want an empty history", entries, err)
+		}
+	})
+
+	t.Run("corrupt file is kept", func(t *testing.T) {
+		corrupt := filepath.Join(t.TempDir(), "corrupt.json")
+		if err := os.WriteFile(corrupt, []byte("{not json"), 0644); err != nil {
+			t.Fatalf("Failed to write history: %v", err)
+		}
+		_, err := RecordHistory(corrupt, 80)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("Expected ParseError, got: %v", err)
+		}
+		if data, _ := os.ReadFile(corrupt); string(data) != "{not json" {
+			t.Errorf("Corrupt history was overwritten: %q", data)
+		}
+	})
+}
+
+func TestHistorySparkline(t *testing.T) {
+	entries := []HistoryEntry{{Coverage: 90}, {Coverage: 74}, {Coverage: 76.2}, {Coverage: 75.1}}
+	if got := HistorySparkline(entries, 3); got != "▁█▄" {
+		t.Errorf("HistorySparkline(3) = %q, want %q", got, "▁█▄")
+	}
+	if got := HistorySparkline(entries, 10); got != "█▁▁▁" {
+		t.Errorf("HistorySparkline(10) = %q, want %q", got, "█▁▁▁")
+	}
+}
+
+func TestHistoryMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".gocov-history.json")
+	args := []string{"-coverprofile", "testdata/coverage.out", "-quiet", "-history", path, "-history-sparkline", "5"}
+
+	var buf bytes.Buffer
+	for range 2 {
+		buf.Reset()
+		if err := NewCLI(&buf, args).Run(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if !strings.HasSuffix(buf.String(), "History (last 2 runs): ▁▁\n") {
+		t.Errorf("Expected a sparkline of both runs\nGot: %s", buf.String())
+	}
+
+	t.Run("corrupt history only warns", func(t *testing.T) {
+		if err := os.WriteFile(path, []byte("[{"), 0644); err != nil {
+			t.Fatalf("Failed to write history: %v", err)
+		}
+		var errBuf bytes.Buffer
+		cli := NewCLI(io.Discard, args)
+		cli.ErrOutput = &errBuf
+		if err := cli.Run(); err != nil {
+			t.Fatalf("A corrupt history should not fail the run: %v", err)
+		}
+		if !strings.Contains(errBuf.String(), "warning: coverage history not recorded") {
+			t.Errorf("Expected a warning\nGot: %s", errBuf.String())
+		}
+	})
+
+	t.Run("sparkline requires history", func(t *testing.T) {
+		var validationErr *ValidationError
+		err := NewCLI(io.Discard, []string{"-coverprofile", "testdata/coverage.out", "-history-sparkline", "5"}).Run()
+		if !errors.As(err, &validationErr) {
+			t.Errorf("Expected ValidationError, got: %v", err)
+		}
+	})
+}
@@ -1,13 +1,21 @@
 package main
 
 import (
-	"log"
+	"fmt"
 	"os"
 )
 
+// Build information, injected via -ldflags "-X main.version=..."
+var (
+	version = "dev"
+	commit  = "dev"
+	date    = "dev"
+)
+
 func main() {
 	cli := NewCLI(os.Stdout, os.Args[1:])
 	if err := cli.Run(); err != nil {
-		log.Fatal(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(ExitCode(err))
 	}
 }
@@ -36,6 +36,12 @@ func TestMainE2E(t *testing.T) {
 			wantError:  true,
 			wantOutput: []string{"coverprofile"},
 		},
+		{
+			name:       "version flag",
+			args:       []string{"-version"},
+			wantError:  false,
+			wantOutput: []string{"gocov dev"},
+		},
 		{
 			name:       "valid coverage file",
 			args:       []string{"-coverprofile", "testdata/coverage.out"},
@@ -122,6 +128,60 @@ func TestMainE2E(t *testing.T) {
 	}
 }
 
+// TestMainExitCodes tests that threshold failures are distinguishable from tool errors
+func TestMainExitCodes(t *testing.T) {
+	cmd := exec.Command("go", "build", "-o", "gocov_test_exit", ".")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+	defer os.Remove("gocov_test_exit")
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+	}{
+		{
+			name:     "success",
+			args:     []string{"-coverprofile", "testdata/coverage.out"},
+			wantCode: ExitSuccess,
+		},
+		{
+			name:     "usage error",
+			args:     []string{},
+			wantCode: ExitError,
+		},
+		{
+			name:     "parse error",
+			args:     []string{"-coverprofile", "testdata/invalid.out"},
+			wantCode: ExitError,
+		},
+		{
+			name:     "threshold failure",
+			args:     []string{"-coverprofile", "testdata/coverage.out", "-threshold", "90"},
+			wantCode: ExitThresholdError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("./gocov_test_exit", tt.args...)
+			err := cmd.Run()
+
+			code := 0
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				code = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatalf("Failed to run binary: %v", err)
+			}
+
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+}
+
 // TestMainWithConfig tests main function with configuration file
 func TestMainWithConfig(t *testing.T) {
 	// Create a temporary directory and config file
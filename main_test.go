@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/blck-snwmn/gocov/pkg/gocov"
 	"golang.org/x/tools/cover"
 )
 
@@ -11,7 +12,7 @@ func TestMainFunction(t *testing.T) {
 	// Test that main function properly initializes CLI
 	// This is a simple smoke test to ensure main doesn't panic
 	t.Run("main smoke test", func(t *testing.T) {
-		// We can't easily test main() directly because it calls log.Fatal
+		// We can't easily test main() directly because it calls os.Exit
 		// but we can verify that the components it uses are properly connected
 		cli := NewCLI(bytes.NewBuffer(nil), []string{})
 		if cli == nil {
@@ -46,7 +47,7 @@ func TestErrorCases(t *testing.T) {
 		}
 
 		// Test with empty data
-		analyzer := NewCoverageAnalyzer(0, nil)
+		analyzer := gocov.NewCoverageAnalyzer(0, nil)
 		coverageByDir := analyzer.Aggregate(profiles)
 		if len(coverageByDir) != 0 {
 			t.Errorf("Expected empty coverage map, got %d entries", len(coverageByDir))
@@ -61,7 +62,7 @@ func TestBoundaryValues(t *testing.T) {
 			t.Fatalf("Failed to parse zero statement file: %v", err)
 		}
 
-		analyzer := NewCoverageAnalyzer(0, nil)
+		analyzer := gocov.NewCoverageAnalyzer(0, nil)
 		coverageByDir := analyzer.Aggregate(profiles)
 		if len(coverageByDir) != 1 {
 			t.Fatalf("Expected 1 directory, got %d", len(coverageByDir))
@@ -71,7 +72,7 @@ func TestBoundaryValues(t *testing.T) {
 			if cov.StmtCount != 0 {
 				t.Errorf("Expected 0 statements, got %d", cov.StmtCount)
 			}
-			coverage := CalculateCoverage(cov.StmtCount, cov.StmtCovered)
+			coverage := gocov.CalculateCoverage(cov.StmtCount, cov.StmtCovered)
 			if coverage != 0.0 {
 				t.Errorf("Expected 0%% coverage, got %.1f%%", coverage)
 			}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// ErrNoModulePath is returned when go.mod has no module directive
+var ErrNoModulePath = errors.New("module path not found in go.mod")
+
+// DetectModulePath reads the module path from go.mod in dir
+func DetectModulePath(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read go.mod: %w", err)
+	}
+
+	modulePath := modfile.ModulePath(data)
+	if modulePath == "" {
+		return "", ErrNoModulePath
+	}
+	return modulePath, nil
+}
+
+// findModulePath returns the module path of the nearest go.mod at or above root
+// root is the -source-root directory, or empty for the working directory
+func findModulePath(root string) (string, error) {
+	modulePath, _, err := findModuleRoot(root)
+	return modulePath, err
+}
+
+// findModuleRoot returns the module path and directory of the nearest go.mod at or above root
+func findModuleRoot(root string) (modulePath, dir string, err error) {
+	dir, err = sourceDir(root)
+	if err != nil {
+		return "", "", err
+	}
+	for {
+		modulePath, err = DetectModulePath(dir)
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			return modulePath, dir, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", err
+		}
+		dir = parent
+	}
+}
+
+// sourceDir returns root as an absolute directory, or the working directory when root is empty
+func sourceDir(root string) (string, error) {
+	var dir string
+	var err error
+	if root != "" {
+		dir, err = filepath.Abs(root)
+	} else {
+		dir, err = os.Getwd()
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	return dir, nil
+}
+
+// resolveAutoLevel returns the aggregation level one below the module root found from root (see findModulePath)
+// For "github.com/example/project" that is 4; without a go.mod it falls back to 0 (leaf directories)
+func resolveAutoLevel(root string) int {
+	modulePath, err := findModulePath(root)
+	if err != nil {
+		return 0
+	}
+	return moduleLevel(modulePath)
+}
+
+// moduleLevel returns the number of segments in modulePath plus one
+func moduleLevel(modulePath string) int {
+	return len(strings.Split(modulePath, "/")) + 1
+}
+
+// resolveSourceRoot returns the module path and directory used to locate profiled source files
+// root is the -source-root directory, or empty for the working directory; the directory holding
+// the nearest go.mod at or above it is used (see findModuleRoot).
+// Without a go.mod the module path is empty and file names are resolved against root itself
+func resolveSourceRoot(root string) (modulePath, dir string, err error) {
+	modulePath, moduleDir, err := findModuleRoot(root)
+	if err == nil {
+		return modulePath, moduleDir, nil
+	}
+	dir, err = sourceDir(root)
+	if err != nil {
+		return "", "", err
+	}
+	return "", dir, nil
+}
+
+// resolveTrimPrefix expands "auto" to the module path found from root (see findModulePath)
+func resolveTrimPrefix(trimPrefix, root string) (string, error) {
+	if trimPrefix != "auto" {
+		return trimPrefix, nil
+	}
+
+	modulePath, err := findModulePath(root)
+	if err != nil {
+		return "", NewConfigError("trim_prefix", trimPrefix, err)
+	}
+	return modulePath, nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectModulePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "simple module",
+			content: "module github.com/example/project\n\ngo 1.25.0\n",
+			want:    "github.com/example/project",
+		},
+		{
+			name:    "quoted module",
+			content: "// comment\nmodule \"github.com/example/quoted\"\n",
+			want:    "github.com/example/quoted",
+		},
+		{
+			name:    "no module directive",
+			content: "go 1.25.0\n",
+			wantErr: ErrNoModulePath,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write go.mod: %v", err)
+			}
+
+			got, err := DetectModulePath(dir)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("DetectModulePath() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DetectModulePath() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("DetectModulePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("missing go.mod", func(t *testing.T) {
+		if _, err := DetectModulePath(t.TempDir()); err == nil {
+			t.Error("Expected error for missing go.mod")
+		}
+	})
+}
+
+func TestResolveTrimPrefix(t *testing.T) {
+	t.Run("explicit prefix", func(t *testing.T) {
+		got, err := resolveTrimPrefix("github.com/example/project", "")
+		if err != nil || got != "github.com/example/project" {
+			t.Errorf("resolveTrimPrefix() = %q, %v", got, err)
+		}
+	})
+
+	t.Run("auto uses go.mod of the working directory", func(t *testing.T) {
+		got, err := resolveTrimPrefix("auto", "")
+		if err != nil {
+			t.Fatalf("resolveTrimPrefix() unexpected error: %v", err)
+		}
+		if got != "github.com/blck-snwmn/gocov" {
+			t.Errorf("resolveTrimPrefix() = %q, want %q", got, "github.com/blck-snwmn/gocov")
+		}
+	})
+
+	t.Run("auto searches upwards from the source root", func(t *testing.T) {
+		root := t.TempDir()
+		if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/svc\n"), 0644); err != nil {
+			t.Fatalf("Failed to write go.mod: %v", err)
+		}
+		sub := filepath.Join(root, "internal", "api")
+		if err := os.MkdirAll(sub, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		// The working directory's own module is not used once a source root is given
+		got, err := resolveTrimPrefix("auto", sub)
+		if err != nil || got != "example.com/svc" {
+			t.Errorf("resolveTrimPrefix() = %q, %v, want %q", got, err, "example.com/svc")
+		}
+	})
+
+	t.Run("auto without go.mod", func(t *testing.T) {
+		_, err := resolveTrimPrefix("auto", t.TempDir())
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) {
+			t.Errorf("resolveTrimPrefix() error = %v, want ConfigError", err)
+		}
+	})
+}
+
+func TestModuleLevel(t *testing.T) {
+	tests := []struct {
+		modulePath string
+		want       int
+	}{
+		{"example", 2},
+		{"github.com/example/project", 4},
+		{"github.com/example/project/v2", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.modulePath, func(t *testing.T) {
+			if got := moduleLevel(tt.modulePath); got != tt.want {
+				t.Errorf("moduleLevel(%q) = %d, want %d", tt.modulePath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveAutoLevel(t *testing.T) {
+	t.Run("uses go.mod of the working directory", func(t *testing.T) {
+		// github.com/blck-snwmn/gocov has three segments
+		if got := resolveAutoLevel(""); got != 4 {
+			t.Errorf("resolveAutoLevel() = %d, want 4", got)
+		}
+	})
+
+	t.Run("falls back to leaf directories without go.mod", func(t *testing.T) {
+		t.Chdir(t.TempDir())
+		if got := resolveAutoLevel(""); got != 0 {
+			t.Errorf("resolveAutoLevel() = %d, want 0", got)
+		}
+	})
+
+	t.Run("uses go.mod above the source root", func(t *testing.T) {
+		root := t.TempDir()
+		if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/svc\n"), 0644); err != nil {
+			t.Fatalf("Failed to write go.mod: %v", err)
+		}
+		sub := filepath.Join(root, "cmd")
+		if err := os.MkdirAll(sub, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		t.Chdir(t.TempDir())
+		if got := resolveAutoLevel(sub); got != 3 {
+			t.Errorf("resolveAutoLevel() = %d, want 3", got)
+		}
+	})
+}
+
+func TestResolveSourceRoot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/svc\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+	sub := filepath.Join(root, "internal", "api")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	t.Run("run from a package subdirectory", func(t *testing.T) {
+		t.Chdir(sub)
+		modulePath, dir, err := resolveSourceRoot("")
+		if err != nil {
+			t.Fatalf("resolveSourceRoot() error = %v", err)
+		}
+		if modulePath != "example.com/svc" || !sameDir(t, dir, root) {
+			t.Errorf("resolveSourceRoot() = %q, %q, want %q, %q", modulePath, dir, "example.com/svc", root)
+		}
+	})
+
+	t.Run("source root below the module root", func(t *testing.T) {
+		t.Chdir(t.TempDir())
+		modulePath, dir, err := resolveSourceRoot(sub)
+		if err != nil {
+			t.Fatalf("resolveSourceRoot() error = %v", err)
+		}
+		if modulePath != "example.com/svc" || !sameDir(t, dir, root) {
+			t.Errorf("resolveSourceRoot() = %q, %q, want %q, %q", modulePath, dir, "example.com/svc", root)
+		}
+	})
+
+	t.Run("falls back to the source root without go.mod", func(t *testing.T) {
+		plain := t.TempDir()
+		modulePath, dir, err := resolveSourceRoot(plain)
+		if err != nil {
+			t.Fatalf("resolveSourceRoot() error = %v", err)
+		}
+		if modulePath != "" || !sameDir(t, dir, plain) {
+			t.Errorf("resolveSourceRoot() = %q, %q, want \"\", %q", modulePath, dir, plain)
+		}
+	})
+}
+
+// sameDir reports whether a and b name the same directory, ignoring symlinks in temporary paths
+func sameDir(t *testing.T, a, b string) bool {
+	t.Helper()
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
@@ -0,0 +1,803 @@
+package gocov
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+// UncoveredBlock represents a profile block whose statements were not executed
+type UncoveredBlock struct {
+	File      string `json:"file"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+}
+
+// DirCoverage represents coverage information for a directory
+type DirCoverage struct {
+	Dir         string
+	StmtCount   int
+	StmtCovered int
+	// TotalHits is the sum of block.Count * block.NumStmt
+	TotalHits int
+	// Executions is the sum of block.Count, i.e. how often the blocks ran regardless of their size
+	Executions int
+	// FileCount is the number of profiles aggregated into this directory
+	FileCount int
+	// LineCount and LineCovered count distinct source lines spanned by blocks with statements
+	LineCount   int
+	LineCovered int
+	// UncoveredBlocks is only populated when the analyzer runs in verbose mode
+	UncoveredBlocks []UncoveredBlock
+}
+
+// ThresholdViolation represents a directory below its configured threshold
+// In diff mode Directory holds the changed file and Pattern is empty
+type ThresholdViolation struct {
+	Directory string
+	Pattern   string
+	Threshold float64
+	Actual    float64
+}
+
+// CoverageAnalyzer analyzes coverage data
+type CoverageAnalyzer struct {
+	level          int
+	ignorePatterns []string
+	verbose        bool
+	workers        int
+	excludeTests   bool
+	// ignoreFiles holds the file patterns set with SetIgnoreFilePatterns
+	ignoreFiles filePatternSet
+	// ignoreSet and includeSet are compiled once so matching does not re-split patterns per profile
+	ignoreSet  patternSet
+	includeSet patternSet
+	// generated is nil unless generated files are ignored
+	generated *generatedDetector
+	// ignored is nil unless //gocov:ignore directives are honored
+	ignored *ignoreDirectives
+	// root is nil unless SetModuleRoot was called; it lets patterns match absolute and module paths alike
+	root *moduleRoot
+	// groupBy is GroupByDir or GroupByPackage
+	groupBy string
+	// levelRules override level for matching directories, first match wins
+	levelRules []compiledLevelRule
+}
+
+// LevelRule aggregates directories matching Pattern at Level instead of the global level
+// Patterns are matched like ignore patterns and Level has the same meaning as the global level
+type LevelRule struct {
+	Pattern string
+	Level   int
+}
+
+// compiledLevelRule is a LevelRule with its pattern split into segments
+type compiledLevelRule struct {
+	set   patternSet
+	level int
+}
+
+// Grouping modes accepted by SetGroupBy
+const (
+	// GroupByDir groups profiles by the directory of their file name as written in the profile
+	GroupByDir = "dir"
+	// GroupByPackage groups profiles by Go import path, mapping local file names into the module
+	GroupByPackage = "package"
+)
+
+// NewCoverageAnalyzer creates a new CoverageAnalyzer
+func NewCoverageAnalyzer(level int, ignorePatterns []string) *CoverageAnalyzer {
+	return &CoverageAnalyzer{
+		level:          level,
+		ignorePatterns: ignorePatterns,
+		ignoreSet:      compilePatterns(ignorePatterns),
+	}
+}
+
+// SetVerbose enables retaining uncovered blocks for each directory
+func (a *CoverageAnalyzer) SetVerbose(verbose bool) {
+	a.verbose = verbose
+}
+
+// SetWorkers sets the worker pool size for concurrent aggregation
+// A value of 0 or less uses runtime.NumCPU()
+func (a *CoverageAnalyzer) SetWorkers(workers int) {
+	a.workers = workers
+}
+
+// SetExcludeTests skips profiles for _test.go files during aggregation
+func (a *CoverageAnalyzer) SetExcludeTests(excludeTests bool) {
+	a.excludeTests = excludeTests
+}
+
+// SetIgnoreFilePatterns skips profiles for files matching any pattern (see ShouldIgnoreFile),
+// before directories are matched against ignore and include patterns
+func (a *CoverageAnalyzer) SetIgnoreFilePatterns(patterns []string) {
+	a.ignoreFiles = compileFilePatterns(patterns)
+}
+
+// SetIncludePatterns restricts aggregation to directories matching at least one pattern
+// Ignore patterns still take precedence
+func (a *CoverageAnalyzer) SetIncludePatterns(patterns []string) {
+	a.includeSet = compilePatterns(patterns)
+}
+
+// SetLevelRules sets per-tree aggregation levels; the first rule matching a directory decides its level
+// Directories matching no rule use the global level
+func (a *CoverageAnalyzer) SetLevelRules(rules []LevelRule) {
+	a.levelRules = make([]compiledLevelRule, 0, len(rules))
+	for _, rule := range rules {
+		a.levelRules = append(a.levelRules, compiledLevelRule{
+			set:   compilePatterns([]string{rule.Pattern}),
+			level: rule.Level,
+		})
+	}
+}
+
+// SetGroupBy selects whether profiles are grouped by directory (the default) or by package import path
+// For go test profiles both are the same, since file names are already import paths.
+// They differ for local file names: by package, "./pkg/util/a.go" and absolute paths inside the module root
+// (see SetModuleRoot) are reported under the import path, and paths always use forward slashes
+func (a *CoverageAnalyzer) SetGroupBy(groupBy string) {
+	a.groupBy = groupBy
+}
+
+// GroupKey returns the directory or package that fileName is aggregated under, before level adjustment
+func (a *CoverageAnalyzer) GroupKey(fileName string) string {
+	if a.groupBy != GroupByPackage {
+		return filepath.Dir(fileName)
+	}
+
+	pkg := path.Dir(filepath.ToSlash(fileName))
+	if a.root == nil {
+		return pkg
+	}
+	if filepath.IsAbs(fileName) {
+		if importPath, ok := a.root.modulePathOf(filepath.Dir(fileName)); ok {
+			return importPath
+		}
+		return pkg
+	}
+	if pkg == "." || strings.HasPrefix(filepath.ToSlash(fileName), "./") {
+		// A local file name is relative to the module directory
+		if importPath, ok := a.root.modulePathOf(filepath.Join(a.root.dir, filepath.FromSlash(pkg))); ok {
+			return importPath
+		}
+	}
+	return pkg
+}
+
+// SetModuleRoot lets ignore and include patterns written for module paths match absolute profile paths
+// Absolute profile directories under dir are also tried as modulePath/...
+func (a *CoverageAnalyzer) SetModuleRoot(modulePath, dir string) {
+	root := newModuleRoot(modulePath, dir)
+	a.root = &root
+}
+
+// Aggregate aggregates coverage data by directory
+func (a *CoverageAnalyzer) Aggregate(profiles []*cover.Profile) map[string]*DirCoverage {
+	coverageByDir, _ := a.AggregateContext(context.Background(), profiles)
+	return coverageByDir
+}
+
+// AggregateContext is like Aggregate but stops early and returns ctx.Err() when ctx is done
+func (a *CoverageAnalyzer) AggregateContext(ctx context.Context, profiles []*cover.Profile) (map[string]*DirCoverage, error) {
+	// Pre-allocate map with estimated capacity based on number of profiles
+	// Typically, each profile represents one file, and we might have multiple files per directory
+	estimatedDirs := len(profiles) / 3
+	if estimatedDirs < 10 {
+		estimatedDirs = 10
+	}
+	coverageByDir := make(map[string]*DirCoverage, estimatedDirs)
+
+	for _, profile := range profiles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		a.addProfile(coverageByDir, profile)
+	}
+
+	return coverageByDir, nil
+}
+
+// mergeCoverage adds every directory in src to dst, taking ownership of src's entries
+func mergeCoverage(dst, src map[string]*DirCoverage) {
+	for dir, cov := range src {
+		existing, exists := dst[dir]
+		if !exists {
+			dst[dir] = cov
+			continue
+		}
+		existing.StmtCount += cov.StmtCount
+		existing.StmtCovered += cov.StmtCovered
+		existing.TotalHits += cov.TotalHits
+		existing.Executions += cov.Executions
+		existing.FileCount += cov.FileCount
+		existing.LineCount += cov.LineCount
+		existing.LineCovered += cov.LineCovered
+		existing.UncoveredBlocks = append(existing.UncoveredBlocks, cov.UncoveredBlocks...)
+	}
+}
+
+// shouldSkipProfile reports whether a profile is excluded before directory matching
+func (a *CoverageAnalyzer) shouldSkipProfile(profile *cover.Profile) bool {
+	if a.excludeTests && strings.HasSuffix(profile.FileName, "_test.go") {
+		return true
+	}
+	if a.matchFile(profile.FileName) {
+		return true
+	}
+	return a.generated != nil && a.generated.isGenerated(profile.FileName)
+}
+
+// shouldSkipDirectory reports whether dir is ignored or not included
+func (a *CoverageAnalyzer) shouldSkipDirectory(dir string) bool {
+	skip, _, _ := a.matchDirectory(dir)
+	return skip
+}
+
+// matchDirectory decides whether dir is skipped and returns the ignore or include match that decided it
+// With a module root every form of dir is matched, so a pattern matching any of them applies
+func (a *CoverageAnalyzer) matchDirectory(dir string) (skip bool, ignoredBy, includedBy patternMatch) {
+	forms := []string{dir}
+	if a.root != nil {
+		forms = a.root.forms(dir)
+	}
+
+	included := !a.includeSet.configured
+	for _, form := range forms {
+		if m, ok := a.ignoreSet.matchPattern(form); ok {
+			return true, m, patternMatch{}
+		}
+		if !included {
+			includedBy, included = a.includeSet.matchPattern(form)
+		}
+	}
+	return !included, patternMatch{}, includedBy
+}
+
+// ExplainDirectory reports whether profiles in dir are aggregated and why, for debugging ignore and include patterns
+// A matching pattern is reported with the path segments it matched, which tells which form of dir
+// (with a module root) and which run of its segments decided
+func (a *CoverageAnalyzer) ExplainDirectory(dir string) (kept bool, reason string) {
+	skip, ignoredBy, includedBy := a.matchDirectory(dir)
+	switch {
+	case ignoredBy.pattern != "":
+		return false, fmt.Sprintf("matched ignore pattern %q at %q", ignoredBy.pattern, ignoredBy.segments)
+	case skip:
+		return false, "matched no include pattern"
+	case includedBy.pattern != "":
+		return true, fmt.Sprintf("matched include pattern %q at %q", includedBy.pattern, includedBy.segments)
+	default:
+		return true, "matched no ignore pattern"
+	}
+}
+
+func (a *CoverageAnalyzer) adjustDirectoryLevel(dir string) string {
+	level := a.levelFor(dir)
+	if level > 0 {
+		parts := strings.Split(dir, string(filepath.Separator))
+		if len(parts) > level {
+			dir = filepath.Join(parts[:level]...)
+		}
+	} else if level == -1 {
+		// -1 means aggregate at top level (module root)
+		dir = "."
+	}
+	// level == 0 means use the original directory (leaf level)
+	return dir
+}
+
+// levelFor returns the level of the first level rule matching the original dir, or the global level
+func (a *CoverageAnalyzer) levelFor(dir string) int {
+	for _, rule := range a.levelRules {
+		if rule.set.match(dir) {
+			return rule.level
+		}
+	}
+	return a.level
+}
+
+// patternSet is a list of directory patterns split into path segments
+type patternSet struct {
+	segments [][]string
+	// patterns holds the pattern each entry of segments was compiled from
+	patterns []string
+	// configured is true when at least one non-empty pattern was given, even if invalid
+	configured bool
+}
+
+// compilePatterns splits patterns into segments, dropping empty and malformed patterns
+func compilePatterns(patterns []string) patternSet {
+	var set patternSet
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		set.configured = true
+
+		parts := strings.Split(strings.Trim(pattern, "/"), "/")
+		valid := true
+		for _, part := range parts {
+			if _, err := filepath.Match(part, ""); err != nil {
+				valid = false
+				break
+			}
+		}
+		if valid {
+			set.segments = append(set.segments, parts)
+			set.patterns = append(set.patterns, pattern)
+		}
+	}
+	return set
+}
+
+// match reports whether dir matches any pattern in the set
+func (s patternSet) match(dir string) bool {
+	_, ok := s.matchPattern(dir)
+	return ok
+}
+
+// patternMatch is a pattern together with the consecutive directory segments it matched
+type patternMatch struct {
+	pattern  string
+	segments string
+}
+
+// matchPattern returns the first pattern in the set that matches dir
+func (s patternSet) matchPattern(dir string) (patternMatch, bool) {
+	if len(s.segments) == 0 {
+		return patternMatch{}, false
+	}
+	dirParts := strings.Split(filepath.ToSlash(dir), "/")
+	for i, parts := range s.segments {
+		if start := matchSegments(parts, dirParts); start >= 0 {
+			return patternMatch{
+				pattern:  s.patterns[i],
+				segments: strings.Join(dirParts[start:start+len(parts)], "/"),
+			}, true
+		}
+	}
+	return patternMatch{}, false
+}
+
+// ShouldIgnoreDirectory checks if a directory matches any of the ignore patterns
+// Patterns are matched per path segment against any run of consecutive segments in dir,
+// so "net" or "*/net/*" never match a directory named "network"
+func ShouldIgnoreDirectory(dir string, patterns []string) bool {
+	return compilePatterns(patterns).match(dir)
+}
+
+// ShouldIgnoreDirectoryReason is like ShouldIgnoreDirectory but also returns the pattern that matched
+func ShouldIgnoreDirectoryReason(dir string, patterns []string) (bool, string) {
+	m, ok := compilePatterns(patterns).matchPattern(dir)
+	return ok, m.pattern
+}
+
+// matchSegments returns the index of the first run of consecutive elements of dirParts
+// that patternParts matches, or -1 when there is none
+func matchSegments(patternParts, dirParts []string) int {
+	for start := 0; start+len(patternParts) <= len(dirParts); start++ {
+		matched := true
+		for i, part := range patternParts {
+			// Patterns are validated by compilePatterns, so errors cannot occur here
+			if ok, _ := filepath.Match(part, dirParts[start+i]); !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return start
+		}
+	}
+	return -1
+}
+
+// TrimDirectory removes prefix from dir as TrimDirectoryPrefix does; dir equal to prefix becomes "."
+func TrimDirectory(dir, prefix string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if dir == prefix {
+		return "."
+	}
+	if strings.HasPrefix(dir, prefix+"/") {
+		return strings.TrimPrefix(dir, prefix+"/")
+	}
+	return dir
+}
+
+// TrimDirectoryPrefix returns a copy of coverageByDir keyed by directories with prefix removed
+// The directory equal to prefix itself becomes "."
+func TrimDirectoryPrefix(coverageByDir map[string]*DirCoverage, prefix string) map[string]*DirCoverage {
+	trimmed := make(map[string]*DirCoverage, len(coverageByDir))
+	for dir, cov := range coverageByDir {
+		newDir := TrimDirectory(dir, prefix)
+
+		if existing, exists := trimmed[newDir]; exists {
+			existing.StmtCount += cov.StmtCount
+			existing.StmtCovered += cov.StmtCovered
+			existing.TotalHits += cov.TotalHits
+			existing.Executions += cov.Executions
+			existing.FileCount += cov.FileCount
+			existing.LineCount += cov.LineCount
+			existing.LineCovered += cov.LineCovered
+			existing.UncoveredBlocks = append(existing.UncoveredBlocks, cov.UncoveredBlocks...)
+			continue
+		}
+		trimmed[newDir] = &DirCoverage{
+			Dir:             newDir,
+			StmtCount:       cov.StmtCount,
+			StmtCovered:     cov.StmtCovered,
+			TotalHits:       cov.TotalHits,
+			Executions:      cov.Executions,
+			FileCount:       cov.FileCount,
+			LineCount:       cov.LineCount,
+			LineCovered:     cov.LineCovered,
+			UncoveredBlocks: cov.UncoveredBlocks,
+		}
+	}
+	return trimmed
+}
+
+// filePatternSet is a list of file patterns split into path segments
+type filePatternSet struct {
+	segments [][]string
+}
+
+// compileFilePatterns splits patterns into segments, dropping empty and malformed patterns
+func compileFilePatterns(patterns []string) filePatternSet {
+	var set filePatternSet
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+		valid := true
+		for _, part := range parts {
+			if _, err := filepath.Match(part, ""); err != nil {
+				valid = false
+				break
+			}
+		}
+		if valid {
+			set.segments = append(set.segments, parts)
+		}
+	}
+	return set
+}
+
+// match reports whether fileName matches any pattern in the set
+// A pattern matches the trailing segments of fileName, so a pattern without a slash matches the base name
+func (s filePatternSet) match(fileName string) bool {
+	nameParts := strings.Split(filepath.ToSlash(NormalizeFilePath(fileName)), "/")
+	for _, parts := range s.segments {
+		for start := 0; start < len(nameParts); start++ {
+			if matchGlobSegments(parts, nameParts[start:]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchGlobSegments reports whether patternParts matches all of nameParts, with "**" matching any number of segments
+func matchGlobSegments(patternParts, nameParts []string) bool {
+	if len(patternParts) == 0 {
+		return len(nameParts) == 0
+	}
+	if patternParts[0] == "**" {
+		for skip := 0; skip <= len(nameParts); skip++ {
+			if matchGlobSegments(patternParts[1:], nameParts[skip:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(nameParts) == 0 {
+		return false
+	}
+	// Patterns are validated by compileFilePatterns, so errors cannot occur here
+	if ok, _ := filepath.Match(patternParts[0], nameParts[0]); !ok {
+		return false
+	}
+	return matchGlobSegments(patternParts[1:], nameParts[1:])
+}
+
+// matchFile reports whether fileName matches a file ignore pattern
+// With a module root, absolute file names inside the module are also matched by their module path
+func (a *CoverageAnalyzer) matchFile(fileName string) bool {
+	if len(a.ignoreFiles.segments) == 0 {
+		return false
+	}
+	forms := []string{fileName}
+	if a.root != nil {
+		forms = a.root.forms(fileName)
+	}
+	for _, form := range forms {
+		if a.ignoreFiles.match(form) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShouldIgnoreFile checks if a profiled file name matches any of the file ignore patterns
+// A pattern without a slash matches the base name ("main.go", "*_mock.go"); one with slashes matches
+// the trailing path segments ("gen/*.go", "github.com/example/project/cmd/*/main.go"), where "**" spans any number of segments
+func ShouldIgnoreFile(fileName string, patterns []string) bool {
+	return compileFilePatterns(patterns).match(fileName)
+}
+
+// ShouldIncludeDirectory checks if a directory matches any of the include patterns
+// Patterns are matched like ShouldIgnoreDirectory, and no patterns includes every directory
+func ShouldIncludeDirectory(dir string, patterns []string) bool {
+	return compilePatterns(patterns).includes(dir)
+}
+
+// includes reports whether dir is kept by an include set; an unconfigured set keeps everything
+func (s patternSet) includes(dir string) bool {
+	return !s.configured || s.match(dir)
+}
+
+// Stats summarizes how much input was aggregated
+type Stats struct {
+	Files       int `json:"files"`
+	Directories int `json:"directories"`
+}
+
+// CalculateStats counts the files and directories in aggregated coverage data
+func CalculateStats(coverageByDir map[string]*DirCoverage) Stats {
+	stats := Stats{Directories: len(coverageByDir)}
+	for _, cov := range coverageByDir {
+		stats.Files += cov.FileCount
+	}
+	return stats
+}
+
+// DefaultHistogramBands are the band boundaries used by -histogram unless configured
+var DefaultHistogramBands = []float64{50, 80}
+
+// CoverageHistogram counts directories per coverage band
+// bands are ascending boundaries between buckets, so the result has len(bands)+1 counts:
+// bucket i holds coverage from bands[i-1] (inclusive) up to bands[i] (exclusive)
+func CoverageHistogram(coverageByDir map[string]*DirCoverage, bands []float64) []int {
+	counts := make([]int, len(bands)+1)
+	for _, cov := range coverageByDir {
+		coverage := CalculateCoverage(cov.StmtCount, cov.StmtCovered)
+		counts[sort.Search(len(bands), func(i int) bool { return bands[i] > coverage })]++
+	}
+	return counts
+}
+
+// normalizeBlocks merges overlapping blocks so their statements are counted once
+// A merged block spans all of its parts and keeps the largest statement and execution counts.
+// Blocks that merely touch at a boundary are kept separate, and the input is returned as is
+// when nothing overlaps
+func normalizeBlocks(blocks []cover.ProfileBlock) []cover.ProfileBlock {
+	sorted := sort.SliceIsSorted(blocks, func(i, j int) bool {
+		return blockStartsBefore(blocks[i], blocks[j])
+	})
+	if sorted && !hasOverlap(blocks) {
+		return blocks
+	}
+
+	ordered := make([]cover.ProfileBlock, len(blocks))
+	copy(ordered, blocks)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return blockStartsBefore(ordered[i], ordered[j])
+	})
+
+	merged := make([]cover.ProfileBlock, 0, len(ordered))
+	for _, block := range ordered {
+		if len(merged) == 0 || !blocksOverlap(merged[len(merged)-1], block) {
+			merged = append(merged, block)
+			continue
+		}
+		last := &merged[len(merged)-1]
+		if block.EndLine > last.EndLine || (block.EndLine == last.EndLine && block.EndCol > last.EndCol) {
+			last.EndLine, last.EndCol = block.EndLine, block.EndCol
+		}
+		last.NumStmt = max(last.NumStmt, block.NumStmt)
+		last.Count = max(last.Count, block.Count)
+	}
+	return merged
+}
+
+// blockStartsBefore orders blocks by their start position
+func blockStartsBefore(a, b cover.ProfileBlock) bool {
+	if a.StartLine != b.StartLine {
+		return a.StartLine < b.StartLine
+	}
+	return a.StartCol < b.StartCol
+}
+
+// blocksOverlap reports whether b, which does not start before a, begins inside a
+func blocksOverlap(a, b cover.ProfileBlock) bool {
+	return b.StartLine < a.EndLine || (b.StartLine == a.EndLine && b.StartCol < a.EndCol)
+}
+
+// hasOverlap reports whether any block in a sorted slice overlaps its predecessor
+func hasOverlap(blocks []cover.ProfileBlock) bool {
+	for i := 1; i < len(blocks); i++ {
+		if blocksOverlap(blocks[i-1], blocks[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// countLines returns the distinct lines spanned by blocks with statements and how many of them executed
+// A line shared by several blocks is counted once and is covered when any of those blocks ran
+func countLines(blocks []cover.ProfileBlock) (total, covered int) {
+	lines := make(map[int]bool)
+	for _, block := range blocks {
+		if block.NumStmt == 0 {
+			continue
+		}
+		for line := block.StartLine; line <= block.EndLine; line++ {
+			lines[line] = lines[line] || block.Count > 0
+		}
+	}
+	for _, hit := range lines {
+		total++
+		if hit {
+			covered++
+		}
+	}
+	return total, covered
+}
+
+// CalculateCoverage calculates the coverage percentage
+func CalculateCoverage(stmtCount, stmtCovered int) float64 {
+	if stmtCount > 0 {
+		return float64(stmtCovered) / float64(stmtCount) * 100
+	}
+	return 0.0
+}
+
+// DefaultPrecision is the number of decimal places coverage is displayed with and compared at
+const DefaultPrecision = 1
+
+// RawPrecision compares and formats coverage without rounding it
+const RawPrecision = -1
+
+// RoundCoverage rounds coverage to places decimal places, halves away from zero; RawPrecision keeps it as is
+// The precise value is kept everywhere else; rounding is only for display and threshold comparisons
+func RoundCoverage(coverage float64, places int) float64 {
+	if places < 0 {
+		return coverage
+	}
+	scale := math.Pow10(places)
+	return math.Round(coverage*scale) / scale
+}
+
+// FormatCoverage formats coverage with places decimal places, without a percent sign
+// RawPrecision uses as many places as needed to represent coverage exactly
+func FormatCoverage(coverage float64, places int) string {
+	return strconv.FormatFloat(RoundCoverage(coverage, places), 'f', places, 64)
+}
+
+// BelowThreshold reports whether coverage fails threshold once rounded to places decimal places,
+// so a value displayed as "80.0" passes a threshold of 80
+func BelowThreshold(coverage, threshold float64, places int) bool {
+	return RoundCoverage(coverage, places) < threshold
+}
+
+// FilterDirectories filters directories based on coverage thresholds
+// Directories with fewer than minStatements statements are dropped
+func FilterDirectories(coverageByDir map[string]*DirCoverage, minCoverage, maxCoverage float64, minStatements int) []string {
+	// Pre-allocate slice with worst-case capacity (all directories)
+	filtered := make([]string, 0, len(coverageByDir))
+	for dir, cov := range coverageByDir {
+		if cov.StmtCount < minStatements {
+			continue
+		}
+		coverage := CalculateCoverage(cov.StmtCount, cov.StmtCovered)
+		if coverage >= minCoverage && coverage <= maxCoverage {
+			filtered = append(filtered, dir)
+		}
+	}
+	sort.Strings(filtered)
+	return filtered
+}
+
+// CheckDirectoryThresholds checks each directory against the most specific matching threshold pattern
+// The longest matching pattern is considered the most specific; coverage is compared rounded to places (see BelowThreshold)
+func CheckDirectoryThresholds(coverageByDir map[string]*DirCoverage, thresholds map[string]float64, places int) []ThresholdViolation {
+	matched := MatchThresholdPatterns(coverageByDir, thresholds)
+	dirs := make([]string, 0, len(matched))
+	for dir := range matched {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var violations []ThresholdViolation
+	for _, dir := range dirs {
+		pattern := matched[dir]
+		cov := coverageByDir[dir]
+		coverage := CalculateCoverage(cov.StmtCount, cov.StmtCovered)
+		if BelowThreshold(coverage, thresholds[pattern], places) {
+			violations = append(violations, ThresholdViolation{
+				Directory: dir,
+				Pattern:   pattern,
+				Threshold: thresholds[pattern],
+				Actual:    coverage,
+			})
+		}
+	}
+	return violations
+}
+
+// MatchThresholdPatterns returns the most specific threshold pattern matching each directory
+// Longer patterns are more specific; directories matching no pattern are left out
+func MatchThresholdPatterns(coverageByDir map[string]*DirCoverage, thresholds map[string]float64) map[string]string {
+	if len(thresholds) == 0 {
+		return nil
+	}
+
+	// Sort patterns by specificity so the first match wins
+	patterns := make([]string, 0, len(thresholds))
+	for pattern := range thresholds {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	compiled := make([]patternSet, len(patterns))
+	for i, pattern := range patterns {
+		compiled[i] = compilePatterns([]string{pattern})
+	}
+
+	matched := make(map[string]string)
+	for dir := range coverageByDir {
+		for i, pattern := range patterns {
+			if compiled[i].match(dir) {
+				matched[dir] = pattern
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// CheckMinDirectoryCoverage reports every directory whose coverage is below minCoverage
+// Directories without statements have nothing to cover and are never reported; a minCoverage of 0 disables the check.
+// Coverage is compared rounded to places (see BelowThreshold)
+func CheckMinDirectoryCoverage(coverageByDir map[string]*DirCoverage, minCoverage float64, places int) []ThresholdViolation {
+	if minCoverage <= 0 {
+		return nil
+	}
+
+	dirs := make([]string, 0, len(coverageByDir))
+	for dir := range coverageByDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var violations []ThresholdViolation
+	for _, dir := range dirs {
+		cov := coverageByDir[dir]
+		if cov.StmtCount == 0 {
+			continue
+		}
+		coverage := CalculateCoverage(cov.StmtCount, cov.StmtCovered)
+		if BelowThreshold(coverage, minCoverage, places) {
+			violations = append(violations, ThresholdViolation{
+				Directory: dir,
+				Threshold: minCoverage,
+				Actual:    coverage,
+			})
+		}
+	}
+
+	return violations
+}
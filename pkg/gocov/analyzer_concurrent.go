@@ -0,0 +1,158 @@
+package gocov
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"golang.org/x/tools/cover"
+)
+
+// AutoConcurrentThreshold is the number of profiles above which AggregateAuto
+// switches to concurrent processing. Below it the worker pool overhead outweighs the gain
+const AutoConcurrentThreshold = 200
+
+// AggregateAuto aggregates coverage data, choosing concurrent processing for large inputs
+func (a *CoverageAnalyzer) AggregateAuto(profiles []*cover.Profile) map[string]*DirCoverage {
+	coverageByDir, _ := a.AggregateAutoContext(context.Background(), profiles)
+	return coverageByDir
+}
+
+// AggregateAutoContext is like AggregateAuto but stops early and returns ctx.Err() when ctx is done
+func (a *CoverageAnalyzer) AggregateAutoContext(ctx context.Context, profiles []*cover.Profile) (map[string]*DirCoverage, error) {
+	if len(profiles) > AutoConcurrentThreshold {
+		return a.AggregateConcurrentContext(ctx, profiles)
+	}
+	return a.AggregateContext(ctx, profiles)
+}
+
+// AggregateConcurrent aggregates coverage data by directory using concurrent processing
+// Each worker accumulates into its own map, so only one map per worker is merged at the end
+func (a *CoverageAnalyzer) AggregateConcurrent(profiles []*cover.Profile) map[string]*DirCoverage {
+	coverageByDir, _ := a.AggregateConcurrentContext(context.Background(), profiles)
+	return coverageByDir
+}
+
+// AggregateConcurrentContext is like AggregateConcurrent but stops early and returns ctx.Err() when ctx is done
+func (a *CoverageAnalyzer) AggregateConcurrentContext(ctx context.Context, profiles []*cover.Profile) (map[string]*DirCoverage, error) {
+	if len(profiles) <= 10 {
+		// For small number of profiles, use sequential processing
+		return a.AggregateContext(ctx, profiles)
+	}
+
+	// Use worker pool pattern
+	numWorkers := a.workers
+	if numWorkers <= 0 {
+		numWorkers = runtime.NumCPU()
+	}
+	if len(profiles) < numWorkers {
+		numWorkers = len(profiles)
+	}
+
+	profileChan := make(chan *cover.Profile, len(profiles))
+	resultChan := make(chan map[string]*DirCoverage, numWorkers)
+
+	// Start workers
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			local := make(map[string]*DirCoverage)
+			for profile := range profileChan {
+				if ctx.Err() != nil {
+					// Stop working; the remaining profiles are left in the buffered channel
+					break
+				}
+				if profile == nil {
+					continue
+				}
+				a.addProfile(local, profile)
+			}
+			resultChan <- local
+		}()
+	}
+
+	// Send profiles to workers
+	for _, profile := range profiles {
+		profileChan <- profile
+	}
+	close(profileChan)
+
+	// Wait for all workers to finish
+	go func() {
+		wg.Wait()
+		close(resultChan)
+	}()
+
+	// Merge the per-worker results
+	// Pre-allocate map with estimated capacity
+	estimatedDirs := len(profiles) / 3
+	if estimatedDirs < 10 {
+		estimatedDirs = 10
+	}
+	finalCoverage := make(map[string]*DirCoverage, estimatedDirs)
+	for result := range resultChan {
+		mergeCoverage(finalCoverage, result)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return finalCoverage, nil
+}
+
+// processProfile processes a single profile and returns coverage by directory
+func (a *CoverageAnalyzer) processProfile(profile *cover.Profile) map[string]*DirCoverage {
+	// Most profiles will have only one directory
+	coverageByDir := make(map[string]*DirCoverage, 1)
+	a.addProfile(coverageByDir, profile)
+	return coverageByDir
+}
+
+// addProfile accumulates a single profile into coverageByDir
+func (a *CoverageAnalyzer) addProfile(coverageByDir map[string]*DirCoverage, profile *cover.Profile) {
+	if a.shouldSkipProfile(profile) {
+		return
+	}
+
+	dir := a.GroupKey(profile.FileName)
+
+	// Check if directory should be ignored or is not included
+	if a.shouldSkipDirectory(dir) {
+		return
+	}
+
+	// Adjust directory path based on level
+	dir = a.adjustDirectoryLevel(dir)
+
+	if _, exists := coverageByDir[dir]; !exists {
+		coverageByDir[dir] = &DirCoverage{Dir: dir}
+	}
+	coverageByDir[dir].FileCount++
+	blocks := profile.Blocks
+	if a.ignored != nil {
+		blocks = a.ignored.dropIgnored(profile.FileName, blocks)
+	}
+	blocks = normalizeBlocks(blocks)
+	lineCount, lineCovered := countLines(blocks)
+	coverageByDir[dir].LineCount += lineCount
+	coverageByDir[dir].LineCovered += lineCovered
+
+	for _, block := range blocks {
+		stmtCount := block.NumStmt
+		coverageByDir[dir].StmtCount += stmtCount
+		coverageByDir[dir].TotalHits += block.Count * stmtCount
+		coverageByDir[dir].Executions += block.Count
+
+		if block.Count > 0 {
+			coverageByDir[dir].StmtCovered += stmtCount
+		} else if a.verbose && stmtCount > 0 {
+			coverageByDir[dir].UncoveredBlocks = append(coverageByDir[dir].UncoveredBlocks, UncoveredBlock{
+				File:      profile.FileName,
+				StartLine: block.StartLine,
+				EndLine:   block.EndLine,
+			})
+		}
+	}
+}
@@ -1,6 +1,8 @@
-package main
+package gocov
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
@@ -85,6 +87,34 @@ func TestAggregateConcurrent(t *testing.T) {
 	}
 }
 
+func TestAggregateConcurrentWorkers(t *testing.T) {
+	var profiles []*cover.Profile
+	for i := range 30 {
+		profiles = append(profiles, &cover.Profile{
+			FileName: fmt.Sprintf("github.com/example/project/pkg/module%d/file.go", i%5),
+			Mode:     "set",
+			Blocks: []cover.ProfileBlock{
+				{StartLine: 1, StartCol: 1, EndLine: 10, EndCol: 1, NumStmt: 3, Count: 1},
+				{StartLine: 11, StartCol: 1, EndLine: 20, EndCol: 1, NumStmt: 2, Count: 0},
+			},
+		})
+	}
+
+	seqResult := NewCoverageAnalyzer(0, nil).Aggregate(profiles)
+
+	for _, workers := range []int{0, 1, 3, 64} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			analyzer := NewCoverageAnalyzer(0, nil)
+			analyzer.SetWorkers(workers)
+
+			concResult := analyzer.AggregateConcurrent(profiles)
+			if !reflect.DeepEqual(seqResult, concResult) {
+				t.Errorf("Concurrent result with %d workers differs from sequential result", workers)
+			}
+		})
+	}
+}
+
 func TestAggregateConcurrentSmallInput(t *testing.T) {
 	// Test that small inputs fall back to sequential processing
 	profiles := []*cover.Profile{
@@ -105,6 +135,11 @@ func TestAggregateConcurrentSmallInput(t *testing.T) {
 			Dir:         "test",
 			StmtCount:   5,
 			StmtCovered: 5,
+			TotalHits:   5,
+			Executions:  1,
+			FileCount:   1,
+			LineCount:   10,
+			LineCovered: 10,
 		},
 	}
 
@@ -280,10 +315,61 @@ func TestProcessProfileErrorHandling(t *testing.T) {
 	}
 }
 
-func BenchmarkAggregate(b *testing.B) {
-	// Create a large set of test profiles
+func TestAggregateAuto(t *testing.T) {
+	for _, n := range []int{AutoConcurrentThreshold, AutoConcurrentThreshold + 1} {
+		t.Run(fmt.Sprintf("%d profiles", n), func(t *testing.T) {
+			profiles := benchmarkProfiles(n)
+			analyzer := NewCoverageAnalyzer(0, nil)
+
+			got := analyzer.AggregateAuto(profiles)
+			want := analyzer.Aggregate(profiles)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("AggregateAuto() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestAggregateContext(t *testing.T) {
+	profiles := benchmarkProfiles(500)
+	analyzer := NewCoverageAnalyzer(0, nil)
+
+	aggregators := map[string]func(context.Context, []*cover.Profile) (map[string]*DirCoverage, error){
+		"sequential": analyzer.AggregateContext,
+		"concurrent": analyzer.AggregateConcurrentContext,
+		"auto":       analyzer.AggregateAutoContext,
+	}
+
+	for name, aggregate := range aggregators {
+		t.Run(name+" completes", func(t *testing.T) {
+			got, err := aggregate(context.Background(), profiles)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if want := analyzer.Aggregate(profiles); !reflect.DeepEqual(got, want) {
+				t.Errorf("Result differs from Aggregate()")
+			}
+		})
+
+		t.Run(name+" canceled", func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			got, err := aggregate(ctx, profiles)
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected context.Canceled, got %v", err)
+			}
+			if got != nil {
+				t.Errorf("Expected no result after cancellation, got %d directories", len(got))
+			}
+		})
+	}
+}
+
+// benchmarkProfiles creates n profiles spread over 26 directories
+func benchmarkProfiles(n int) []*cover.Profile {
 	var profiles []*cover.Profile
-	for i := range 100 {
+	for i := range n {
 		profiles = append(profiles, &cover.Profile{
 			FileName: "github.com/example/project/pkg/module" + string(rune('a'+i%26)) + "/file.go",
 			Mode:     "set",
@@ -293,6 +379,12 @@ func BenchmarkAggregate(b *testing.B) {
 			},
 		})
 	}
+	return profiles
+}
+
+func BenchmarkAggregate(b *testing.B) {
+	// Create a large set of test profiles
+	profiles := benchmarkProfiles(100)
 
 	analyzer := NewCoverageAnalyzer(0, nil)
 
@@ -307,4 +399,38 @@ func BenchmarkAggregate(b *testing.B) {
 			_ = analyzer.AggregateConcurrent(profiles)
 		}
 	})
+
+	for _, workers := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("ConcurrentWorkers%d", workers), func(b *testing.B) {
+			analyzer := NewCoverageAnalyzer(0, nil)
+			analyzer.SetWorkers(workers)
+			for i := 0; i < b.N; i++ {
+				_ = analyzer.AggregateConcurrent(profiles)
+			}
+		})
+	}
+}
+
+// BenchmarkAggregateAuto compares both strategies around AutoConcurrentThreshold
+func BenchmarkAggregateAuto(b *testing.B) {
+	for _, n := range []int{50, AutoConcurrentThreshold, 1000} {
+		profiles := benchmarkProfiles(n)
+		analyzer := NewCoverageAnalyzer(0, nil)
+
+		b.Run(fmt.Sprintf("Sequential%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = analyzer.Aggregate(profiles)
+			}
+		})
+		b.Run(fmt.Sprintf("Concurrent%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = analyzer.AggregateConcurrent(profiles)
+			}
+		})
+		b.Run(fmt.Sprintf("Auto%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = analyzer.AggregateAuto(profiles)
+			}
+		})
+	}
 }
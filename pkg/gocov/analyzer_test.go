@@ -0,0 +1,1275 @@
+package gocov
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestNewCoverageAnalyzer(t *testing.T) {
+	tests := []struct {
+		name           string
+		level          int
+		ignorePatterns []string
+	}{
+		{
+			name:           "default analyzer",
+			level:          0,
+			ignorePatterns: nil,
+		},
+		{
+			name:           "with level",
+			level:          3,
+			ignorePatterns: nil,
+		},
+		{
+			name:           "with ignore patterns",
+			level:          0,
+			ignorePatterns: []string{"*/test/*", "*/vendor/*"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := NewCoverageAnalyzer(tt.level, tt.ignorePatterns)
+			if analyzer == nil {
+				t.Fatal("NewCoverageAnalyzer returned nil")
+			}
+			if analyzer.level != tt.level {
+				t.Errorf("level = %d, want %d", analyzer.level, tt.level)
+			}
+			if len(analyzer.ignorePatterns) != len(tt.ignorePatterns) {
+				t.Errorf("ignorePatterns length = %d, want %d", len(analyzer.ignorePatterns), len(tt.ignorePatterns))
+			}
+		})
+	}
+}
+
+func TestAggregateCoverageByDirectory(t *testing.T) {
+	profiles, err := cover.ParseProfiles("../../testdata/coverage.out")
+	if err != nil {
+		t.Fatalf("Failed to parse test coverage file: %v", err)
+	}
+
+	t.Run("level 0 (leaf directories)", func(t *testing.T) {
+		analyzer := NewCoverageAnalyzer(0, nil)
+		result := analyzer.Aggregate(profiles)
+
+		tests := []struct {
+			dir          string
+			wantStmts    int
+			wantCovered  int
+			wantCoverage float64
+		}{
+			{
+				dir:          "github.com/example/project/pkg/util",
+				wantStmts:    7,
+				wantCovered:  5,
+				wantCoverage: 71.4,
+			},
+			{
+				dir:          "github.com/example/project/cmd/server",
+				wantStmts:    7,
+				wantCovered:  5,
+				wantCoverage: 71.4,
+			},
+			{
+				dir:          "github.com/example/project/internal/service",
+				wantStmts:    7,
+				wantCovered:  6,
+				wantCoverage: 85.7,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.dir, func(t *testing.T) {
+				cov, exists := result[tt.dir]
+				if !exists {
+					t.Fatalf("Directory %s not found in results", tt.dir)
+				}
+
+				if cov.StmtCount != tt.wantStmts {
+					t.Errorf("StmtCount = %d, want %d", cov.StmtCount, tt.wantStmts)
+				}
+
+				if cov.StmtCovered != tt.wantCovered {
+					t.Errorf("StmtCovered = %d, want %d", cov.StmtCovered, tt.wantCovered)
+				}
+
+				coverage := CalculateCoverage(cov.StmtCount, cov.StmtCovered)
+				if coverage < tt.wantCoverage-0.1 || coverage > tt.wantCoverage+0.1 {
+					t.Errorf("coverage = %.1f%%, want %.1f%%", coverage, tt.wantCoverage)
+				}
+			})
+		}
+	})
+
+	t.Run("level -1 (top level)", func(t *testing.T) {
+		analyzer := NewCoverageAnalyzer(-1, nil)
+		result := analyzer.Aggregate(profiles)
+
+		cov, exists := result["."]
+		if !exists {
+			t.Fatal("Top level directory '.' not found in results")
+		}
+
+		wantStmts := 21
+		wantCovered := 16
+		wantCoverage := 76.2
+
+		if cov.StmtCount != wantStmts {
+			t.Errorf("StmtCount = %d, want %d", cov.StmtCount, wantStmts)
+		}
+
+		if cov.StmtCovered != wantCovered {
+			t.Errorf("StmtCovered = %d, want %d", cov.StmtCovered, wantCovered)
+		}
+
+		coverage := CalculateCoverage(cov.StmtCount, cov.StmtCovered)
+		if coverage < wantCoverage-0.1 || coverage > wantCoverage+0.1 {
+			t.Errorf("coverage = %.1f%%, want %.1f%%", coverage, wantCoverage)
+		}
+	})
+
+	t.Run("level 3 (github.com/example/project)", func(t *testing.T) {
+		analyzer := NewCoverageAnalyzer(3, nil)
+		result := analyzer.Aggregate(profiles)
+
+		cov, exists := result["github.com/example/project"]
+		if !exists {
+			t.Fatal("Directory 'github.com/example/project' not found in results")
+		}
+
+		wantStmts := 21
+		wantCovered := 16
+		wantCoverage := 76.2
+
+		if cov.StmtCount != wantStmts {
+			t.Errorf("StmtCount = %d, want %d", cov.StmtCount, wantStmts)
+		}
+
+		if cov.StmtCovered != wantCovered {
+			t.Errorf("StmtCovered = %d, want %d", cov.StmtCovered, wantCovered)
+		}
+
+		coverage := CalculateCoverage(cov.StmtCount, cov.StmtCovered)
+		if coverage < wantCoverage-0.1 || coverage > wantCoverage+0.1 {
+			t.Errorf("coverage = %.1f%%, want %.1f%%", coverage, wantCoverage)
+		}
+	})
+
+	t.Run("level 4 (github.com/example/project/pkg, cmd, internal)", func(t *testing.T) {
+		analyzer := NewCoverageAnalyzer(4, nil)
+		result := analyzer.Aggregate(profiles)
+
+		tests := []struct {
+			dir          string
+			wantStmts    int
+			wantCovered  int
+			wantCoverage float64
+		}{
+			{
+				dir:          "github.com/example/project/pkg",
+				wantStmts:    7,
+				wantCovered:  5,
+				wantCoverage: 71.4,
+			},
+			{
+				dir:          "github.com/example/project/cmd",
+				wantStmts:    7,
+				wantCovered:  5,
+				wantCoverage: 71.4,
+			},
+			{
+				dir:          "github.com/example/project/internal",
+				wantStmts:    7,
+				wantCovered:  6,
+				wantCoverage: 85.7,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.dir, func(t *testing.T) {
+				cov, exists := result[tt.dir]
+				if !exists {
+					t.Fatalf("Directory %s not found in results", tt.dir)
+				}
+
+				if cov.StmtCount != tt.wantStmts {
+					t.Errorf("StmtCount = %d, want %d", cov.StmtCount, tt.wantStmts)
+				}
+
+				if cov.StmtCovered != tt.wantCovered {
+					t.Errorf("StmtCovered = %d, want %d", cov.StmtCovered, tt.wantCovered)
+				}
+
+				coverage := CalculateCoverage(cov.StmtCount, cov.StmtCovered)
+				if coverage < tt.wantCoverage-0.1 || coverage > tt.wantCoverage+0.1 {
+					t.Errorf("coverage = %.1f%%, want %.1f%%", coverage, tt.wantCoverage)
+				}
+			})
+		}
+	})
+}
+
+func TestAggregateVerbose(t *testing.T) {
+	profiles, err := cover.ParseProfiles("../../testdata/coverage.out")
+	if err != nil {
+		t.Fatalf("Failed to parse test coverage file: %v", err)
+	}
+
+	t.Run("verbose disabled", func(t *testing.T) {
+		analyzer := NewCoverageAnalyzer(0, nil)
+		result := analyzer.Aggregate(profiles)
+
+		for dir, cov := range result {
+			if len(cov.UncoveredBlocks) != 0 {
+				t.Errorf("Directory %s should not retain uncovered blocks, got %v", dir, cov.UncoveredBlocks)
+			}
+		}
+	})
+
+	t.Run("verbose enabled", func(t *testing.T) {
+		analyzer := NewCoverageAnalyzer(0, nil)
+		analyzer.SetVerbose(true)
+		result := analyzer.Aggregate(profiles)
+
+		cov, exists := result["github.com/example/project/pkg/util"]
+		if !exists {
+			t.Fatal("Directory github.com/example/project/pkg/util not found in results")
+		}
+
+		want := []UncoveredBlock{
+			{File: "github.com/example/project/pkg/util/helper.go", StartLine: 16, EndLine: 18},
+			{File: "github.com/example/project/pkg/util/math.go", StartLine: 9, EndLine: 11},
+		}
+		if len(cov.UncoveredBlocks) != len(want) {
+			t.Fatalf("UncoveredBlocks = %v, want %v", cov.UncoveredBlocks, want)
+		}
+		for i := range want {
+			if cov.UncoveredBlocks[i] != want[i] {
+				t.Errorf("UncoveredBlocks[%d] = %v, want %v", i, cov.UncoveredBlocks[i], want[i])
+			}
+		}
+
+		// Each directory only retains its own uncovered blocks
+		serviceCov := result["github.com/example/project/internal/service"]
+		if len(serviceCov.UncoveredBlocks) != 1 {
+			t.Errorf("Expected 1 uncovered block for internal/service, got %v", serviceCov.UncoveredBlocks)
+		}
+	})
+}
+
+func TestAggregateHits(t *testing.T) {
+	profiles := []*cover.Profile{
+		{
+			FileName: "github.com/example/project/pkg/util/helper.go",
+			Mode:     "atomic",
+			Blocks: []cover.ProfileBlock{
+				{StartLine: 1, EndLine: 5, NumStmt: 2, Count: 10},
+				{StartLine: 6, EndLine: 8, NumStmt: 3, Count: 0},
+			},
+		},
+		{
+			FileName: "github.com/example/project/pkg/util/math.go",
+			Mode:     "atomic",
+			Blocks: []cover.ProfileBlock{
+				{StartLine: 1, EndLine: 5, NumStmt: 1, Count: 7},
+			},
+		},
+	}
+
+	analyzer := NewCoverageAnalyzer(0, nil)
+
+	for name, result := range map[string]map[string]*DirCoverage{
+		"sequential": analyzer.Aggregate(profiles),
+		"concurrent": analyzer.AggregateConcurrent(profiles),
+	} {
+		t.Run(name, func(t *testing.T) {
+			cov, exists := result["github.com/example/project/pkg/util"]
+			if !exists {
+				t.Fatal("Directory github.com/example/project/pkg/util not found in results")
+			}
+			// 2*10 + 3*0 + 1*7
+			if cov.TotalHits != 27 {
+				t.Errorf("TotalHits = %d, want 27", cov.TotalHits)
+			}
+			// 10 + 0 + 7, regardless of block size
+			if cov.Executions != 17 {
+				t.Errorf("Executions = %d, want 17", cov.Executions)
+			}
+		})
+	}
+}
+
+func TestNormalizeBlocks(t *testing.T) {
+	tests := []struct {
+		name   string
+		blocks []cover.ProfileBlock
+		want   []cover.ProfileBlock
+	}{
+		{
+			name: "disjoint blocks are unchanged",
+			blocks: []cover.ProfileBlock{
+				{StartLine: 1, StartCol: 2, EndLine: 3, EndCol: 4, NumStmt: 2, Count: 1},
+				{StartLine: 3, StartCol: 4, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 0},
+			},
+			want: []cover.ProfileBlock{
+				{StartLine: 1, StartCol: 2, EndLine: 3, EndCol: 4, NumStmt: 2, Count: 1},
+				{StartLine: 3, StartCol: 4, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 0},
+			},
+		},
+		{
+			name: "identical blocks are merged",
+			blocks: []cover.ProfileBlock{
+				{StartLine: 10, StartCol: 2, EndLine: 12, EndCol: 3, NumStmt: 2, Count: 0},
+				{StartLine: 10, StartCol: 2, EndLine: 12, EndCol: 3, NumStmt: 2, Count: 4},
+			},
+			want: []cover.ProfileBlock{
+				{StartLine: 10, StartCol: 2, EndLine: 12, EndCol: 3, NumStmt: 2, Count: 4},
+			},
+		},
+		{
+			name: "partially overlapping unsorted blocks are merged",
+			blocks: []cover.ProfileBlock{
+				{StartLine: 20, StartCol: 1, EndLine: 30, EndCol: 1, NumStmt: 3, Count: 0},
+				{StartLine: 15, StartCol: 1, EndLine: 25, EndCol: 1, NumStmt: 4, Count: 0},
+				{StartLine: 1, StartCol: 1, EndLine: 5, EndCol: 1, NumStmt: 1, Count: 1},
+			},
+			want: []cover.ProfileBlock{
+				{StartLine: 1, StartCol: 1, EndLine: 5, EndCol: 1, NumStmt: 1, Count: 1},
+				{StartLine: 15, StartCol: 1, EndLine: 30, EndCol: 1, NumStmt: 4, Count: 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeBlocks(tt.blocks); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeBlocks() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregateOverlappingBlocks(t *testing.T) {
+	// The same statements reported twice, e.g. by merging profiles from two test binaries
+	profiles := []*cover.Profile{
+		{
+			FileName: "github.com/example/project/pkg/util/helper.go",
+			Mode:     "set",
+			Blocks: []cover.ProfileBlock{
+				{StartLine: 5, StartCol: 10, EndLine: 8, EndCol: 2, NumStmt: 3, Count: 1},
+				{StartLine: 5, StartCol: 10, EndLine: 8, EndCol: 2, NumStmt: 3, Count: 0},
+				{StartLine: 10, StartCol: 10, EndLine: 12, EndCol: 2, NumStmt: 2, Count: 0},
+			},
+		},
+	}
+
+	analyzer := NewCoverageAnalyzer(0, nil)
+
+	for name, result := range map[string]map[string]*DirCoverage{
+		"sequential": analyzer.Aggregate(profiles),
+		"concurrent": analyzer.AggregateConcurrent(profiles),
+	} {
+		t.Run(name, func(t *testing.T) {
+			cov := result["github.com/example/project/pkg/util"]
+			if cov == nil {
+				t.Fatal("Directory github.com/example/project/pkg/util not found in results")
+			}
+			if cov.StmtCount != 5 || cov.StmtCovered != 3 {
+				t.Errorf("Got %d/%d statements, want 3/5", cov.StmtCovered, cov.StmtCount)
+			}
+		})
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	tests := []struct {
+		name        string
+		blocks      []cover.ProfileBlock
+		wantTotal   int
+		wantCovered int
+	}{
+		{
+			name:        "disjoint blocks",
+			blocks:      []cover.ProfileBlock{{StartLine: 1, EndLine: 3, NumStmt: 2, Count: 1}, {StartLine: 5, EndLine: 6, NumStmt: 1, Count: 0}},
+			wantTotal:   5,
+			wantCovered: 3,
+		},
+		{
+			name:        "adjacent blocks share a line",
+			blocks:      []cover.ProfileBlock{{StartLine: 1, EndLine: 4, NumStmt: 2, Count: 0}, {StartLine: 4, EndLine: 6, NumStmt: 1, Count: 1}},
+			wantTotal:   6,
+			wantCovered: 3,
+		},
+		{
+			name:        "overlapping blocks are not double counted",
+			blocks:      []cover.ProfileBlock{{StartLine: 10, EndLine: 20, NumStmt: 5, Count: 1}, {StartLine: 10, EndLine: 20, NumStmt: 5, Count: 0}},
+			wantTotal:   11,
+			wantCovered: 11,
+		},
+		{
+			name:        "blocks without statements are skipped",
+			blocks:      []cover.ProfileBlock{{StartLine: 1, EndLine: 10, NumStmt: 0, Count: 0}},
+			wantTotal:   0,
+			wantCovered: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, covered := countLines(tt.blocks)
+			if total != tt.wantTotal || covered != tt.wantCovered {
+				t.Errorf("countLines() = (%d, %d), want (%d, %d)", total, covered, tt.wantTotal, tt.wantCovered)
+			}
+		})
+	}
+}
+
+func TestAggregateExcludeTests(t *testing.T) {
+	// Enough profiles to exercise the concurrent worker path as well
+	var profiles []*cover.Profile
+	for i := 0; i < 6; i++ {
+		profiles = append(profiles,
+			&cover.Profile{
+				FileName: fmt.Sprintf("github.com/example/project/pkg/util/file%d.go", i),
+				Mode:     "set",
+				Blocks:   []cover.ProfileBlock{{StartLine: 1, EndLine: 5, NumStmt: 2, Count: 1}},
+			},
+			&cover.Profile{
+				FileName: fmt.Sprintf("github.com/example/project/pkg/util/file%d_test.go", i),
+				Mode:     "set",
+				Blocks:   []cover.ProfileBlock{{StartLine: 1, EndLine: 5, NumStmt: 3, Count: 0}},
+			},
+		)
+	}
+
+	tests := []struct {
+		name         string
+		excludeTests bool
+		wantStmts    int
+		wantCovered  int
+	}{
+		{"include tests", false, 30, 12},
+		{"exclude tests", true, 12, 12},
+	}
+
+	for _, tt := range tests {
+		analyzer := NewCoverageAnalyzer(0, nil)
+		analyzer.SetExcludeTests(tt.excludeTests)
+
+		for name, result := range map[string]map[string]*DirCoverage{
+			"sequential": analyzer.Aggregate(profiles),
+			"concurrent": analyzer.AggregateConcurrent(profiles),
+		} {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				cov, exists := result["github.com/example/project/pkg/util"]
+				if !exists {
+					t.Fatal("Directory github.com/example/project/pkg/util not found in results")
+				}
+				if cov.StmtCount != tt.wantStmts || cov.StmtCovered != tt.wantCovered {
+					t.Errorf("StmtCount/StmtCovered = %d/%d, want %d/%d", cov.StmtCount, cov.StmtCovered, tt.wantStmts, tt.wantCovered)
+				}
+			})
+		}
+	}
+}
+
+func TestShouldIgnoreDirectory(t *testing.T) {
+	tests := []struct {
+		name     string
+		dir      string
+		patterns []string
+		want     bool
+	}{
+		{
+			name:     "no patterns",
+			dir:      "pkg/util",
+			patterns: []string{},
+			want:     false,
+		},
+		{
+			name:     "exact match",
+			dir:      "pkg/util",
+			patterns: []string{"pkg/util"},
+			want:     true,
+		},
+		{
+			name:     "wildcard match",
+			dir:      "pkg/util",
+			patterns: []string{"pkg/*"},
+			want:     true,
+		},
+		{
+			name:     "parent directory match",
+			dir:      "pkg/util/subdir",
+			patterns: []string{"pkg"},
+			want:     true,
+		},
+		{
+			name:     "no match",
+			dir:      "cmd/server",
+			patterns: []string{"pkg/*", "internal/*"},
+			want:     false,
+		},
+		{
+			name:     "multiple patterns with match",
+			dir:      "internal/api",
+			patterns: []string{"pkg/*", "internal/*", "cmd/*"},
+			want:     true,
+		},
+		{
+			name:     "empty pattern",
+			dir:      "pkg/util",
+			patterns: []string{"", "pkg/*"},
+			want:     true,
+		},
+		{
+			name:     "complex wildcard",
+			dir:      "github.com/example/project/internal/api",
+			patterns: []string{"*/internal/*"},
+			want:     true,
+		},
+		{
+			name:     "invalid pattern",
+			dir:      "pkg/util",
+			patterns: []string{"[invalid"},
+			want:     false,
+		},
+		{
+			name:     "pattern with path separator no match",
+			dir:      "pkg/util",
+			patterns: []string{"cmd/server"},
+			want:     false,
+		},
+		{
+			name:     "wildcard segments do not match substrings",
+			dir:      "internal/network",
+			patterns: []string{"*/net/*"},
+			want:     false,
+		},
+		{
+			name:     "wildcard segments match full component",
+			dir:      "internal/net/http",
+			patterns: []string{"*/net/*"},
+			want:     true,
+		},
+		{
+			name:     "component name does not match substrings",
+			dir:      "github.com/example/project/internal/network",
+			patterns: []string{"net"},
+			want:     false,
+		},
+		{
+			name:     "multi-segment pattern in the middle of the path",
+			dir:      "github.com/example/project/pkg/util/subdir",
+			patterns: []string{"pkg/util"},
+			want:     true,
+		},
+		{
+			name:     "multi-segment pattern must be consecutive",
+			dir:      "pkg/other/util",
+			patterns: []string{"pkg/util"},
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ShouldIgnoreDirectory(tt.dir, tt.patterns)
+			if got != tt.want {
+				t.Errorf("ShouldIgnoreDirectory(%q, %v) = %v, want %v",
+					tt.dir, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldIgnoreDirectoryReason(t *testing.T) {
+	patterns := []string{"*/vendor/*", "internal"}
+
+	tests := []struct {
+		dir         string
+		wantIgnored bool
+		wantPattern string
+	}{
+		{"github.com/example/project/vendor/lib", true, "*/vendor/*"},
+		{"github.com/example/project/internal/db", true, "internal"},
+		{"github.com/example/project/pkg/util", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			ignored, pattern := ShouldIgnoreDirectoryReason(tt.dir, patterns)
+			if ignored != tt.wantIgnored || pattern != tt.wantPattern {
+				t.Errorf("ShouldIgnoreDirectoryReason(%q) = (%v, %q), want (%v, %q)", tt.dir, ignored, pattern, tt.wantIgnored, tt.wantPattern)
+			}
+		})
+	}
+}
+
+func TestShouldIgnoreFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		patterns []string
+		want     bool
+	}{
+		{"no patterns", "github.com/example/project/cmd/server/main.go", nil, false},
+		{"base name", "github.com/example/project/cmd/server/main.go", []string{"main.go"}, true},
+		{"base name in every package", "github.com/example/project/main.go", []string{"main.go"}, true},
+		{"base name is not a suffix", "github.com/example/project/cmd/server/domain.go", []string{"main.go"}, false},
+		{"base name glob", "github.com/example/project/pkg/util/client_mock.go", []string{"*_mock.go"}, true},
+		{"double star", "github.com/example/project/cmd/server/main.go", []string{"**/main.go"}, true},
+		{"double star in the middle", "github.com/example/project/pkg/api/gen/types.go", []string{"pkg/**/gen/*.go"}, true},
+		{"double star matches no segments", "github.com/example/project/pkg/gen/types.go", []string{"pkg/**/gen/*.go"}, true},
+		{"full path", "github.com/example/project/pkg/api/zz_generated.go", []string{"github.com/example/project/pkg/api/zz_generated.go"}, true},
+		{"full path glob", "github.com/example/project/cmd/server/main.go", []string{"github.com/example/project/cmd/*/main.go"}, true},
+		{"full path glob of another file", "github.com/example/project/cmd/server/flags.go", []string{"github.com/example/project/cmd/*/main.go"}, false},
+		{"directory pattern is not a file", "github.com/example/project/cmd/server/main.go", []string{"cmd/server"}, false},
+		{"local path", "./cmd/server/main.go", []string{"cmd/*/main.go"}, true},
+		{"invalid pattern", "github.com/example/project/main.go", []string{"[main.go"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShouldIgnoreFile(tt.fileName, tt.patterns); got != tt.want {
+				t.Errorf("ShouldIgnoreFile(%q, %v) = %v, want %v", tt.fileName, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregateIgnoreFiles(t *testing.T) {
+	profiles := []*cover.Profile{
+		{FileName: "example.com/m/cmd/server/main.go", Blocks: []cover.ProfileBlock{{NumStmt: 10, Count: 0}}},
+		{FileName: "example.com/m/cmd/server/server.go", Blocks: []cover.ProfileBlock{{NumStmt: 4, Count: 1}}},
+		// Absolute names inside the module are matched by their module path
+		{FileName: "/src/m/cmd/worker/main.go", Blocks: []cover.ProfileBlock{{NumStmt: 6, Count: 0}}},
+	}
+
+	analyzer := NewCoverageAnalyzer(0, nil)
+	analyzer.SetModuleRoot("example.com/m", "/src/m")
+	analyzer.SetIgnoreFilePatterns([]string{"example.com/m/cmd/*/main.go"})
+
+	for name, aggregate := range map[string]func([]*cover.Profile) map[string]*DirCoverage{
+		"sequential": analyzer.Aggregate,
+		"concurrent": analyzer.AggregateConcurrent,
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := aggregate(profiles)
+			if cov := got["example.com/m/cmd/server"]; cov == nil || cov.StmtCount != 4 || cov.FileCount != 1 {
+				t.Errorf("main.go should be excluded from cmd/server, got %+v", cov)
+			}
+			if _, ok := got["/src/m/cmd/worker"]; ok {
+				t.Errorf("cmd/worker only holds an ignored file, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestExplainDirectory(t *testing.T) {
+	analyzer := NewCoverageAnalyzer(0, []string{"*/internal/*"})
+
+	tests := []struct {
+		name       string
+		include    []string
+		dir        string
+		wantKept   bool
+		wantReason string
+	}{
+		{"ignored", nil, "example.com/m/internal/db", false, `matched ignore pattern "*/internal/*" at "m/internal/db"`},
+		{"kept", nil, "example.com/m/pkg/util", true, "matched no ignore pattern"},
+		{"included", []string{"pkg"}, "example.com/m/pkg/util", true, `matched include pattern "pkg" at "pkg"`},
+		{"not included", []string{"pkg"}, "example.com/m/cmd/app", false, "matched no include pattern"},
+		{"ignore wins over include", []string{"internal"}, "example.com/m/internal/db", false, `matched ignore pattern "*/internal/*" at "m/internal/db"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer.SetIncludePatterns(tt.include)
+			kept, reason := analyzer.ExplainDirectory(tt.dir)
+			if kept != tt.wantKept || reason != tt.wantReason {
+				t.Errorf("ExplainDirectory(%q) = (%v, %q), want (%v, %q)", tt.dir, kept, reason, tt.wantKept, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestAggregateCoverageWithIgnoredDirectories(t *testing.T) {
+	profiles, err := cover.ParseProfiles("../../testdata/coverage.out")
+	if err != nil {
+		t.Fatalf("Failed to parse test coverage file: %v", err)
+	}
+
+	t.Run("ignore internal directory", func(t *testing.T) {
+		ignoredPatterns := []string{"*/internal/*"}
+		analyzer := NewCoverageAnalyzer(0, ignoredPatterns)
+		result := analyzer.Aggregate(profiles)
+
+		// Should not contain internal/service
+		if _, exists := result["github.com/example/project/internal/service"]; exists {
+			t.Error("Internal directory should be ignored")
+		}
+
+		// Should contain other directories
+		if _, exists := result["github.com/example/project/pkg/util"]; !exists {
+			t.Error("pkg/util should not be ignored")
+		}
+		if _, exists := result["github.com/example/project/cmd/server"]; !exists {
+			t.Error("cmd/server should not be ignored")
+		}
+	})
+
+	t.Run("ignore multiple patterns", func(t *testing.T) {
+		ignoredPatterns := []string{"*/pkg/*", "*/cmd/*"}
+		analyzer := NewCoverageAnalyzer(0, ignoredPatterns)
+		result := analyzer.Aggregate(profiles)
+
+		// Should only contain internal directory
+		if len(result) != 1 {
+			t.Errorf("Expected only 1 directory (internal), got %d", len(result))
+		}
+
+		if _, exists := result["github.com/example/project/internal/service"]; !exists {
+			t.Error("internal/service should not be ignored")
+		}
+	})
+
+	t.Run("ignore all with wildcard", func(t *testing.T) {
+		ignoredPatterns := []string{"*"}
+		analyzer := NewCoverageAnalyzer(0, ignoredPatterns)
+		result := analyzer.Aggregate(profiles)
+
+		if len(result) != 0 {
+			t.Errorf("Expected all directories to be ignored, got %d", len(result))
+		}
+	})
+}
+
+func TestTrimDirectoryPrefix(t *testing.T) {
+	coverageByDir := map[string]*DirCoverage{
+		"github.com/example/project":          {Dir: "github.com/example/project", StmtCount: 4, StmtCovered: 2},
+		"github.com/example/project/pkg/util": {Dir: "github.com/example/project/pkg/util", StmtCount: 10, StmtCovered: 8},
+		"github.com/example/projectx/cmd":     {Dir: "github.com/example/projectx/cmd", StmtCount: 5, StmtCovered: 5},
+	}
+
+	got := TrimDirectoryPrefix(coverageByDir, "github.com/example/project/")
+
+	want := map[string]int{
+		".":                               4,
+		"pkg/util":                        10,
+		"github.com/example/projectx/cmd": 5,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("TrimDirectoryPrefix() returned %d directories, want %d: %v", len(got), len(want), got)
+	}
+	for dir, stmts := range want {
+		cov, exists := got[dir]
+		if !exists {
+			t.Errorf("Directory %q not found in results", dir)
+			continue
+		}
+		if cov.Dir != dir || cov.StmtCount != stmts {
+			t.Errorf("got[%q] = %+v, want Dir %q and StmtCount %d", dir, cov, dir, stmts)
+		}
+	}
+
+	// The original map must not be modified
+	if _, exists := coverageByDir["github.com/example/project/pkg/util"]; !exists {
+		t.Error("TrimDirectoryPrefix() should not modify its input")
+	}
+}
+
+func TestShouldIncludeDirectory(t *testing.T) {
+	tests := []struct {
+		name     string
+		dir      string
+		patterns []string
+		want     bool
+	}{
+		{"no patterns keeps all", "cmd/server", nil, true},
+		{"empty patterns keep all", "cmd/server", []string{""}, true},
+		{"matching pattern", "github.com/example/project/pkg/util", []string{"pkg"}, true},
+		{"non-matching pattern", "github.com/example/project/cmd/server", []string{"pkg"}, false},
+		{"any pattern matches", "github.com/example/project/cmd/server", []string{"pkg", "cmd"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShouldIncludeDirectory(tt.dir, tt.patterns); got != tt.want {
+				t.Errorf("ShouldIncludeDirectory(%q, %v) = %v, want %v", tt.dir, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregateWithIncludePatterns(t *testing.T) {
+	profiles, err := cover.ParseProfiles("../../testdata/coverage.out")
+	if err != nil {
+		t.Fatalf("Failed to parse profiles: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		include  []string
+		ignore   []string
+		wantDirs []string
+	}{
+		{
+			name:     "include only",
+			include:  []string{"pkg"},
+			wantDirs: []string{"github.com/example/project/pkg/util"},
+		},
+		{
+			name:     "ignore wins over include",
+			include:  []string{"pkg", "cmd"},
+			ignore:   []string{"*/pkg/*"},
+			wantDirs: []string{"github.com/example/project/cmd/server"},
+		},
+		{
+			name:     "empty include keeps all",
+			include:  nil,
+			wantDirs: []string{"github.com/example/project/cmd/server", "github.com/example/project/internal/service", "github.com/example/project/pkg/util"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := NewCoverageAnalyzer(0, tt.ignore)
+			analyzer.SetIncludePatterns(tt.include)
+			result := analyzer.Aggregate(profiles)
+
+			got := FilterDirectories(result, 0, 100, 0)
+			if !reflect.DeepEqual(got, tt.wantDirs) {
+				t.Errorf("Aggregate() directories = %v, want %v", got, tt.wantDirs)
+			}
+		})
+	}
+}
+
+func TestCalculateStats(t *testing.T) {
+	profiles, err := cover.ParseProfiles("../../testdata/coverage.out")
+	if err != nil {
+		t.Fatalf("Failed to parse profiles: %v", err)
+	}
+
+	analyzer := NewCoverageAnalyzer(0, []string{"*/internal/*"})
+	stats := CalculateStats(analyzer.Aggregate(profiles))
+
+	want := Stats{Files: 4, Directories: 2}
+	if stats != want {
+		t.Errorf("CalculateStats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestCoverageHistogram(t *testing.T) {
+	coverageByDir := map[string]*DirCoverage{
+		"a": {StmtCount: 10, StmtCovered: 2},
+		"b": {StmtCount: 10, StmtCovered: 5},
+		"c": {StmtCount: 10, StmtCovered: 7},
+		"d": {StmtCount: 10, StmtCovered: 8},
+		"e": {StmtCount: 10, StmtCovered: 10},
+		"f": {StmtCount: 0, StmtCovered: 0},
+	}
+
+	tests := []struct {
+		name  string
+		bands []float64
+		want  []int
+	}{
+		{"default bands", DefaultHistogramBands, []int{2, 2, 2}},
+		{"single band", []float64{100}, []int{5, 1}},
+		{"no bands", nil, []int{6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CoverageHistogram(coverageByDir, tt.bands)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CoverageHistogram() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundCoverage(t *testing.T) {
+	tests := []struct {
+		coverage  float64
+		threshold float64
+		places    int
+		want      string
+		below     bool
+	}{
+		// 1999/2500 is displayed as 80.0%, so it passes a threshold of 80
+		{CalculateCoverage(2500, 1999), 80, 1, "80.0", false},
+		{79.95, 80, 1, "80.0", false},
+		{79.94, 80, 1, "79.9", true},
+		{CalculateCoverage(2500, 1999), 80, 2, "79.96", true},
+		{79.5, 80, 0, "80", false},
+		{79.4, 80, 0, "79", true},
+		{100, 100, 1, "100.0", false},
+		{CalculateCoverage(2500, 1999), 80, RawPrecision, "79.96", true},
+		{79.95, 80, RawPrecision, "79.95", true},
+		{80, 80, RawPrecision, "80", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v at %d places", tt.coverage, tt.places), func(t *testing.T) {
+			if got := FormatCoverage(tt.coverage, tt.places); got != tt.want {
+				t.Errorf("FormatCoverage(%v, %d) = %q, want %q", tt.coverage, tt.places, got, tt.want)
+			}
+			if got := BelowThreshold(tt.coverage, tt.threshold, tt.places); got != tt.below {
+				t.Errorf("BelowThreshold(%v, %v, %d) = %v, want %v", tt.coverage, tt.threshold, tt.places, got, tt.below)
+			}
+		})
+	}
+}
+
+func TestCalculateCoverage(t *testing.T) {
+	tests := []struct {
+		name        string
+		stmtCount   int
+		stmtCovered int
+		want        float64
+	}{
+		{
+			name:        "normal coverage",
+			stmtCount:   10,
+			stmtCovered: 7,
+			want:        70.0,
+		},
+		{
+			name:        "zero statements",
+			stmtCount:   0,
+			stmtCovered: 0,
+			want:        0.0,
+		},
+		{
+			name:        "100% coverage",
+			stmtCount:   10,
+			stmtCovered: 10,
+			want:        100.0,
+		},
+		{
+			name:        "0% coverage",
+			stmtCount:   10,
+			stmtCovered: 0,
+			want:        0.0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateCoverage(tt.stmtCount, tt.stmtCovered)
+			if got != tt.want {
+				t.Errorf("CalculateCoverage(%d, %d) = %f, want %f",
+					tt.stmtCount, tt.stmtCovered, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterDirectories(t *testing.T) {
+	coverageByDir := map[string]*DirCoverage{
+		"exactly50": {
+			Dir:         "exactly50",
+			StmtCount:   10,
+			StmtCovered: 5,
+		},
+		"high": {
+			Dir:         "high",
+			StmtCount:   10,
+			StmtCovered: 8,
+		},
+		"low": {
+			Dir:         "low",
+			StmtCount:   10,
+			StmtCovered: 2,
+		},
+		"tiny": {
+			Dir:         "tiny",
+			StmtCount:   2,
+			StmtCovered: 2,
+		},
+	}
+
+	tests := []struct {
+		name          string
+		minCoverage   float64
+		maxCoverage   float64
+		minStatements int
+		want          []string
+	}{
+		{
+			name:        "all directories",
+			minCoverage: 0.0,
+			maxCoverage: 100.0,
+			want:        []string{"exactly50", "high", "low", "tiny"},
+		},
+		{
+			name:        "minimum threshold",
+			minCoverage: 50.0,
+			maxCoverage: 100.0,
+			want:        []string{"exactly50", "high", "tiny"},
+		},
+		{
+			name:        "maximum threshold",
+			minCoverage: 0.0,
+			maxCoverage: 50.0,
+			want:        []string{"exactly50", "low"},
+		},
+		{
+			name:        "exact match",
+			minCoverage: 50.0,
+			maxCoverage: 50.0,
+			want:        []string{"exactly50"},
+		},
+		{
+			name:        "no match",
+			minCoverage: 90.0,
+			maxCoverage: 95.0,
+			want:        []string{},
+		},
+		{
+			name:          "minimum statements",
+			minCoverage:   0.0,
+			maxCoverage:   100.0,
+			minStatements: 3,
+			want:          []string{"exactly50", "high", "low"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FilterDirectories(coverageByDir, tt.minCoverage, tt.maxCoverage, tt.minStatements)
+			if len(got) != len(tt.want) {
+				t.Errorf("FilterDirectories() returned %d items, want %d", len(got), len(tt.want))
+				return
+			}
+			for i, dir := range got {
+				if dir != tt.want[i] {
+					t.Errorf("FilterDirectories()[%d] = %s, want %s", i, dir, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGroupKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		groupBy  string
+		root     bool
+		fileName string
+		want     string
+	}{
+		{"dir import path", GroupByDir, true, "example.com/m/pkg/util/a.go", "example.com/m/pkg/util"},
+		{"dir relative", GroupByDir, true, "./pkg/util/a.go", "pkg/util"},
+		{"dir absolute", GroupByDir, true, "/src/m/pkg/util/a.go", "/src/m/pkg/util"},
+		{"package import path", GroupByPackage, true, "example.com/m/pkg/util/a.go", "example.com/m/pkg/util"},
+		{"package relative", GroupByPackage, true, "./pkg/util/a.go", "example.com/m/pkg/util"},
+		{"package file at module root", GroupByPackage, true, "main.go", "example.com/m"},
+		{"package absolute in module", GroupByPackage, true, "/src/m/pkg/util/a.go", "example.com/m/pkg/util"},
+		{"package absolute outside module", GroupByPackage, true, "/src/other/a.go", "/src/other"},
+		{"package without module root", GroupByPackage, false, "./pkg/util/a.go", "pkg/util"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := NewCoverageAnalyzer(0, nil)
+			analyzer.SetGroupBy(tt.groupBy)
+			if tt.root {
+				analyzer.SetModuleRoot("example.com/m", "/src/m")
+			}
+			if got := analyzer.GroupKey(tt.fileName); got != tt.want {
+				t.Errorf("GroupKey(%q) = %q, want %q", tt.fileName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdjustDirectoryLevel(t *testing.T) {
+	tests := []struct {
+		name  string
+		dir   string
+		level int
+		want  string
+	}{
+		{
+			name:  "level 0 unchanged",
+			dir:   "github.com/example/project/pkg/util",
+			level: 0,
+			want:  "github.com/example/project/pkg/util",
+		},
+		{
+			name:  "level -1 root",
+			dir:   "github.com/example/project/pkg/util",
+			level: -1,
+			want:  ".",
+		},
+		{
+			name:  "level 3",
+			dir:   "github.com/example/project/pkg/util",
+			level: 3,
+			want:  "github.com/example/project",
+		},
+		{
+			name:  "level exceeds depth",
+			dir:   "pkg/util",
+			level: 5,
+			want:  "pkg/util",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := &CoverageAnalyzer{level: tt.level}
+			got := analyzer.adjustDirectoryLevel(tt.dir)
+			if got != tt.want {
+				t.Errorf("adjustDirectoryLevel(%s) = %s, want %s", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdjustDirectoryLevelRules(t *testing.T) {
+	analyzer := NewCoverageAnalyzer(0, nil)
+	analyzer.SetLevelRules([]LevelRule{
+		{Pattern: "example.com/m/cmd", Level: 3},
+		{Pattern: "example.com/m/internal", Level: 5},
+		{Pattern: "internal", Level: -1},
+	})
+
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{"example.com/m/cmd/server/handlers", "example.com/m/cmd"},
+		{"example.com/m/cmd/worker", "example.com/m/cmd"},
+		{"example.com/m/internal/db/sql/postgres", "example.com/m/internal/db/sql"},
+		{"example.com/m/internal/cache", "example.com/m/internal/cache"},
+		// The global level applies when no rule matches
+		{"example.com/m/pkg/util", "example.com/m/pkg/util"},
+		// The first matching rule wins
+		{"example.com/other/internal/db", "."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			if got := analyzer.adjustDirectoryLevel(tt.dir); got != tt.want {
+				t.Errorf("adjustDirectoryLevel(%s) = %s, want %s", tt.dir, got, tt.want)
+			}
+		})
+	}
+
+	profiles := []*cover.Profile{
+		{FileName: "example.com/m/cmd/server/main.go", Blocks: []cover.ProfileBlock{{NumStmt: 2, Count: 1}}},
+		{FileName: "example.com/m/cmd/worker/main.go", Blocks: []cover.ProfileBlock{{NumStmt: 2, Count: 0}}},
+		{FileName: "example.com/m/internal/db/sql/postgres/conn.go", Blocks: []cover.ProfileBlock{{NumStmt: 2, Count: 1}}},
+	}
+	got := FilterDirectories(analyzer.Aggregate(profiles), 0, 100, 0)
+	want := []string{"example.com/m/cmd", "example.com/m/internal/db/sql"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Aggregate() directories = %v, want %v", got, want)
+	}
+}
+
+func TestCheckDirectoryThresholds(t *testing.T) {
+	coverageByDir := map[string]*DirCoverage{
+		"github.com/example/project/pkg/util":   {Dir: "github.com/example/project/pkg/util", StmtCount: 10, StmtCovered: 8},
+		"github.com/example/project/pkg/core":   {Dir: "github.com/example/project/pkg/core", StmtCount: 10, StmtCovered: 10},
+		"github.com/example/project/cmd/server": {Dir: "github.com/example/project/cmd/server", StmtCount: 10, StmtCovered: 4},
+		"github.com/example/project/internal":   {Dir: "github.com/example/project/internal", StmtCount: 10, StmtCovered: 1},
+	}
+
+	t.Run("no thresholds", func(t *testing.T) {
+		if violations := CheckDirectoryThresholds(coverageByDir, nil, DefaultPrecision); len(violations) != 0 {
+			t.Errorf("Expected no violations, got %v", violations)
+		}
+	})
+
+	t.Run("violations are reported for every failing directory", func(t *testing.T) {
+		thresholds := map[string]float64{
+			"pkg/*": 90,
+			"cmd/*": 50,
+		}
+
+		violations := CheckDirectoryThresholds(coverageByDir, thresholds, DefaultPrecision)
+		if len(violations) != 2 {
+			t.Fatalf("Expected 2 violations, got %v", violations)
+		}
+		if violations[0].Directory != "github.com/example/project/cmd/server" || violations[0].Pattern != "cmd/*" {
+			t.Errorf("Unexpected first violation: %+v", violations[0])
+		}
+		if violations[1].Directory != "github.com/example/project/pkg/util" || violations[1].Threshold != 90 {
+			t.Errorf("Unexpected second violation: %+v", violations[1])
+		}
+	})
+
+	t.Run("most specific pattern wins", func(t *testing.T) {
+		thresholds := map[string]float64{
+			"pkg/*":      90,
+			"*/pkg/util": 70,
+		}
+
+		violations := CheckDirectoryThresholds(coverageByDir, thresholds, DefaultPrecision)
+		if len(violations) != 0 {
+			t.Errorf("Expected pkg/util to use the more specific 70%% threshold, got %v", violations)
+		}
+	})
+}
+
+func TestCheckMinDirectoryCoverage(t *testing.T) {
+	coverageByDir := map[string]*DirCoverage{
+		"pkg/util":   {Dir: "pkg/util", StmtCount: 10, StmtCovered: 8},
+		"pkg/core":   {Dir: "pkg/core", StmtCount: 10, StmtCovered: 6},
+		"cmd/server": {Dir: "cmd/server", StmtCount: 10, StmtCovered: 4},
+		"docs":       {Dir: "docs", StmtCount: 0, StmtCovered: 0},
+	}
+
+	if violations := CheckMinDirectoryCoverage(coverageByDir, 0, DefaultPrecision); len(violations) != 0 {
+		t.Errorf("Expected a minimum of 0 to disable the check, got %v", violations)
+	}
+
+	violations := CheckMinDirectoryCoverage(coverageByDir, 70, DefaultPrecision)
+	want := []ThresholdViolation{
+		{Directory: "cmd/server", Threshold: 70, Actual: 40},
+		{Directory: "pkg/core", Threshold: 70, Actual: 60},
+	}
+	if !reflect.DeepEqual(violations, want) {
+		t.Errorf("CheckMinDirectoryCoverage() = %+v, want %+v", violations, want)
+	}
+
+	// 69.96% is displayed as 70.0%, so it only fails when compared at two places
+	boundary := map[string]*DirCoverage{"pkg/edge": {Dir: "pkg/edge", StmtCount: 2500, StmtCovered: 1749}}
+	if violations := CheckMinDirectoryCoverage(boundary, 70, DefaultPrecision); len(violations) != 0 {
+		t.Errorf("Expected 69.96%% to pass 70 at one place, got %v", violations)
+	}
+	if violations := CheckMinDirectoryCoverage(boundary, 70, 2); len(violations) != 1 {
+		t.Errorf("Expected 69.96%% to fail 70 at two places, got %v", violations)
+	}
+}
+
+func BenchmarkAggregateWithIgnorePatterns(b *testing.B) {
+	var profiles []*cover.Profile
+	for i := range 1000 {
+		profiles = append(profiles, &cover.Profile{
+			FileName: fmt.Sprintf("github.com/example/project/pkg/module%d/sub%d/file.go", i%50, i%7),
+			Mode:     "set",
+			Blocks: []cover.ProfileBlock{
+				{StartLine: 1, EndLine: 10, NumStmt: 10, Count: 1},
+			},
+		})
+	}
+	patterns := []string{"*/vendor/*", "*/test/*", "*/internal/*", "module1*", "*/mocks", "pkg/module4*/sub3"}
+
+	analyzer := NewCoverageAnalyzer(0, patterns)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = analyzer.Aggregate(profiles)
+	}
+}
@@ -0,0 +1,206 @@
+package gocov
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/cover"
+)
+
+// thresholdDirective matches a "//gocov:threshold 90" comment, which sets the minimum coverage of its package
+var thresholdDirective = regexp.MustCompile(`^//gocov:threshold\s+(\S+)$`)
+
+// ignoreDirective matches a "//gocov:ignore" comment, which excludes the block starting on its line
+var ignoreDirective = regexp.MustCompile(`//gocov:ignore(\s|$)`)
+
+// SourceThresholdPattern is the ThresholdViolation pattern reported for //gocov:threshold directives
+const SourceThresholdPattern = "//gocov:threshold"
+
+// SourceThresholds reads //gocov:threshold directives from the .go files next to the profiled files
+// The result is keyed by the aggregated directory, like the result of Aggregate;
+// when several packages are aggregated into one directory the highest threshold wins.
+// Profile file names are mapped to disk through the module root (see SetModuleRoot),
+// or the working directory without one. Unreadable directories and invalid values are skipped
+func (a *CoverageAnalyzer) SourceThresholds(profiles []*cover.Profile) map[string]float64 {
+	root := newModuleRoot("", "")
+	if a.root != nil {
+		root = *a.root
+	}
+
+	thresholds := make(map[string]float64)
+	scanned := make(map[string]bool)
+	for _, profile := range profiles {
+		if a.shouldSkipProfile(profile) {
+			continue
+		}
+		dir := a.GroupKey(profile.FileName)
+		if a.shouldSkipDirectory(dir) {
+			continue
+		}
+
+		sourceDir := filepath.Dir(root.sourcePath(profile.FileName))
+		if scanned[sourceDir] {
+			continue
+		}
+		scanned[sourceDir] = true
+
+		threshold, ok := readDirectoryThreshold(sourceDir)
+		if !ok {
+			continue
+		}
+		dir = a.adjustDirectoryLevel(dir)
+		if existing, exists := thresholds[dir]; !exists || threshold > existing {
+			thresholds[dir] = threshold
+		}
+	}
+	return thresholds
+}
+
+// readDirectoryThreshold returns the first //gocov:threshold directive in the non-test .go files of dir
+func readDirectoryThreshold(dir string) (float64, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		// Fail soft: without sources there is nothing to enforce
+		return 0, false
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if threshold, ok := readFileThreshold(filepath.Join(dir, name)); ok {
+			return threshold, true
+		}
+	}
+	return 0, false
+}
+
+// readFileThreshold returns the first valid //gocov:threshold directive in path
+func readFileThreshold(path string) (float64, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		match := thresholdDirective.FindStringSubmatch(strings.TrimRight(scanner.Text(), " \t\r"))
+		if match == nil {
+			continue
+		}
+		threshold, err := strconv.ParseFloat(match[1], 64)
+		if err != nil || threshold < 0 || threshold > 100 {
+			continue
+		}
+		return threshold, true
+	}
+	return 0, false
+}
+
+// CheckSourceThresholds returns a violation for every directory below its //gocov:threshold directive
+// thresholds is the result of SourceThresholds; directories are reported in sorted order.
+// Coverage is compared rounded to places (see BelowThreshold)
+func CheckSourceThresholds(coverageByDir map[string]*DirCoverage, thresholds map[string]float64, places int) []ThresholdViolation {
+	dirs := make([]string, 0, len(thresholds))
+	for dir := range thresholds {
+		if _, ok := coverageByDir[dir]; ok {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+
+	var violations []ThresholdViolation
+	for _, dir := range dirs {
+		cov := coverageByDir[dir]
+		coverage := CalculateCoverage(cov.StmtCount, cov.StmtCovered)
+		if BelowThreshold(coverage, thresholds[dir], places) {
+			violations = append(violations, ThresholdViolation{
+				Directory: dir,
+				Pattern:   SourceThresholdPattern,
+				Threshold: thresholds[dir],
+				Actual:    coverage,
+			})
+		}
+	}
+	return violations
+}
+
+// ignoreDirectives finds the //gocov:ignore lines of profiled files, caching the result per file
+type ignoreDirectives struct {
+	root moduleRoot
+
+	mu    sync.Mutex
+	cache map[string]map[int]bool
+}
+
+// SetIgnoreDirectives drops blocks whose start line carries a //gocov:ignore comment, such as
+// defensive panics that cannot be tested, from both the statement and covered counts.
+// Profile file names are mapped to disk by replacing modulePath with dir; files that cannot be read keep every block
+func (a *CoverageAnalyzer) SetIgnoreDirectives(modulePath, dir string) {
+	a.ignored = &ignoreDirectives{
+		root:  newModuleRoot(modulePath, dir),
+		cache: make(map[string]map[int]bool),
+	}
+}
+
+// lines returns the //gocov:ignore lines of fileName, reading the source at most once
+func (d *ignoreDirectives) lines(fileName string) map[int]bool {
+	d.mu.Lock()
+	lines, ok := d.cache[fileName]
+	d.mu.Unlock()
+	if ok {
+		return lines
+	}
+
+	lines = ignoredLines(d.root.sourcePath(fileName))
+
+	d.mu.Lock()
+	d.cache[fileName] = lines
+	d.mu.Unlock()
+	return lines
+}
+
+// dropIgnored returns blocks without those starting on a //gocov:ignore line of fileName
+func (d *ignoreDirectives) dropIgnored(fileName string, blocks []cover.ProfileBlock) []cover.ProfileBlock {
+	lines := d.lines(fileName)
+	if len(lines) == 0 {
+		return blocks
+	}
+	kept := make([]cover.ProfileBlock, 0, len(blocks))
+	for _, block := range blocks {
+		if !lines[block.StartLine] {
+			kept = append(kept, block)
+		}
+	}
+	return kept
+}
+
+// ignoredLines returns the 1-based numbers of the lines in path carrying a //gocov:ignore comment
+func ignoredLines(path string) map[int]bool {
+	file, err := os.Open(path)
+	if err != nil {
+		// Fail soft: a missing source keeps all of its blocks
+		return nil
+	}
+	defer file.Close()
+
+	var lines map[int]bool
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if !ignoreDirective.MatchString(scanner.Text()) {
+			continue
+		}
+		if lines == nil {
+			lines = make(map[int]bool)
+		}
+		lines[line] = true
+	}
+	return lines
+}
@@ -0,0 +1,169 @@
+package gocov
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestReadFileThreshold(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+		want    float64
+		wantOK  bool
+	}{
+		{"package comment", "//gocov:threshold 90\npackage pay\n", 90, true},
+		{"after code", "package pay\n\nfunc f() {}\n\n//gocov:threshold 72.5\n", 72.5, true},
+		{"crlf", "//gocov:threshold 80\r\npackage pay\r\n", 80, true},
+		{"first valid wins", "//gocov:threshold high\n//gocov:threshold 120\n//gocov:threshold 60\n", 60, true},
+		{"space after slashes", "// gocov:threshold 90\npackage pay\n", 0, false},
+		{"no value", "//gocov:threshold\npackage pay\n", 0, false},
+		{"none", "package pay\n", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tmpDir, tt.name+".go")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write source file: %v", err)
+			}
+			got, ok := readFileThreshold(path)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("readFileThreshold() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSourceThresholds(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"pay/doc.go":        "//gocov:threshold 90\npackage pay\n",
+		"pay/pay.go":        "package pay\n",
+		"pay/card/card.go":  "//gocov:threshold 95\npackage card\n",
+		"util/util.go":      "package util\n",
+		"util/util_test.go": "//gocov:threshold 50\npackage util\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write source file: %v", err)
+		}
+	}
+
+	profiles := []*cover.Profile{
+		{FileName: "example.com/m/pay/pay.go", Blocks: []cover.ProfileBlock{{NumStmt: 10, Count: 1}}},
+		{FileName: "example.com/m/pay/card/card.go", Blocks: []cover.ProfileBlock{{NumStmt: 10, Count: 0}}},
+		{FileName: "example.com/m/util/util.go", Blocks: []cover.ProfileBlock{{NumStmt: 2, Count: 1}}},
+		// Not on disk, so it has no threshold
+		{FileName: "example.com/m/missing/a.go", Blocks: []cover.ProfileBlock{{NumStmt: 2, Count: 0}}},
+	}
+
+	t.Run("leaf directories", func(t *testing.T) {
+		analyzer := NewCoverageAnalyzer(0, nil)
+		analyzer.SetModuleRoot("example.com/m", tmpDir)
+		got := analyzer.SourceThresholds(profiles)
+		want := map[string]float64{"example.com/m/pay": 90, "example.com/m/pay/card": 95}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("SourceThresholds() = %v, want %v", got, want)
+		}
+
+		violations := CheckSourceThresholds(analyzer.Aggregate(profiles), got, DefaultPrecision)
+		wantViolations := []ThresholdViolation{{Directory: "example.com/m/pay/card", Pattern: SourceThresholdPattern, Threshold: 95, Actual: 0}}
+		if !reflect.DeepEqual(violations, wantViolations) {
+			t.Errorf("CheckSourceThresholds() = %+v, want %+v", violations, wantViolations)
+		}
+	})
+
+	t.Run("merged directories use the highest threshold", func(t *testing.T) {
+		analyzer := NewCoverageAnalyzer(3, nil)
+		analyzer.SetModuleRoot("example.com/m", tmpDir)
+		got := analyzer.SourceThresholds(profiles)
+		want := map[string]float64{"example.com/m/pay": 95}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("SourceThresholds() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("ignored directories", func(t *testing.T) {
+		analyzer := NewCoverageAnalyzer(0, []string{"*/card"})
+		analyzer.SetModuleRoot("example.com/m", tmpDir)
+		got := analyzer.SourceThresholds(profiles)
+		want := map[string]float64{"example.com/m/pay": 90}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("SourceThresholds() = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestIgnoredLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.go")
+	source := "package server\n" +
+		"\n" +
+		"func mustListen(err error) {\n" +
+		"\tif err != nil { //gocov:ignore cannot happen in tests\n" +
+		"\t\tpanic(err)\n" +
+		"\t}\n" +
+		"\t//gocov:ignore\r\n" +
+		"\t_ = \"//gocov:ignored is not the directive\"\n" +
+		"}\n"
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+
+	want := map[int]bool{4: true, 7: true}
+	if got := ignoredLines(path); !reflect.DeepEqual(got, want) {
+		t.Errorf("ignoredLines() = %v, want %v", got, want)
+	}
+	if got := ignoredLines(filepath.Join(t.TempDir(), "missing.go")); len(got) != 0 {
+		t.Errorf("ignoredLines() of a missing file = %v, want none", got)
+	}
+}
+
+func TestAggregateIgnoreDirectives(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "server"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	source := "package server\n\nfunc run(err error) {\n\tif err != nil { //gocov:ignore\n\t\tpanic(err)\n\t}\n\tserve()\n}\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "server", "server.go"), []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+
+	profiles := []*cover.Profile{
+		{FileName: "example.com/m/server/server.go", Blocks: []cover.ProfileBlock{
+			{StartLine: 3, StartCol: 22, EndLine: 4, EndCol: 16, NumStmt: 1, Count: 1},
+			{StartLine: 4, StartCol: 16, EndLine: 6, EndCol: 3, NumStmt: 1, Count: 0},
+			{StartLine: 7, StartCol: 2, EndLine: 8, EndCol: 2, NumStmt: 1, Count: 1},
+		}},
+		// Not on disk, so every block is kept
+		{FileName: "example.com/m/server/missing.go", Blocks: []cover.ProfileBlock{{StartLine: 4, NumStmt: 2, Count: 0}}},
+	}
+
+	analyzer := NewCoverageAnalyzer(0, nil)
+	analyzer.SetIgnoreDirectives("example.com/m", tmpDir)
+
+	for name, aggregate := range map[string]func([]*cover.Profile) map[string]*DirCoverage{
+		"sequential": analyzer.Aggregate,
+		"concurrent": analyzer.AggregateConcurrent,
+	} {
+		t.Run(name, func(t *testing.T) {
+			cov := aggregate(profiles)["example.com/m/server"]
+			if cov == nil {
+				t.Fatal("Expected coverage for example.com/m/server")
+			}
+			if cov.StmtCount != 4 || cov.StmtCovered != 2 {
+				t.Errorf("The block on the //gocov:ignore line should be dropped, got %d/%d", cov.StmtCovered, cov.StmtCount)
+			}
+		})
+	}
+}
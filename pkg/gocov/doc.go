@@ -0,0 +1,15 @@
+// Package gocov aggregates Go coverage profiles by directory and formats the results.
+//
+// It is the library behind the gocov command:
+//
+//	profiles, err := cover.ParseProfiles("coverage.out")
+//	if err != nil {
+//		return err
+//	}
+//	analyzer := gocov.NewCoverageAnalyzer(0, nil)
+//	coverageByDir := analyzer.Aggregate(profiles)
+//	for _, dir := range gocov.FilterDirectories(coverageByDir, 0, 100, 0) {
+//		cov := coverageByDir[dir]
+//		fmt.Printf("%s %.1f%%\n", dir, gocov.CalculateCoverage(cov.StmtCount, cov.StmtCovered))
+//	}
+package gocov
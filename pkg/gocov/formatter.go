@@ -0,0 +1,673 @@
+package gocov
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:embed templates/report.html
+var htmlReportTemplate string
+
+// JSONSchemaVersion is the version of the JSON output schema
+// Bump it whenever the shape of the JSON output changes
+const JSONSchemaVersion = "1"
+
+// CoverageResult represents the coverage data for output
+type CoverageResult struct {
+	Directory  string  `json:"directory"`
+	Statements int     `json:"statements"`
+	Covered    int     `json:"covered"`
+	Coverage   float64 `json:"coverage"`
+	// Hits is the sum of execution counts weighted by statements (-show-hits)
+	Hits *int `json:"hits,omitempty"`
+	// Executions is the sum of block execution counts (-weighted)
+	Executions *int `json:"executions,omitempty"`
+	// LineCount and LineCovered are line-based metrics (-lines)
+	LineCount   *int `json:"line_count,omitempty"`
+	LineCovered *int `json:"line_covered,omitempty"`
+	// UncoveredBlocks lists the uncovered line ranges in verbose mode
+	UncoveredBlocks []UncoveredBlock `json:"uncovered_blocks,omitempty"`
+	// Pass reports whether the directory meets its per-directory threshold; nil when none applies
+	Pass *bool `json:"pass,omitempty"`
+}
+
+// JSONReport is the document written by JSONFormatter
+// Its field names and JSON keys are the stable contract for -format json consumers
+type JSONReport struct {
+	// Version is JSONSchemaVersion
+	Version string `json:"version"`
+	// Results holds one entry per displayed directory, sorted by directory unless the caller keeps its own order (see SetKeepOrder)
+	Results []CoverageResult `json:"results"`
+	// Total covers every aggregated directory
+	Total CoverageResult `json:"total"`
+	// FilteredTotal covers only the directories that pass the filters and is omitted when no filter is active
+	FilteredTotal *CoverageResult `json:"filtered_total,omitempty"`
+	// Excluded holds the directories dropped by the filters, sorted by directory, and is omitted when none were dropped
+	// Results and Excluded together cover every directory counted in Total, except for the rows left out by -top
+	Excluded []CoverageResult `json:"excluded,omitempty"`
+	// Threshold is the required total coverage, only present when a threshold is checked
+	Threshold *float64 `json:"threshold,omitempty"`
+	// Passed reports whether every threshold check passed, only present when a threshold is checked
+	Passed *bool `json:"passed,omitempty"`
+	// Stats is only present with -stats
+	Stats *Stats `json:"stats,omitempty"`
+	// Metadata is only present with -metadata
+	Metadata *Metadata `json:"metadata,omitempty"`
+}
+
+// Metadata records when and how a JSON report was generated
+type Metadata struct {
+	// GeneratedAt is the generation time in RFC 3339 format (UTC)
+	GeneratedAt string `json:"generated_at"`
+	// Command is the invoked command line
+	Command []string `json:"command"`
+}
+
+// OutputFormatter interface for different output formats
+type OutputFormatter interface {
+	Format(results []CoverageResult, totalResult CoverageResult, filteredTotal *CoverageResult) error
+}
+
+// DefaultColumnWidth is the width of the directory column in table output
+const DefaultColumnWidth = 50
+
+// MinColumnWidth is the narrowest directory column that SetWidth accepts
+const MinColumnWidth = 10
+
+// TableFormatter formats output as a table
+type TableFormatter struct {
+	writer io.Writer
+	quiet  bool
+	// hideTotal omits the TOTAL and FILTERED TOTAL rows
+	hideTotal bool
+	directoryColumn
+	// ascii marks the pass column with PASS and FAIL instead of check marks
+	ascii bool
+	// groupBy names the first column; see SetGroupBy
+	groupBy string
+	coveragePrecision
+}
+
+// directoryColumn holds the width of the directory column; table-style formatters embed it
+type directoryColumn struct {
+	// width is the directory column width; 0 pads to DefaultColumnWidth without truncating
+	width int
+}
+
+// SetWidth sets the directory column width, truncating longer names
+// Values below MinColumnWidth are raised to it; 0 restores the default, which pads to DefaultColumnWidth
+// but keeps longer names intact
+func (c *directoryColumn) SetWidth(width int) {
+	if width > 0 && width < MinColumnWidth {
+		width = MinColumnWidth
+	}
+	c.width = width
+}
+
+// columnWidth returns the directory column width
+func (c directoryColumn) columnWidth() int {
+	if c.width > 0 {
+		return c.width
+	}
+	return DefaultColumnWidth
+}
+
+// label returns name truncated to the column width when a width is set
+func (c directoryColumn) label(name string) string {
+	if c.width > 0 {
+		return TruncateString(name, c.width)
+	}
+	return name
+}
+
+// coveragePrecision holds the decimal places coverage is displayed with; text formatters embed it
+type coveragePrecision struct {
+	// places is nil for DefaultPrecision
+	places *int
+}
+
+// SetPrecision sets the decimal places of displayed coverage, which should match the places thresholds are compared at
+func (p *coveragePrecision) SetPrecision(places int) {
+	p.places = &places
+}
+
+// format returns coverage rounded to the configured places, without a percent sign
+func (p coveragePrecision) format(coverage float64) string {
+	places := DefaultPrecision
+	if p.places != nil {
+		places = *p.places
+	}
+	return FormatCoverage(coverage, places)
+}
+
+// JSONFormatter formats output as JSON
+type JSONFormatter struct {
+	writer io.Writer
+	quiet  bool
+	stats  *Stats
+	// indent selects pretty-printed output over single-line JSON
+	indent bool
+	// hideTotal omits the total and filtered_total objects
+	hideTotal bool
+	// threshold and passed hold the threshold check result, which is only written when it is set
+	threshold *float64
+	passed    *bool
+	// command is recorded in the metadata object, which is only written when it is set
+	command []string
+	// excluded holds the directories dropped by the filters; nil when no filter is active
+	excluded []CoverageResult
+	// keepOrder writes results in the caller's order instead of sorting them by directory
+	keepOrder bool
+	// clock provides the metadata timestamp; tests replace it for deterministic output
+	clock func() time.Time
+}
+
+// HTMLFormatter formats output as a self-contained HTML report
+type HTMLFormatter struct {
+	writer io.Writer
+}
+
+// SummaryFormatter formats output as a single summary line
+type SummaryFormatter struct {
+	writer io.Writer
+	coveragePrecision
+}
+
+// TreeFormatter formats output as an indented directory tree with aggregated coverage per node
+type TreeFormatter struct {
+	writer io.Writer
+	quiet  bool
+	// hideTotal omits the TOTAL and FILTERED TOTAL rows
+	hideTotal bool
+	// groupBy names the first column; see SetGroupBy
+	groupBy string
+	directoryColumn
+	coveragePrecision
+}
+
+// TSVFormatter formats output as tab-separated values for cut and awk
+type TSVFormatter struct {
+	writer io.Writer
+	quiet  bool
+	// header adds a header row before the data
+	header bool
+	// hideTotal omits the TOTAL and FILTERED TOTAL lines
+	hideTotal bool
+	// groupBy names the first header column; see SetGroupBy
+	groupBy string
+	coveragePrecision
+}
+
+// NewTableFormatter creates a TableFormatter writing to w
+// In quiet mode only the TOTAL row is written
+func NewTableFormatter(w io.Writer, quiet bool) *TableFormatter {
+	return &TableFormatter{writer: w, quiet: quiet}
+}
+
+// SetHideTotal omits the TOTAL and FILTERED TOTAL rows, e.g. when the table is parsed by another tool
+func (f *TableFormatter) SetHideTotal(hide bool) {
+	f.hideTotal = hide
+}
+
+// SetASCII marks the pass column with PASS and FAIL instead of ✓ and ✗, for terminals without Unicode
+func (f *TableFormatter) SetASCII(ascii bool) {
+	f.ascii = ascii
+}
+
+// SetGroupBy labels the first column "Package" for GroupByPackage and "Directory" otherwise
+func (f *TableFormatter) SetGroupBy(by string) {
+	f.groupBy = by
+}
+
+// passMark returns the pass column cell for pass, which is empty for rows without a threshold
+func (f *TableFormatter) passMark(pass *bool) string {
+	switch {
+	case pass == nil:
+		return ""
+	case f.ascii && *pass:
+		return "PASS"
+	case f.ascii:
+		return "FAIL"
+	case *pass:
+		return "✓"
+	default:
+		return "✗"
+	}
+}
+
+// NewJSONFormatter creates a JSONFormatter writing to w
+// In quiet mode only the version and total are written
+func NewJSONFormatter(w io.Writer, quiet bool) *JSONFormatter {
+	return &JSONFormatter{writer: w, quiet: quiet, indent: true, clock: time.Now}
+}
+
+// SetHideTotal omits the "total" and "filtered_total" objects from the JSON output
+func (f *JSONFormatter) SetHideTotal(hide bool) {
+	f.hideTotal = hide
+}
+
+// SetStats adds a "stats" object to the JSON output
+func (f *JSONFormatter) SetStats(stats Stats) {
+	f.stats = &stats
+}
+
+// SetThresholdResult adds the required total coverage and whether all threshold checks passed to the JSON output
+func (f *JSONFormatter) SetThresholdResult(threshold float64, passed bool) {
+	f.threshold = &threshold
+	f.passed = &passed
+}
+
+// SetExcluded adds an "excluded" array with the directories dropped by the filters to the JSON output,
+// so consumers can tell them apart from the rows in "results"; an empty slice leaves it out
+func (f *JSONFormatter) SetExcluded(excluded []CoverageResult) {
+	f.excluded = excluded
+}
+
+// SetMetadata adds a "metadata" object with the generation time and command to the JSON output
+func (f *JSONFormatter) SetMetadata(command []string) {
+	f.command = command
+}
+
+// metadata returns the metadata object, or nil when SetMetadata was not called
+func (f *JSONFormatter) metadata() *Metadata {
+	if f.command == nil {
+		return nil
+	}
+	return &Metadata{
+		GeneratedAt: f.clock().UTC().Format(time.RFC3339),
+		Command:     f.command,
+	}
+}
+
+// SetIndent switches between pretty-printed (the default) and compact single-line output
+func (f *JSONFormatter) SetIndent(indent bool) {
+	f.indent = indent
+}
+
+// SetKeepOrder writes results in the order they are passed, e.g. worst first for -top, instead of sorting them by directory
+func (f *JSONFormatter) SetKeepOrder(keep bool) {
+	f.keepOrder = keep
+}
+
+// NewHTMLFormatter creates an HTMLFormatter writing to w
+func NewHTMLFormatter(w io.Writer) *HTMLFormatter {
+	return &HTMLFormatter{writer: w}
+}
+
+// NewTreeFormatter creates a TreeFormatter writing to w
+// In quiet mode only the TOTAL row is written
+func NewTreeFormatter(w io.Writer, quiet bool) *TreeFormatter {
+	return &TreeFormatter{writer: w, quiet: quiet}
+}
+
+// SetHideTotal omits the TOTAL and FILTERED TOTAL rows
+func (f *TreeFormatter) SetHideTotal(hide bool) {
+	f.hideTotal = hide
+}
+
+// SetGroupBy labels the first column "Package" for GroupByPackage and "Directory" otherwise
+func (f *TreeFormatter) SetGroupBy(by string) {
+	f.groupBy = by
+}
+
+// NewSummaryFormatter creates a SummaryFormatter writing to w
+func NewSummaryFormatter(w io.Writer) *SummaryFormatter {
+	return &SummaryFormatter{writer: w}
+}
+
+// NewTSVFormatter creates a TSVFormatter writing to w
+// Output has no header row unless SetHeader is called; in quiet mode only the TOTAL line is written
+func NewTSVFormatter(w io.Writer, quiet bool) *TSVFormatter {
+	return &TSVFormatter{writer: w, quiet: quiet}
+}
+
+// SetHeader toggles the header row
+func (f *TSVFormatter) SetHeader(header bool) {
+	f.header = header
+}
+
+// SetHideTotal omits the TOTAL and FILTERED TOTAL lines
+func (f *TSVFormatter) SetHideTotal(hide bool) {
+	f.hideTotal = hide
+}
+
+// SetGroupBy names the first header column "package" for GroupByPackage and "directory" otherwise
+func (f *TSVFormatter) SetGroupBy(by string) {
+	f.groupBy = by
+}
+
+// groupLabel returns the title of the column holding the aggregation key for a grouping mode
+func groupLabel(by string) string {
+	if by == GroupByPackage {
+		return "Package"
+	}
+	return "Directory"
+}
+
+// Format implements OutputFormatter for TableFormatter
+func (f *TableFormatter) Format(results []CoverageResult, totalResult CoverageResult, filteredTotal *CoverageResult) error {
+	// Hits, executions and lines are only populated when -show-hits, -weighted and -lines are enabled
+	showHits := totalResult.Hits != nil
+	showExecutions := totalResult.Executions != nil
+	showLines := totalResult.LineCount != nil
+	// The pass column appears once any directory has a per-directory threshold
+	showPass := false
+	for _, result := range results {
+		showPass = showPass || result.Pass != nil
+	}
+
+	// Quiet mode only shows the total line
+	if f.quiet {
+		if !f.hideTotal {
+			f.writeRow("TOTAL", totalResult, showHits, showExecutions, showLines, false)
+		}
+		return nil
+	}
+
+	// Display header
+	columnWidth := f.columnWidth()
+	width := columnWidth + 30
+	fmt.Fprintf(f.writer, "%-*s %10s %10s %8s", columnWidth, groupLabel(f.groupBy), "Statements", "Covered", "Coverage")
+	if showHits {
+		fmt.Fprintf(f.writer, " %12s", "Hits")
+		width += 13
+	}
+	if showExecutions {
+		fmt.Fprintf(f.writer, " %12s", "Executions")
+		width += 13
+	}
+	if showLines {
+		fmt.Fprintf(f.writer, " %15s %8s", "Lines", "Line Cov")
+		width += 25
+	}
+	if showPass {
+		fmt.Fprintf(f.writer, " %4s", "Pass")
+		width += 5
+	}
+	fmt.Fprintln(f.writer)
+	fmt.Fprintln(f.writer, strings.Repeat("-", width))
+
+	// Display results
+	for _, result := range results {
+		f.writeRow(result.Directory, result, showHits, showExecutions, showLines, showPass)
+
+		// Show uncovered line ranges if any (verbose mode)
+		for _, line := range formatUncoveredBlocks(result.UncoveredBlocks) {
+			fmt.Fprintln(f.writer, line)
+		}
+	}
+
+	if f.hideTotal {
+		return nil
+	}
+
+	// Display total
+	fmt.Fprintln(f.writer, strings.Repeat("-", width))
+
+	// Show filtered total if provided
+	if filteredTotal != nil {
+		f.writeRow("FILTERED TOTAL", *filteredTotal, showHits, showExecutions, showLines, showPass)
+	}
+
+	f.writeRow("TOTAL", totalResult, showHits, showExecutions, showLines, showPass)
+
+	return nil
+}
+
+// writeRow writes a single table row with the given label
+func (f *TableFormatter) writeRow(label string, result CoverageResult, showHits, showExecutions, showLines, showPass bool) {
+	fmt.Fprintf(f.writer, "%-*s %10d %10d %7s%%", f.columnWidth(), f.label(label), result.Statements, result.Covered, f.format(result.Coverage))
+	if showHits {
+		hits := 0
+		if result.Hits != nil {
+			hits = *result.Hits
+		}
+		fmt.Fprintf(f.writer, " %12d", hits)
+	}
+	if showExecutions {
+		executions := 0
+		if result.Executions != nil {
+			executions = *result.Executions
+		}
+		fmt.Fprintf(f.writer, " %12d", executions)
+	}
+	if showLines {
+		lines, covered := 0, 0
+		if result.LineCount != nil && result.LineCovered != nil {
+			lines, covered = *result.LineCount, *result.LineCovered
+		}
+		fmt.Fprintf(f.writer, " %15s %7s%%", fmt.Sprintf("%d/%d", covered, lines), f.format(CalculateCoverage(lines, covered)))
+	}
+	if showPass {
+		fmt.Fprintf(f.writer, " %4s", f.passMark(result.Pass))
+	}
+	fmt.Fprintln(f.writer)
+}
+
+// Format implements OutputFormatter for JSONFormatter
+func (f *JSONFormatter) Format(results []CoverageResult, totalResult CoverageResult, filteredTotal *CoverageResult) error {
+	// Quiet mode only emits the total object
+	if f.quiet {
+		return f.encode(struct {
+			Version   string          `json:"version"`
+			Total     *CoverageResult `json:"total,omitempty"`
+			Threshold *float64        `json:"threshold,omitempty"`
+			Passed    *bool           `json:"passed,omitempty"`
+			Stats     *Stats          `json:"stats,omitempty"`
+			Metadata  *Metadata       `json:"metadata,omitempty"`
+		}{
+			Version:   JSONSchemaVersion,
+			Total:     f.total(totalResult),
+			Threshold: f.threshold,
+			Passed:    f.passed,
+			Stats:     f.stats,
+			Metadata:  f.metadata(),
+		})
+	}
+
+	if !f.keepOrder {
+		results = sortByDirectory(results)
+	}
+	output := JSONReport{
+		Version:       JSONSchemaVersion,
+		Results:       results,
+		Total:         totalResult,
+		FilteredTotal: filteredTotal,
+		Threshold:     f.threshold,
+		Passed:        f.passed,
+		Stats:         f.stats,
+		Metadata:      f.metadata(),
+	}
+	if len(f.excluded) > 0 {
+		output.Excluded = sortByDirectory(f.excluded)
+	}
+
+	if f.hideTotal {
+		// The outer fields shadow the embedded ones, so both totals are left out
+		return f.encode(struct {
+			*JSONReport
+			Total         *CoverageResult `json:"total,omitempty"`
+			FilteredTotal *CoverageResult `json:"filtered_total,omitempty"`
+		}{JSONReport: &output})
+	}
+	return f.encode(output)
+}
+
+// sortByDirectory returns a copy of results sorted by directory, guaranteeing deterministic ordering regardless of the caller
+func sortByDirectory(results []CoverageResult) []CoverageResult {
+	sorted := make([]CoverageResult, len(results))
+	copy(sorted, results)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Directory < sorted[j].Directory
+	})
+	return sorted
+}
+
+// total returns the total object, or nil when it is hidden
+func (f *JSONFormatter) total(totalResult CoverageResult) *CoverageResult {
+	if f.hideTotal {
+		return nil
+	}
+	return &totalResult
+}
+
+// encode writes v as a single JSON document followed by a newline
+func (f *JSONFormatter) encode(v any) error {
+	var data []byte
+	var err error
+	if f.indent {
+		data, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		data, err = json.Marshal(v)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = f.writer.Write(append(data, '\n'))
+	return err
+}
+
+// formatUncoveredBlocks groups uncovered blocks by file and renders one line per file
+func formatUncoveredBlocks(blocks []UncoveredBlock) []string {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	var lines []string
+	var ranges []string
+	currentFile := blocks[0].File
+
+	for _, block := range blocks {
+		if block.File != currentFile {
+			lines = append(lines, fmt.Sprintf("  Uncovered lines in %s: %s", currentFile, strings.Join(ranges, ", ")))
+			currentFile = block.File
+			ranges = ranges[:0]
+		}
+		if block.StartLine == block.EndLine {
+			ranges = append(ranges, fmt.Sprintf("%d", block.StartLine))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", block.StartLine, block.EndLine))
+		}
+	}
+	lines = append(lines, fmt.Sprintf("  Uncovered lines in %s: %s", currentFile, strings.Join(ranges, ", ")))
+
+	return lines
+}
+
+// Format implements OutputFormatter for HTMLFormatter
+func (f *HTMLFormatter) Format(results []CoverageResult, totalResult CoverageResult, filteredTotal *CoverageResult) error {
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"coverageClass": coverageClass,
+	}).Parse(htmlReportTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse HTML template: %w", err)
+	}
+
+	data := struct {
+		Results       []CoverageResult
+		Total         CoverageResult
+		FilteredTotal *CoverageResult
+	}{
+		Results:       results,
+		Total:         totalResult,
+		FilteredTotal: filteredTotal,
+	}
+
+	return tmpl.Execute(f.writer, data)
+}
+
+// Format implements OutputFormatter for SummaryFormatter
+func (f *SummaryFormatter) Format(results []CoverageResult, totalResult CoverageResult, filteredTotal *CoverageResult) error {
+	_, err := fmt.Fprintf(f.writer, "coverage: %s%% (%d/%d statements)\n", f.format(totalResult.Coverage), totalResult.Covered, totalResult.Statements)
+	return err
+}
+
+// Format implements OutputFormatter for TreeFormatter
+// Each node shows the coverage of its directory and everything below it; see BuildTree
+func (f *TreeFormatter) Format(results []CoverageResult, totalResult CoverageResult, filteredTotal *CoverageResult) error {
+	columnWidth := f.columnWidth()
+	width := columnWidth + 30
+	if !f.quiet {
+		fmt.Fprintf(f.writer, "%-*s %10s %10s %8s\n", columnWidth, groupLabel(f.groupBy), "Statements", "Covered", "Coverage")
+		fmt.Fprintln(f.writer, strings.Repeat("-", width))
+		for _, node := range BuildTree(results) {
+			f.writeNode(node, 0)
+		}
+		if !f.hideTotal {
+			fmt.Fprintln(f.writer, strings.Repeat("-", width))
+		}
+	}
+	if f.hideTotal {
+		return nil
+	}
+	if filteredTotal != nil && !f.quiet {
+		f.writeRow("FILTERED TOTAL", filteredTotal.Statements, filteredTotal.Covered)
+	}
+	_, err := f.writeRow("TOTAL", totalResult.Statements, totalResult.Covered)
+	return err
+}
+
+// writeNode writes node indented by depth, followed by its children
+func (f *TreeFormatter) writeNode(node *TreeNode, depth int) {
+	f.writeRow(strings.Repeat("  ", depth)+node.Name, node.Statements, node.Covered)
+	for _, child := range node.Children {
+		f.writeNode(child, depth+1)
+	}
+}
+
+// writeRow writes a single tree row with the given label
+func (f *TreeFormatter) writeRow(label string, statements, covered int) (int, error) {
+	return fmt.Fprintf(f.writer, "%-*s %10d %10d %7s%%\n", f.columnWidth(), f.label(label), statements, covered, f.format(CalculateCoverage(statements, covered)))
+}
+
+// Format implements OutputFormatter for TSVFormatter
+// Each line is directory, statements, covered, and coverage (a plain percentage without "%")
+func (f *TSVFormatter) Format(results []CoverageResult, totalResult CoverageResult, filteredTotal *CoverageResult) error {
+	if f.header {
+		fmt.Fprintf(f.writer, "%s\tstatements\tcovered\tcoverage\n", strings.ToLower(groupLabel(f.groupBy)))
+	}
+	if !f.quiet {
+		for _, result := range results {
+			f.writeRow(result.Directory, result)
+		}
+		if filteredTotal != nil && !f.hideTotal {
+			f.writeRow("FILTERED TOTAL", *filteredTotal)
+		}
+	}
+	if f.hideTotal {
+		return nil
+	}
+	_, err := f.writeRow("TOTAL", totalResult)
+	return err
+}
+
+// writeRow writes a single tab-separated line with the given label
+func (f *TSVFormatter) writeRow(label string, result CoverageResult) (int, error) {
+	return fmt.Fprintf(f.writer, "%s\t%d\t%d\t%s\n", label, result.Statements, result.Covered, f.format(result.Coverage))
+}
+
+// TruncateString shortens s to maxLen bytes, replacing the end with "..."
+func TruncateString(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
+
+// coverageClass returns the CSS class used to color a coverage bar
+func coverageClass(coverage float64) string {
+	switch {
+	case coverage >= 80:
+		return "high"
+	case coverage >= 50:
+		return "medium"
+	default:
+		return "low"
+	}
+}
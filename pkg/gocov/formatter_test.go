@@ -0,0 +1,977 @@
+package gocov
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutputFormatters(t *testing.T) {
+	results := []CoverageResult{
+		{
+			Directory:  "cmd/server",
+			Statements: 20,
+			Covered:    10,
+			Coverage:   50.0,
+		},
+		{
+			Directory:  "pkg/util",
+			Statements: 10,
+			Covered:    8,
+			Coverage:   80.0,
+		},
+	}
+
+	totalResult := CoverageResult{
+		Directory:  "TOTAL",
+		Statements: 30,
+		Covered:    18,
+		Coverage:   60.0,
+	}
+
+	t.Run("TableFormatter", func(t *testing.T) {
+		var buf bytes.Buffer
+		formatter := &TableFormatter{writer: &buf}
+
+		err := formatter.Format(results, totalResult, nil)
+		if err != nil {
+			t.Fatalf("TableFormatter failed: %v", err)
+		}
+
+		output := buf.String()
+
+		// Verify table output
+		if !strings.Contains(output, "Directory") {
+			t.Error("Table output should contain header")
+		}
+		if !strings.Contains(output, "80.0%") {
+			t.Error("Table output should contain coverage percentage")
+		}
+		if !strings.Contains(output, "TOTAL") {
+			t.Error("Table output should contain TOTAL line")
+		}
+	})
+
+	t.Run("TableFormatter with filtered total", func(t *testing.T) {
+		var buf bytes.Buffer
+		formatter := &TableFormatter{writer: &buf}
+
+		filteredTotal := &CoverageResult{
+			Directory:  "FILTERED TOTAL",
+			Statements: 10,
+			Covered:    8,
+			Coverage:   80.0,
+		}
+
+		err := formatter.Format(results, totalResult, filteredTotal)
+		if err != nil {
+			t.Fatalf("TableFormatter failed: %v", err)
+		}
+
+		output := buf.String()
+
+		// Verify filtered total appears
+		if !strings.Contains(output, "FILTERED TOTAL") {
+			t.Error("Table output should contain FILTERED TOTAL line")
+		}
+		if !strings.Contains(output, "TOTAL") {
+			t.Error("Table output should still contain TOTAL line")
+		}
+	})
+
+	t.Run("JSONFormatter", func(t *testing.T) {
+		var buf bytes.Buffer
+		formatter := &JSONFormatter{writer: &buf, indent: true}
+
+		err := formatter.Format(results, totalResult, nil)
+		if err != nil {
+			t.Fatalf("JSONFormatter failed: %v", err)
+		}
+
+		// Parse JSON output
+		var output struct {
+			Results []CoverageResult `json:"results"`
+			Total   CoverageResult   `json:"total"`
+		}
+
+		if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v", err)
+		}
+
+		// Verify JSON structure
+		if len(output.Results) != 2 {
+			t.Errorf("Expected 2 results, got %d", len(output.Results))
+		}
+
+		if output.Total.Statements != 30 {
+			t.Errorf("Expected total statements 30, got %d", output.Total.Statements)
+		}
+
+		if output.Total.Covered != 18 {
+			t.Errorf("Expected total covered 18, got %d", output.Total.Covered)
+		}
+	})
+
+	t.Run("JSONFormatter with filters", func(t *testing.T) {
+		var buf bytes.Buffer
+		formatter := &JSONFormatter{writer: &buf, indent: true}
+
+		filteredTotal := &CoverageResult{
+			Directory:  "FILTERED TOTAL",
+			Statements: 10,
+			Covered:    8,
+			Coverage:   80.0,
+		}
+
+		err := formatter.Format(results, totalResult, filteredTotal)
+		if err != nil {
+			t.Fatalf("JSONFormatter failed: %v", err)
+		}
+
+		// Parse JSON output
+		var output struct {
+			Results       []CoverageResult `json:"results"`
+			Total         CoverageResult   `json:"total"`
+			FilteredTotal *CoverageResult  `json:"filtered_total"`
+		}
+
+		if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v", err)
+		}
+
+		// Verify filtered results
+		if len(output.Results) != 2 {
+			t.Errorf("Expected 2 results, got %d", len(output.Results))
+		}
+
+		if output.FilteredTotal == nil {
+			t.Error("Expected filtered_total to be present")
+		} else if output.FilteredTotal.Statements != 10 {
+			t.Errorf("Expected filtered total statements 10, got %d", output.FilteredTotal.Statements)
+		}
+	})
+}
+
+func TestJSONReportKeys(t *testing.T) {
+	total := CoverageResult{Directory: "TOTAL", Statements: 10, Covered: 5, Coverage: 50.0}
+
+	tests := []struct {
+		name     string
+		report   JSONReport
+		wantKeys []string
+	}{
+		{
+			name:     "without filtered total",
+			report:   JSONReport{Version: JSONSchemaVersion, Results: []CoverageResult{}, Total: total},
+			wantKeys: []string{"version", "results", "total"},
+		},
+		{
+			name:     "with filtered total",
+			report:   JSONReport{Version: JSONSchemaVersion, Results: []CoverageResult{}, Total: total, FilteredTotal: &total},
+			wantKeys: []string{"version", "results", "total", "filtered_total"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.report)
+			if err != nil {
+				t.Fatalf("Failed to marshal JSONReport: %v", err)
+			}
+
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(data, &fields); err != nil {
+				t.Fatalf("Failed to parse JSON: %v", err)
+			}
+			if len(fields) != len(tt.wantKeys) {
+				t.Errorf("JSONReport keys = %v, want %v", fields, tt.wantKeys)
+			}
+			for _, key := range tt.wantKeys {
+				if _, ok := fields[key]; !ok {
+					t.Errorf("JSONReport is missing key %q in %s", key, data)
+				}
+			}
+		})
+	}
+
+	// The formatter output decodes back into the exported type
+	var buf bytes.Buffer
+	if err := NewJSONFormatter(&buf, false).Format(nil, total, nil); err != nil {
+		t.Fatalf("JSONFormatter failed: %v", err)
+	}
+	var report JSONReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to decode JSONReport: %v", err)
+	}
+	if report.Total.Statements != total.Statements || report.FilteredTotal != nil {
+		t.Errorf("Decoded report = %+v", report)
+	}
+}
+
+func TestJSONFormatterIndent(t *testing.T) {
+	results := []CoverageResult{{Directory: "pkg/util", Statements: 10, Covered: 8, Coverage: 80.0}}
+	totalResult := CoverageResult{Directory: "TOTAL", Statements: 10, Covered: 8, Coverage: 80.0}
+
+	t.Run("pretty by default", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := NewJSONFormatter(&buf, false).Format(results, totalResult, nil); err != nil {
+			t.Fatalf("JSONFormatter failed: %v", err)
+		}
+		if strings.Count(buf.String(), "\n") < 2 || !strings.Contains(buf.String(), "\n  \"results\"") {
+			t.Errorf("Default output should be indented\nGot: %s", buf.String())
+		}
+	})
+
+	t.Run("compact", func(t *testing.T) {
+		var buf bytes.Buffer
+		formatter := NewJSONFormatter(&buf, false)
+		formatter.SetIndent(false)
+		if err := formatter.Format(results, totalResult, nil); err != nil {
+			t.Fatalf("JSONFormatter failed: %v", err)
+		}
+
+		output := buf.String()
+		if strings.Count(output, "\n") != 1 || !strings.HasSuffix(output, "}\n") {
+			t.Errorf("Compact output should be a single line\nGot: %s", output)
+		}
+		var report JSONReport
+		if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to parse compact JSON: %v", err)
+		}
+		if len(report.Results) != 1 {
+			t.Errorf("Expected 1 result, got %d", len(report.Results))
+		}
+	})
+}
+
+func TestJSONFormatterKeepOrder(t *testing.T) {
+	results := []CoverageResult{
+		{Directory: "pkg/b", Statements: 10, Covered: 2, Coverage: 20.0},
+		{Directory: "pkg/a", Statements: 10, Covered: 5, Coverage: 50.0},
+	}
+	totalResult := CoverageResult{Directory: "TOTAL", Statements: 20, Covered: 7, Coverage: 35.0}
+
+	tests := []struct {
+		name      string
+		keepOrder bool
+		want      []string
+	}{
+		{"sorted by directory", false, []string{"pkg/a", "pkg/b"}},
+		{"caller order", true, []string{"pkg/b", "pkg/a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			formatter := NewJSONFormatter(&buf, false)
+			formatter.SetKeepOrder(tt.keepOrder)
+			if err := formatter.Format(results, totalResult, nil); err != nil {
+				t.Fatalf("JSONFormatter failed: %v", err)
+			}
+
+			var report JSONReport
+			if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+				t.Fatalf("Failed to parse JSON output: %v", err)
+			}
+			var got []string
+			for _, result := range report.Results {
+				got = append(got, result.Directory)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Results = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONFormatterMetadata(t *testing.T) {
+	results := []CoverageResult{{Directory: "pkg/util", Statements: 10, Covered: 8, Coverage: 80.0}}
+	totalResult := CoverageResult{Directory: "TOTAL", Statements: 10, Covered: 8, Coverage: 80.0}
+	clock := func() time.Time {
+		return time.Date(2024, 5, 1, 21, 30, 0, 0, time.FixedZone("JST", 9*60*60))
+	}
+
+	for _, quiet := range []bool{false, true} {
+		var buf bytes.Buffer
+		formatter := NewJSONFormatter(&buf, quiet)
+		formatter.clock = clock
+		formatter.SetMetadata([]string{"gocov", "-format", "json"})
+		if err := formatter.Format(results, totalResult, nil); err != nil {
+			t.Fatalf("JSONFormatter failed: %v", err)
+		}
+
+		var report JSONReport
+		if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to parse JSON: %v", err)
+		}
+		want := &Metadata{GeneratedAt: "2024-05-01T12:30:00Z", Command: []string{"gocov", "-format", "json"}}
+		if report.Metadata == nil || report.Metadata.GeneratedAt != want.GeneratedAt ||
+			strings.Join(report.Metadata.Command, " ") != strings.Join(want.Command, " ") {
+			t.Errorf("quiet=%v: metadata = %+v, want %+v", quiet, report.Metadata, want)
+		}
+		if report.Total.Statements != 10 {
+			t.Errorf("quiet=%v: total should be unchanged, got %+v", quiet, report.Total)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := NewJSONFormatter(&buf, false).Format(results, totalResult, nil); err != nil {
+		t.Fatalf("JSONFormatter failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "metadata") {
+		t.Errorf("metadata should be omitted by default\nGot: %s", buf.String())
+	}
+}
+
+func TestJSONFormatterThresholdResult(t *testing.T) {
+	results := []CoverageResult{{Directory: "pkg/util", Statements: 10, Covered: 7, Coverage: 70.0}}
+	totalResult := CoverageResult{Directory: "TOTAL", Statements: 10, Covered: 7, Coverage: 70.0}
+
+	for _, quiet := range []bool{false, true} {
+		var buf bytes.Buffer
+		formatter := NewJSONFormatter(&buf, quiet)
+		formatter.SetIndent(false)
+		formatter.SetThresholdResult(80, false)
+		if err := formatter.Format(results, totalResult, nil); err != nil {
+			t.Fatalf("JSONFormatter failed: %v", err)
+		}
+		if !strings.Contains(buf.String(), `"threshold":80,"passed":false`) {
+			t.Errorf("quiet=%v: expected threshold result\nGot: %s", quiet, buf.String())
+		}
+	}
+}
+
+func TestJSONFormatterExcluded(t *testing.T) {
+	results := []CoverageResult{{Directory: "pkg/util", Statements: 10, Covered: 8, Coverage: 80.0}}
+	totalResult := CoverageResult{Directory: "TOTAL", Statements: 20, Covered: 10, Coverage: 50.0}
+	filteredTotal := &CoverageResult{Directory: "FILTERED TOTAL", Statements: 10, Covered: 8, Coverage: 80.0}
+	excluded := []CoverageResult{
+		{Directory: "internal/b", Statements: 5, Covered: 1, Coverage: 20.0},
+		{Directory: "internal/a", Statements: 5, Covered: 1, Coverage: 20.0},
+	}
+
+	var buf bytes.Buffer
+	formatter := NewJSONFormatter(&buf, false)
+	formatter.SetExcluded(excluded)
+	if err := formatter.Format(results, totalResult, filteredTotal); err != nil {
+		t.Fatalf("JSONFormatter failed: %v", err)
+	}
+	var report JSONReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	if len(report.Excluded) != 2 || report.Excluded[0].Directory != "internal/a" || report.Excluded[1].Directory != "internal/b" {
+		t.Errorf("Excluded = %+v, want internal/a and internal/b in order", report.Excluded)
+	}
+
+	buf.Reset()
+	formatter.SetExcluded(nil)
+	if err := formatter.Format(results, totalResult, nil); err != nil {
+		t.Fatalf("JSONFormatter failed: %v", err)
+	}
+	if strings.Contains(buf.String(), `"excluded"`) {
+		t.Errorf("excluded should be omitted without filters\nGot: %s", buf.String())
+	}
+}
+
+func TestJSONFormatterSchema(t *testing.T) {
+	results := []CoverageResult{
+		{Directory: "pkg/util", Statements: 10, Covered: 8, Coverage: 80.0},
+		{Directory: "cmd/server", Statements: 20, Covered: 10, Coverage: 50.0},
+	}
+	totalResult := CoverageResult{Directory: "TOTAL", Statements: 30, Covered: 18, Coverage: 60.0}
+	filteredTotal := &CoverageResult{Directory: "FILTERED TOTAL", Statements: 30, Covered: 18, Coverage: 60.0}
+
+	var buf bytes.Buffer
+	formatter := &JSONFormatter{writer: &buf, indent: true}
+	if err := formatter.Format(results, totalResult, filteredTotal); err != nil {
+		t.Fatalf("JSONFormatter failed: %v", err)
+	}
+
+	// Top-level keys must be serialized in a stable order
+	decoder := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	if _, err := decoder.Token(); err != nil {
+		t.Fatalf("Failed to read JSON: %v", err)
+	}
+	var keys []string
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			t.Fatalf("Failed to read JSON key: %v", err)
+		}
+		keys = append(keys, token.(string))
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			t.Fatalf("Failed to read JSON value: %v", err)
+		}
+	}
+	wantKeys := []string{"version", "results", "total", "filtered_total"}
+	if strings.Join(keys, ",") != strings.Join(wantKeys, ",") {
+		t.Errorf("JSON keys = %v, want %v", keys, wantKeys)
+	}
+
+	var output struct {
+		Version string           `json:"version"`
+		Results []CoverageResult `json:"results"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	if output.Version != JSONSchemaVersion {
+		t.Errorf("version = %q, want %q", output.Version, JSONSchemaVersion)
+	}
+	if output.Results[0].Directory != "cmd/server" || output.Results[1].Directory != "pkg/util" {
+		t.Errorf("Results should be sorted by directory, got %v", output.Results)
+	}
+
+	// Result keys are stable as well
+	if !strings.Contains(buf.String(), `"directory": "cmd/server",
+      "statements": 20,
+      "covered": 10,
+      "coverage": 50`) {
+		t.Errorf("Result keys should be serialized in a stable order\nGot: %s", buf.String())
+	}
+}
+
+func TestTableFormatterVerbose(t *testing.T) {
+	results := []CoverageResult{
+		{
+			Directory:  "pkg/util",
+			Statements: 10,
+			Covered:    8,
+			Coverage:   80.0,
+			UncoveredBlocks: []UncoveredBlock{
+				{File: "pkg/util/helper.go", StartLine: 16, EndLine: 18},
+				{File: "pkg/util/helper.go", StartLine: 25, EndLine: 25},
+				{File: "pkg/util/math.go", StartLine: 9, EndLine: 11},
+			},
+		},
+	}
+	totalResult := CoverageResult{Directory: "TOTAL", Statements: 10, Covered: 8, Coverage: 80.0}
+
+	var buf bytes.Buffer
+	formatter := &TableFormatter{writer: &buf}
+	if err := formatter.Format(results, totalResult, nil); err != nil {
+		t.Fatalf("TableFormatter failed: %v", err)
+	}
+
+	output := buf.String()
+	expected := []string{
+		"  Uncovered lines in pkg/util/helper.go: 16-18, 25\n",
+		"  Uncovered lines in pkg/util/math.go: 9-11\n",
+	}
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("Output should contain %q\nGot: %s", want, output)
+		}
+	}
+}
+
+func TestTableFormatterPass(t *testing.T) {
+	pass, fail := true, false
+	results := []CoverageResult{
+		{Directory: "internal/service", Statements: 10, Covered: 9, Coverage: 90.0, Pass: &pass},
+		{Directory: "pkg/util", Statements: 10, Covered: 5, Coverage: 50.0, Pass: &fail},
+		{Directory: "tools", Statements: 10, Covered: 1, Coverage: 10.0},
+	}
+	totalResult := CoverageResult{Directory: "TOTAL", Statements: 30, Covered: 15, Coverage: 50.0}
+
+	tests := []struct {
+		name     string
+		ascii    bool
+		expected []string
+	}{
+		{"marks", false, []string{"90.0%    ✓\n", "50.0%    ✗\n", "10.0%     \n"}},
+		{"ascii", true, []string{"90.0% PASS\n", "50.0% FAIL\n", "10.0%     \n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			formatter := &TableFormatter{writer: &buf}
+			formatter.SetASCII(tt.ascii)
+			if err := formatter.Format(results, totalResult, nil); err != nil {
+				t.Fatalf("TableFormatter failed: %v", err)
+			}
+
+			output := buf.String()
+			if !strings.Contains(output, "Coverage Pass\n") {
+				t.Errorf("Table output should contain Pass column header\nGot: %s", output)
+			}
+			for _, want := range tt.expected {
+				if !strings.Contains(output, want) {
+					t.Errorf("Output should contain %q\nGot: %s", want, output)
+				}
+			}
+		})
+	}
+
+	t.Run("no thresholds", func(t *testing.T) {
+		var buf bytes.Buffer
+		formatter := &TableFormatter{writer: &buf}
+		if err := formatter.Format(results[2:], totalResult, nil); err != nil {
+			t.Fatalf("TableFormatter failed: %v", err)
+		}
+		if strings.Contains(buf.String(), "Pass") {
+			t.Errorf("Table output should not contain Pass column without thresholds\nGot: %s", buf.String())
+		}
+	})
+}
+
+func TestTableFormatterHits(t *testing.T) {
+	hits := 42
+	totalHits := 50
+	results := []CoverageResult{
+		{Directory: "pkg/util", Statements: 10, Covered: 8, Coverage: 80.0, Hits: &hits},
+	}
+	totalResult := CoverageResult{Directory: "TOTAL", Statements: 10, Covered: 8, Coverage: 80.0, Hits: &totalHits}
+
+	var buf bytes.Buffer
+	formatter := &TableFormatter{writer: &buf}
+	if err := formatter.Format(results, totalResult, nil); err != nil {
+		t.Fatalf("TableFormatter failed: %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "Hits") {
+		t.Error("Table output should contain Hits column header")
+	}
+	if !strings.Contains(output, "80.0%           42") {
+		t.Errorf("Table output should contain hit count for pkg/util\nGot: %s", output)
+	}
+	if !strings.Contains(output, "80.0%           50") {
+		t.Errorf("Table output should contain total hit count\nGot: %s", output)
+	}
+}
+
+func TestTableFormatterExecutions(t *testing.T) {
+	executions := 7
+	totalExecutions := 9
+	results := []CoverageResult{
+		{Directory: "pkg/util", Statements: 10, Covered: 8, Coverage: 80.0, Executions: &executions},
+	}
+	totalResult := CoverageResult{Directory: "TOTAL", Statements: 10, Covered: 8, Coverage: 80.0, Executions: &totalExecutions}
+
+	var buf bytes.Buffer
+	formatter := &TableFormatter{writer: &buf}
+	if err := formatter.Format(results, totalResult, nil); err != nil {
+		t.Fatalf("TableFormatter failed: %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "Executions") || strings.Contains(output, "Hits") {
+		t.Errorf("Table output should contain only the Executions column header\nGot: %s", output)
+	}
+	if !strings.Contains(output, "80.0%            7") {
+		t.Errorf("Table output should contain the execution count for pkg/util\nGot: %s", output)
+	}
+	if !strings.Contains(output, "80.0%            9") {
+		t.Errorf("Table output should contain the total execution count\nGot: %s", output)
+	}
+}
+
+func TestTableFormatterLines(t *testing.T) {
+	lines, linesCovered := 20, 15
+	totalLines, totalCovered := 40, 30
+	results := []CoverageResult{
+		{Directory: "pkg/util", Statements: 10, Covered: 8, Coverage: 80.0, LineCount: &lines, LineCovered: &linesCovered},
+	}
+	totalResult := CoverageResult{Directory: "TOTAL", Statements: 10, Covered: 8, Coverage: 80.0, LineCount: &totalLines, LineCovered: &totalCovered}
+
+	var buf bytes.Buffer
+	formatter := &TableFormatter{writer: &buf}
+	if err := formatter.Format(results, totalResult, nil); err != nil {
+		t.Fatalf("TableFormatter failed: %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "Lines Line Cov") {
+		t.Errorf("Table output should contain line column headers\nGot: %s", output)
+	}
+	if !strings.Contains(output, "15/20    75.0%") {
+		t.Errorf("Table output should contain line coverage for pkg/util\nGot: %s", output)
+	}
+	if !strings.Contains(output, "30/40    75.0%") {
+		t.Errorf("Table output should contain total line coverage\nGot: %s", output)
+	}
+
+	// Line coverage follows the precision of statement coverage
+	buf.Reset()
+	formatter.SetPrecision(2)
+	if err := formatter.Format(results, totalResult, nil); err != nil {
+		t.Fatalf("TableFormatter failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "15/20   75.00%") {
+		t.Errorf("Line coverage should use two decimal places\nGot: %s", buf.String())
+	}
+}
+
+func TestFormattersQuiet(t *testing.T) {
+	results := []CoverageResult{
+		{Directory: "cmd/server", Statements: 20, Covered: 10, Coverage: 50.0},
+	}
+	totalResult := CoverageResult{Directory: "TOTAL", Statements: 20, Covered: 10, Coverage: 50.0}
+	filteredTotal := &CoverageResult{Directory: "FILTERED TOTAL", Statements: 20, Covered: 10, Coverage: 50.0}
+
+	t.Run("TableFormatter", func(t *testing.T) {
+		var buf bytes.Buffer
+		formatter := &TableFormatter{writer: &buf, quiet: true}
+
+		if err := formatter.Format(results, totalResult, filteredTotal); err != nil {
+			t.Fatalf("TableFormatter failed: %v", err)
+		}
+
+		output := buf.String()
+		if strings.Count(output, "\n") != 1 {
+			t.Errorf("Quiet table output should be a single line, got: %q", output)
+		}
+		if !strings.HasPrefix(output, "TOTAL") || !strings.Contains(output, "50.0%") {
+			t.Errorf("Quiet table output should contain the TOTAL line, got: %q", output)
+		}
+	})
+
+	t.Run("JSONFormatter", func(t *testing.T) {
+		var buf bytes.Buffer
+		formatter := &JSONFormatter{writer: &buf, quiet: true, indent: true}
+
+		if err := formatter.Format(results, totalResult, filteredTotal); err != nil {
+			t.Fatalf("JSONFormatter failed: %v", err)
+		}
+
+		var output map[string]json.RawMessage
+		if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v", err)
+		}
+		if len(output) != 2 {
+			t.Errorf("Quiet JSON output should only contain version and total, got keys: %v", output)
+		}
+		if _, ok := output["total"]; !ok {
+			t.Error("Quiet JSON output should contain total")
+		}
+	})
+}
+
+func TestHTMLFormatter(t *testing.T) {
+	results := []CoverageResult{
+		{Directory: "cmd/server", Statements: 20, Covered: 10, Coverage: 50.0},
+		{Directory: "pkg/<script>", Statements: 10, Covered: 9, Coverage: 90.0},
+	}
+	totalResult := CoverageResult{Directory: "TOTAL", Statements: 30, Covered: 19, Coverage: 63.3}
+	filteredTotal := &CoverageResult{Directory: "FILTERED TOTAL", Statements: 10, Covered: 9, Coverage: 90.0}
+
+	var buf bytes.Buffer
+	formatter := &HTMLFormatter{writer: &buf}
+	if err := formatter.Format(results, totalResult, filteredTotal); err != nil {
+		t.Fatalf("HTMLFormatter failed: %v", err)
+	}
+
+	output := buf.String()
+	expected := []string{
+		"<!DOCTYPE html>",
+		"<td>cmd/server</td>",
+		"<td>50.0%</td>",
+		`class="fill medium" style="width: 50.0%"`,
+		`class="fill high" style="width: 90.0%"`,
+		"<td>FILTERED TOTAL</td>",
+		"<td>TOTAL</td>",
+		"pkg/&lt;script&gt;",
+	}
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("HTML output should contain %q", want)
+		}
+	}
+	if strings.Contains(output, "<script>") {
+		t.Error("HTML output should escape directory names")
+	}
+}
+
+func TestSummaryFormatter(t *testing.T) {
+	results := []CoverageResult{
+		{Directory: "cmd/server", Statements: 7, Covered: 5, Coverage: 71.4},
+	}
+	totalResult := CoverageResult{Directory: "TOTAL", Statements: 21, Covered: 16, Coverage: 76.19047619047619}
+	filteredTotal := &CoverageResult{Directory: "FILTERED TOTAL", Statements: 7, Covered: 5, Coverage: 71.4}
+
+	var buf bytes.Buffer
+	formatter := &SummaryFormatter{writer: &buf}
+	if err := formatter.Format(results, totalResult, filteredTotal); err != nil {
+		t.Fatalf("SummaryFormatter failed: %v", err)
+	}
+
+	want := "coverage: 76.2% (16/21 statements)\n"
+	if got := buf.String(); got != want {
+		t.Errorf("SummaryFormatter output = %q, want %q", got, want)
+	}
+	buf.Reset()
+	formatter.SetPrecision(2)
+	if err := formatter.Format(results, totalResult, filteredTotal); err != nil {
+		t.Fatalf("SummaryFormatter failed: %v", err)
+	}
+	if want := "coverage: 76.19% (16/21 statements)\n"; buf.String() != want {
+		t.Errorf("SummaryFormatter output with precision 2 = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestTSVFormatter(t *testing.T) {
+	results := []CoverageResult{
+		{Directory: "cmd/server", Statements: 7, Covered: 5, Coverage: 71.42857142857143},
+		{Directory: "pkg/util", Statements: 14, Covered: 11, Coverage: 78.57142857142857},
+	}
+	totalResult := CoverageResult{Directory: "TOTAL", Statements: 21, Covered: 16, Coverage: 76.19047619047619}
+	filteredTotal := &CoverageResult{Directory: "FILTERED TOTAL", Statements: 7, Covered: 5, Coverage: 71.42857142857143}
+
+	tests := []struct {
+		name          string
+		quiet         bool
+		header        bool
+		groupBy       string
+		filteredTotal *CoverageResult
+		want          string
+	}{
+		{
+			name: "no header by default",
+			want: "cmd/server\t7\t5\t71.4\npkg/util\t14\t11\t78.6\nTOTAL\t21\t16\t76.2\n",
+		},
+		{
+			name:   "header",
+			header: true,
+			want:   "directory\tstatements\tcovered\tcoverage\ncmd/server\t7\t5\t71.4\npkg/util\t14\t11\t78.6\nTOTAL\t21\t16\t76.2\n",
+		},
+		{
+			name:    "package header",
+			header:  true,
+			groupBy: GroupByPackage,
+			want:    "package\tstatements\tcovered\tcoverage\ncmd/server\t7\t5\t71.4\npkg/util\t14\t11\t78.6\nTOTAL\t21\t16\t76.2\n",
+		},
+		{
+			name:          "filtered total",
+			filteredTotal: filteredTotal,
+			want:          "cmd/server\t7\t5\t71.4\npkg/util\t14\t11\t78.6\nFILTERED TOTAL\t7\t5\t71.4\nTOTAL\t21\t16\t76.2\n",
+		},
+		{
+			name:  "quiet",
+			quiet: true,
+			want:  "TOTAL\t21\t16\t76.2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			formatter := NewTSVFormatter(&buf, tt.quiet)
+			formatter.SetHeader(tt.header)
+			formatter.SetGroupBy(tt.groupBy)
+			if err := formatter.Format(results, totalResult, tt.filteredTotal); err != nil {
+				t.Fatalf("TSVFormatter failed: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("TSVFormatter output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableFormatterWidth(t *testing.T) {
+	results := []CoverageResult{
+		{Directory: "github.com/example/project/internal/service", Statements: 7, Covered: 6, Coverage: 85.7},
+		{Directory: "pkg/util", Statements: 7, Covered: 5, Coverage: 71.4},
+	}
+	totalResult := CoverageResult{Directory: "TOTAL", Statements: 14, Covered: 11, Coverage: 78.6}
+
+	t.Run("small width truncates", func(t *testing.T) {
+		var buf bytes.Buffer
+		formatter := NewTableFormatter(&buf, false)
+		formatter.SetWidth(20)
+		if err := formatter.Format(results, totalResult, nil); err != nil {
+			t.Fatalf("TableFormatter failed: %v", err)
+		}
+
+		lines := strings.Split(buf.String(), "\n")
+		want := []string{
+			"Directory            Statements    Covered Coverage",
+			strings.Repeat("-", 50),
+			"github.com/exampl...          7          6    85.7%",
+			"pkg/util                      7          5    71.4%",
+		}
+		for i, line := range want {
+			if lines[i] != line {
+				t.Errorf("line %d = %q, want %q", i, lines[i], line)
+			}
+		}
+	})
+
+	t.Run("default keeps long names", func(t *testing.T) {
+		long := []CoverageResult{{Directory: strings.Repeat("a/", 30) + "pkg", Statements: 1, Covered: 1, Coverage: 100}}
+		var buf bytes.Buffer
+		if err := NewTableFormatter(&buf, false).Format(long, totalResult, nil); err != nil {
+			t.Fatalf("TableFormatter failed: %v", err)
+		}
+		if !strings.Contains(buf.String(), long[0].Directory+" ") {
+			t.Errorf("Default width should not truncate\nGot: %s", buf.String())
+		}
+	})
+
+	t.Run("below minimum", func(t *testing.T) {
+		var buf bytes.Buffer
+		formatter := NewTableFormatter(&buf, true)
+		formatter.SetWidth(2)
+		if err := formatter.Format(results, totalResult, nil); err != nil {
+			t.Fatalf("TableFormatter failed: %v", err)
+		}
+		if got := buf.String(); got != "TOTAL              14         11    78.6%\n" {
+			t.Errorf("Width should be raised to MinColumnWidth, got %q", got)
+		}
+	})
+}
+
+func TestFormattersHideTotal(t *testing.T) {
+	results := []CoverageResult{{Directory: "pkg/util", Statements: 10, Covered: 8, Coverage: 80.0}}
+	totalResult := CoverageResult{Directory: "TOTAL", Statements: 10, Covered: 8, Coverage: 80.0}
+	filteredTotal := &CoverageResult{Directory: "FILTERED TOTAL", Statements: 10, Covered: 8, Coverage: 80.0}
+
+	for _, quiet := range []bool{false, true} {
+		var table, tsv, jsonBuf bytes.Buffer
+		tableFormatter := NewTableFormatter(&table, quiet)
+		tableFormatter.SetHideTotal(true)
+		tsvFormatter := NewTSVFormatter(&tsv, quiet)
+		tsvFormatter.SetHideTotal(true)
+		jsonFormatter := NewJSONFormatter(&jsonBuf, quiet)
+		jsonFormatter.SetHideTotal(true)
+		jsonFormatter.SetStats(Stats{Files: 1, Directories: 1})
+
+		for _, formatter := range []OutputFormatter{tableFormatter, tsvFormatter, jsonFormatter} {
+			if err := formatter.Format(results, totalResult, filteredTotal); err != nil {
+				t.Fatalf("%T failed: %v", formatter, err)
+			}
+		}
+
+		for name, output := range map[string]string{"table": table.String(), "tsv": tsv.String(), "json": jsonBuf.String()} {
+			if strings.Contains(output, "TOTAL") || strings.Contains(output, `"total"`) {
+				t.Errorf("quiet=%v: %s output should not contain totals\nGot: %s", quiet, name, output)
+			}
+		}
+		if !quiet && (!strings.Contains(table.String(), "pkg/util") || !strings.Contains(jsonBuf.String(), `"results"`)) {
+			t.Errorf("Results should still be written\nTable: %s\nJSON: %s", table.String(), jsonBuf.String())
+		}
+		if !strings.Contains(jsonBuf.String(), `"stats"`) {
+			t.Errorf("quiet=%v: other JSON fields should be kept\nGot: %s", quiet, jsonBuf.String())
+		}
+	}
+}
+
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		maxLen int
+		want   string
+	}{
+		{
+			name:   "short string",
+			input:  "hello",
+			maxLen: 10,
+			want:   "hello",
+		},
+		{
+			name:   "exact length",
+			input:  "hello",
+			maxLen: 5,
+			want:   "hello",
+		},
+		{
+			name:   "needs truncation",
+			input:  "this is a very long string",
+			maxLen: 10,
+			want:   "this is...",
+		},
+		{
+			name:   "very short max",
+			input:  "hello",
+			maxLen: 3,
+			want:   "...",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateString(tt.input, tt.maxLen)
+			if got != tt.want {
+				t.Errorf("TruncateString(%q, %d) = %q, want %q", tt.input, tt.maxLen, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoverageClass(t *testing.T) {
+	tests := []struct {
+		coverage float64
+		want     string
+	}{
+		{100.0, "high"},
+		{80.0, "high"},
+		{79.9, "medium"},
+		{50.0, "medium"},
+		{49.9, "low"},
+		{0.0, "low"},
+	}
+
+	for _, tt := range tests {
+		if got := coverageClass(tt.coverage); got != tt.want {
+			t.Errorf("coverageClass(%.1f) = %q, want %q", tt.coverage, got, tt.want)
+		}
+	}
+}
+
+func TestFormatterEdgeCases(t *testing.T) {
+	t.Run("display with edge case coverages", func(t *testing.T) {
+		results := []CoverageResult{
+			{
+				Directory:  "zero",
+				Statements: 0,
+				Covered:    0,
+				Coverage:   0.0,
+			},
+			{
+				Directory:  "perfect",
+				Statements: 100,
+				Covered:    100,
+				Coverage:   100.0,
+			},
+			{
+				Directory:  "none",
+				Statements: 50,
+				Covered:    0,
+				Coverage:   0.0,
+			},
+		}
+
+		totalResult := CoverageResult{
+			Directory:  "TOTAL",
+			Statements: 150,
+			Covered:    100,
+			Coverage:   66.7,
+		}
+
+		// Test with TableFormatter
+		var buf bytes.Buffer
+		formatter := &TableFormatter{writer: &buf}
+
+		err := formatter.Format(results, totalResult, nil)
+		if err != nil {
+			t.Fatalf("Format failed: %v", err)
+		}
+
+		output := buf.String()
+
+		// Verify edge cases are displayed correctly
+		if !strings.Contains(output, "0.0%") {
+			t.Error("Output should contain 0.0% coverage")
+		}
+		if !strings.Contains(output, "100.0%") {
+			t.Error("Output should contain 100.0% coverage")
+		}
+	})
+}
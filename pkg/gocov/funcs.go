@@ -0,0 +1,128 @@
+package gocov
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+
+	"golang.org/x/tools/cover"
+)
+
+// UncoveredFunc is a function none of whose statements ran
+type UncoveredFunc struct {
+	// File is the file name as written in the profile
+	File string `json:"file"`
+	Line int    `json:"line"`
+	// Name is the function name, with the receiver for methods, e.g. "(*Server).Start"
+	Name       string `json:"name"`
+	Statements int    `json:"statements"`
+}
+
+// funcExtent is the line range of a function declaration
+type funcExtent struct {
+	name               string
+	startLine, endLine int
+}
+
+// UncoveredFuncs lists the functions whose coverage blocks all have a zero count, sorted by file and line
+// Functions are located by parsing the profiled sources, mapped to disk through the module root
+// (see SetModuleRoot) or the working directory without one. Ignored, excluded, and unreadable files are skipped,
+// and functions without statements are never reported
+func (a *CoverageAnalyzer) UncoveredFuncs(profiles []*cover.Profile) []UncoveredFunc {
+	root := newModuleRoot("", "")
+	if a.root != nil {
+		root = *a.root
+	}
+
+	var uncovered []UncoveredFunc
+	for _, profile := range profiles {
+		if a.shouldSkipProfile(profile) || a.shouldSkipDirectory(a.GroupKey(profile.FileName)) {
+			continue
+		}
+		funcs, err := parseFuncExtents(root.sourcePath(profile.FileName))
+		if err != nil {
+			// Fail soft: without the source there are no functions to report
+			continue
+		}
+
+		blocks := normalizeBlocks(profile.Blocks)
+		for _, fn := range funcs {
+			statements, covered := 0, false
+			for _, block := range blocks {
+				if block.StartLine < fn.startLine || block.EndLine > fn.endLine {
+					continue
+				}
+				statements += block.NumStmt
+				covered = covered || block.Count > 0
+			}
+			if statements > 0 && !covered {
+				uncovered = append(uncovered, UncoveredFunc{
+					File:       profile.FileName,
+					Line:       fn.startLine,
+					Name:       fn.name,
+					Statements: statements,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(uncovered, func(i, j int) bool {
+		if uncovered[i].File != uncovered[j].File {
+			return uncovered[i].File < uncovered[j].File
+		}
+		return uncovered[i].Line < uncovered[j].Line
+	})
+	return uncovered
+}
+
+// parseFuncExtents returns the name and line range of every function with a body in the Go file at path
+func parseFuncExtents(path string) ([]funcExtent, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	var funcs []funcExtent
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		funcs = append(funcs, funcExtent{
+			name:      funcName(fn),
+			startLine: fset.Position(fn.Pos()).Line,
+			endLine:   fset.Position(fn.End()).Line,
+		})
+	}
+	return funcs, nil
+}
+
+// funcName returns the name of fn, prefixed with its receiver type for methods
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	recv := fn.Recv.List[0].Type
+	pointer := false
+	if star, ok := recv.(*ast.StarExpr); ok {
+		pointer = true
+		recv = star.X
+	}
+	// Drop type parameters of generic receivers
+	switch expr := recv.(type) {
+	case *ast.IndexExpr:
+		recv = expr.X
+	case *ast.IndexListExpr:
+		recv = expr.X
+	}
+	name := "?"
+	if ident, ok := recv.(*ast.Ident); ok {
+		name = ident.Name
+	}
+	if pointer {
+		return "(*" + name + ")." + fn.Name.Name
+	}
+	return name + "." + fn.Name.Name
+}
@@ -0,0 +1,65 @@
+package gocov
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestUncoveredFuncs(t *testing.T) {
+	tmpDir := t.TempDir()
+	source := `package server
+
+type Server struct{}
+
+func (s *Server) Start() {
+	s.listen()
+}
+
+func (s Server) listen() {
+	_ = 1
+}
+
+func helper() int {
+	return 1
+}
+
+func empty() {}
+`
+	if err := os.MkdirAll(filepath.Join(tmpDir, "server"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "server", "server.go"), []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+
+	profiles := []*cover.Profile{
+		{FileName: "example.com/m/server/server.go", Blocks: []cover.ProfileBlock{
+			{StartLine: 5, StartCol: 26, EndLine: 7, EndCol: 2, NumStmt: 1, Count: 0},
+			{StartLine: 9, StartCol: 26, EndLine: 11, EndCol: 2, NumStmt: 1, Count: 3},
+			{StartLine: 13, StartCol: 20, EndLine: 15, EndCol: 2, NumStmt: 1, Count: 0},
+		}},
+		// Not on disk, so nothing can be reported
+		{FileName: "example.com/m/missing/a.go", Blocks: []cover.ProfileBlock{{StartLine: 1, EndLine: 2, NumStmt: 1}}},
+	}
+
+	analyzer := NewCoverageAnalyzer(0, nil)
+	analyzer.SetModuleRoot("example.com/m", tmpDir)
+	got := analyzer.UncoveredFuncs(profiles)
+	want := []UncoveredFunc{
+		{File: "example.com/m/server/server.go", Line: 5, Name: "(*Server).Start", Statements: 1},
+		{File: "example.com/m/server/server.go", Line: 13, Name: "helper", Statements: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UncoveredFuncs() = %+v, want %+v", got, want)
+	}
+
+	ignoring := NewCoverageAnalyzer(0, []string{"server"})
+	ignoring.SetModuleRoot("example.com/m", tmpDir)
+	if got := ignoring.UncoveredFuncs(profiles); len(got) != 0 {
+		t.Errorf("Ignored directories should be skipped, got %+v", got)
+	}
+}
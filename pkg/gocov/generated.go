@@ -0,0 +1,68 @@
+package gocov
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// generatedMarker is the standard marker for generated Go files (see "go help generate")
+var generatedMarker = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// generatedHeaderLines is how many leading lines are scanned for generatedMarker
+const generatedHeaderLines = 5
+
+// generatedDetector decides whether profiled files are generated, caching the result per file
+type generatedDetector struct {
+	root moduleRoot
+
+	mu    sync.Mutex
+	cache map[string]bool
+}
+
+// SetIgnoreGenerated skips profiles for files carrying the "Code generated ... DO NOT EDIT." marker
+// Profile file names are mapped to disk by replacing modulePath with dir;
+// files that cannot be read are kept
+func (a *CoverageAnalyzer) SetIgnoreGenerated(modulePath, dir string) {
+	a.generated = &generatedDetector{
+		root:  newModuleRoot(modulePath, dir),
+		cache: make(map[string]bool),
+	}
+}
+
+// isGenerated reports whether fileName is a generated file, reading its header at most once
+func (d *generatedDetector) isGenerated(fileName string) bool {
+	d.mu.Lock()
+	generated, ok := d.cache[fileName]
+	d.mu.Unlock()
+	if ok {
+		return generated
+	}
+
+	generated = hasGeneratedMarker(d.root.sourcePath(fileName))
+
+	d.mu.Lock()
+	d.cache[fileName] = generated
+	d.mu.Unlock()
+	return generated
+}
+
+// hasGeneratedMarker reports whether one of the first lines of path is the generated marker
+func hasGeneratedMarker(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		// Fail soft: a missing source keeps the file in the report
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for i := 0; i < generatedHeaderLines && scanner.Scan(); i++ {
+		if generatedMarker.MatchString(strings.TrimRight(scanner.Text(), "\r")) {
+			return true
+		}
+	}
+	return false
+}
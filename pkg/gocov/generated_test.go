@@ -0,0 +1,86 @@
+package gocov
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestHasGeneratedMarker(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"protobuf", "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage pb\n", true},
+		{"after build tag", "//go:build linux\n\n// Code generated by mockgen. DO NOT EDIT.\npackage mock\n", true},
+		{"crlf", "// Code generated by stringer. DO NOT EDIT.\r\npackage main\r\n", true},
+		{"hand written", "// Package main is not generated.\npackage main\n", false},
+		{"marker too late", "package main\n\n\n\n\n\n// Code generated by hand. DO NOT EDIT.\n", false},
+		{"missing period", "// Code generated by tool. DO NOT EDIT\npackage main\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tmpDir, tt.name+".go")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write source file: %v", err)
+			}
+			if got := hasGeneratedMarker(path); got != tt.want {
+				t.Errorf("hasGeneratedMarker() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if hasGeneratedMarker(filepath.Join(tmpDir, "missing.go")) {
+		t.Error("hasGeneratedMarker() should keep files that cannot be read")
+	}
+}
+
+func TestAggregateIgnoreGenerated(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "pb"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "pb", "api.pb.go"), []byte("// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage pb\n"), 0644); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "pb", "helper.go"), []byte("package pb\n"), 0644); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+
+	profiles := []*cover.Profile{
+		{FileName: "example.com/m/pb/api.pb.go", Blocks: []cover.ProfileBlock{{NumStmt: 10, Count: 0}}},
+		{FileName: "example.com/m/pb/helper.go", Blocks: []cover.ProfileBlock{{NumStmt: 2, Count: 1}}},
+		// Not on disk, so it is kept
+		{FileName: "example.com/m/pb/missing.go", Blocks: []cover.ProfileBlock{{NumStmt: 2, Count: 0}}},
+	}
+
+	analyzer := NewCoverageAnalyzer(0, nil)
+	analyzer.SetIgnoreGenerated("example.com/m", tmpDir)
+
+	for name, aggregate := range map[string]func([]*cover.Profile) map[string]*DirCoverage{
+		"sequential": analyzer.Aggregate,
+		"concurrent": analyzer.AggregateConcurrent,
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := aggregate(profiles)
+			cov := got["example.com/m/pb"]
+			if cov == nil {
+				t.Fatalf("Expected coverage for example.com/m/pb, got %v", got)
+			}
+			if cov.StmtCount != 4 || cov.StmtCovered != 2 || cov.FileCount != 2 {
+				t.Errorf("Generated file should be excluded, got %+v", cov)
+			}
+		})
+	}
+
+	// Decisions are cached per file
+	if len(analyzer.generated.cache) != 3 {
+		t.Errorf("Expected 3 cached decisions, got %d", len(analyzer.generated.cache))
+	}
+}
@@ -0,0 +1,75 @@
+package gocov
+
+import (
+	"sort"
+
+	"golang.org/x/tools/cover"
+)
+
+// blockPosition identifies a block by its source range
+type blockPosition struct {
+	startLine, startCol, endLine, endCol int
+}
+
+// MergeProfiles combines profiles for the same file, e.g. when several coverage files are read at once
+// Blocks at the same position are merged into one: in set mode a block is covered if any profile covered it,
+// in count and atomic mode the execution counts are added up.
+// The result is sorted by file name and the inputs are not modified
+func MergeProfiles(profiles []*cover.Profile) []*cover.Profile {
+	byFile := make(map[string]*cover.Profile, len(profiles))
+	merged := make([]*cover.Profile, 0, len(profiles))
+	for _, profile := range profiles {
+		if profile == nil {
+			continue
+		}
+		existing, ok := byFile[profile.FileName]
+		if !ok {
+			existing = &cover.Profile{
+				FileName: profile.FileName,
+				Mode:     profile.Mode,
+				Blocks:   append([]cover.ProfileBlock(nil), profile.Blocks...),
+			}
+			byFile[profile.FileName] = existing
+			merged = append(merged, existing)
+			continue
+		}
+		existing.Blocks = mergeBlocks(existing.Blocks, profile.Blocks, existing.Mode)
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].FileName < merged[j].FileName
+	})
+	return merged
+}
+
+// mergeBlocks adds src to dst, combining blocks at the same position, and returns them in source order
+func mergeBlocks(dst, src []cover.ProfileBlock, mode string) []cover.ProfileBlock {
+	index := make(map[blockPosition]int, len(dst))
+	for i, block := range dst {
+		index[positionOf(block)] = i
+	}
+
+	for _, block := range src {
+		i, ok := index[positionOf(block)]
+		if !ok {
+			index[positionOf(block)] = len(dst)
+			dst = append(dst, block)
+			continue
+		}
+		if mode == "set" {
+			dst[i].Count = max(dst[i].Count, block.Count)
+		} else {
+			dst[i].Count += block.Count
+		}
+	}
+
+	sort.SliceStable(dst, func(i, j int) bool {
+		return blockStartsBefore(dst[i], dst[j])
+	})
+	return dst
+}
+
+// positionOf returns the source range of block
+func positionOf(block cover.ProfileBlock) blockPosition {
+	return blockPosition{block.StartLine, block.StartCol, block.EndLine, block.EndCol}
+}
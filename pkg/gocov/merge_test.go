@@ -0,0 +1,70 @@
+package gocov
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestMergeProfiles(t *testing.T) {
+	block := func(start, count int) cover.ProfileBlock {
+		return cover.ProfileBlock{StartLine: start, StartCol: 1, EndLine: start + 1, EndCol: 1, NumStmt: 1, Count: count}
+	}
+
+	tests := []struct {
+		name     string
+		profiles []*cover.Profile
+		want     []*cover.Profile
+	}{
+		{
+			name: "different files are kept and sorted",
+			profiles: []*cover.Profile{
+				{FileName: "b.go", Mode: "set", Blocks: []cover.ProfileBlock{block(1, 1)}},
+				{FileName: "a.go", Mode: "set", Blocks: []cover.ProfileBlock{block(1, 0)}},
+			},
+			want: []*cover.Profile{
+				{FileName: "a.go", Mode: "set", Blocks: []cover.ProfileBlock{block(1, 0)}},
+				{FileName: "b.go", Mode: "set", Blocks: []cover.ProfileBlock{block(1, 1)}},
+			},
+		},
+		{
+			name: "set mode keeps any coverage",
+			profiles: []*cover.Profile{
+				{FileName: "a.go", Mode: "set", Blocks: []cover.ProfileBlock{block(1, 0), block(5, 1)}},
+				{FileName: "a.go", Mode: "set", Blocks: []cover.ProfileBlock{block(1, 1), block(5, 1), block(3, 0)}},
+			},
+			want: []*cover.Profile{
+				{FileName: "a.go", Mode: "set", Blocks: []cover.ProfileBlock{block(1, 1), block(3, 0), block(5, 1)}},
+			},
+		},
+		{
+			name: "count mode adds executions",
+			profiles: []*cover.Profile{
+				{FileName: "a.go", Mode: "count", Blocks: []cover.ProfileBlock{block(1, 2)}},
+				{FileName: "a.go", Mode: "count", Blocks: []cover.ProfileBlock{block(1, 3)}},
+			},
+			want: []*cover.Profile{
+				{FileName: "a.go", Mode: "count", Blocks: []cover.ProfileBlock{block(1, 5)}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeProfiles(tt.profiles)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeProfiles() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("inputs are not modified", func(t *testing.T) {
+		first := &cover.Profile{FileName: "a.go", Mode: "count", Blocks: []cover.ProfileBlock{block(1, 2)}}
+		second := &cover.Profile{FileName: "a.go", Mode: "count", Blocks: []cover.ProfileBlock{block(1, 3)}}
+		MergeProfiles([]*cover.Profile{first, second})
+		if first.Blocks[0].Count != 2 || second.Blocks[0].Count != 3 {
+			t.Errorf("MergeProfiles() modified its inputs: %+v, %+v", first, second)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package gocov
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// NormalizeFilePath normalizes profile and diff file paths for comparison
+// Relative paths lose a leading "./"; module paths and absolute paths are kept as is
+func NormalizeFilePath(path string) string {
+	return strings.TrimPrefix(path, "./")
+}
+
+// moduleRoot maps between module-relative names (as written by go test) and paths on disk
+type moduleRoot struct {
+	modulePath string
+	dir        string
+}
+
+// newModuleRoot creates a moduleRoot for the module at dir
+func newModuleRoot(modulePath, dir string) moduleRoot {
+	return moduleRoot{
+		modulePath: strings.TrimSuffix(modulePath, "/"),
+		dir:        dir,
+	}
+}
+
+// sourcePath maps a profile file name to a path on disk
+func (r moduleRoot) sourcePath(fileName string) string {
+	if filepath.IsAbs(fileName) {
+		return fileName
+	}
+	if r.modulePath != "" {
+		if fileName == r.modulePath {
+			fileName = "."
+		} else {
+			fileName = strings.TrimPrefix(fileName, r.modulePath+"/")
+		}
+	}
+	return filepath.Join(r.dir, filepath.FromSlash(fileName))
+}
+
+// modulePathOf maps an absolute path inside the module directory back to its module path
+// ok is false when path is outside the module directory
+func (r moduleRoot) modulePathOf(path string) (string, bool) {
+	rel, err := filepath.Rel(r.dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	switch {
+	case r.modulePath == "":
+		return rel, true
+	case rel == ".":
+		return r.modulePath, true
+	default:
+		return r.modulePath + "/" + rel, true
+	}
+}
+
+// forms returns the spellings of dir that patterns are matched against
+// An absolute directory inside the module is also tried as its module path.
+// Module paths are not expanded to absolute paths, because the checkout location
+// would add segments such as "home" that patterns could match by accident
+func (r moduleRoot) forms(dir string) []string {
+	dir = NormalizeFilePath(dir)
+	if filepath.IsAbs(dir) {
+		if modulePath, ok := r.modulePathOf(dir); ok {
+			return []string{dir, modulePath}
+		}
+	}
+	return []string{dir}
+}
@@ -0,0 +1,114 @@
+package gocov
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestNormalizeFilePath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{
+			name: "relative path",
+			path: "main.go",
+			want: "main.go",
+		},
+		{
+			name: "relative with dot",
+			path: "./main.go",
+			want: "main.go",
+		},
+		{
+			name: "absolute path",
+			path: "/home/user/project/main.go",
+			want: "/home/user/project/main.go",
+		},
+		{
+			name: "nested relative",
+			path: "internal/service/user.go",
+			want: "internal/service/user.go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeFilePath(tt.path)
+			if got != tt.want {
+				t.Errorf("NormalizeFilePath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModuleRootForms(t *testing.T) {
+	root := newModuleRoot("github.com/example/project", "/home/runner/work/project")
+
+	tests := []struct {
+		name string
+		dir  string
+		want []string
+	}{
+		{"module path", "github.com/example/project/pkg/util", []string{"github.com/example/project/pkg/util"}},
+		{"absolute in module", "/home/runner/work/project/pkg/util", []string{"/home/runner/work/project/pkg/util", "github.com/example/project/pkg/util"}},
+		{"absolute module dir", "/home/runner/work/project", []string{"/home/runner/work/project", "github.com/example/project"}},
+		{"absolute outside module", "/home/runner/work/other/pkg", []string{"/home/runner/work/other/pkg"}},
+		{"relative with dot", "./pkg/util", []string{"pkg/util"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := root.forms(filepath.FromSlash(tt.dir))
+			for i := range got {
+				got[i] = filepath.ToSlash(got[i])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("forms(%q) = %v, want %v", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregateAbsolutePaths(t *testing.T) {
+	profiles := []*cover.Profile{
+		{FileName: "/home/runner/work/project/internal/db/db.go", Blocks: []cover.ProfileBlock{{NumStmt: 2, Count: 1}}},
+		{FileName: "/home/runner/work/project/pkg/util/util.go", Blocks: []cover.ProfileBlock{{NumStmt: 2, Count: 1}}},
+		{FileName: "github.com/example/project/cmd/app/main.go", Blocks: []cover.ProfileBlock{{NumStmt: 2, Count: 1}}},
+	}
+
+	tests := []struct {
+		name    string
+		ignore  []string
+		include []string
+		want    []string
+	}{
+		{"module pattern ignores absolute dir", []string{"github.com/example/project/internal/*"}, nil, []string{"/home/runner/work/project/pkg/util", "github.com/example/project/cmd/app"}},
+		{"absolute pattern ignores absolute dir", []string{"/home/runner/work/project/pkg/*"}, nil, []string{"/home/runner/work/project/internal/db", "github.com/example/project/cmd/app"}},
+		{"module pattern ignores module dir", []string{"*/cmd/*"}, nil, []string{"/home/runner/work/project/internal/db", "/home/runner/work/project/pkg/util"}},
+		{"module pattern includes absolute dir", nil, []string{"github.com/example/project/pkg/*"}, []string{"/home/runner/work/project/pkg/util"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := NewCoverageAnalyzer(0, tt.ignore)
+			analyzer.SetIncludePatterns(tt.include)
+			analyzer.SetModuleRoot("github.com/example/project", "/home/runner/work/project")
+
+			got := FilterDirectories(analyzer.Aggregate(profiles), 0, 100, 0)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Aggregate() directories = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Without a module root, module path patterns cannot match absolute directories
+	analyzer := NewCoverageAnalyzer(0, []string{"github.com/example/project/internal/*"})
+	if got := analyzer.Aggregate(profiles); got["/home/runner/work/project/internal/db"] == nil {
+		t.Error("Expected absolute directory to be kept without a module root")
+	}
+}
@@ -0,0 +1,92 @@
+package gocov
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+// utf8BOM is the byte order mark some Windows tools write at the start of a file
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// profileReader strips a leading UTF-8 BOM and turns CRLF line endings into LF
+type profileReader struct {
+	r          *bufio.Reader
+	bomChecked bool
+}
+
+// NewProfileReader returns a reader of r with a leading UTF-8 BOM removed and CRLF line endings
+// normalized to LF, so profiles written on Windows parse like those written by go test elsewhere.
+// Lone carriage returns are kept
+func NewProfileReader(r io.Reader) io.Reader {
+	return &profileReader{r: bufio.NewReader(r)}
+}
+
+// Read implements io.Reader
+func (p *profileReader) Read(b []byte) (int, error) {
+	if !p.bomChecked {
+		p.bomChecked = true
+		if prefix, err := p.r.Peek(len(utf8BOM)); err == nil && string(prefix) == string(utf8BOM) {
+			p.r.Discard(len(utf8BOM))
+		}
+	}
+
+	n := 0
+	for n < len(b) {
+		c, err := p.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		if c == '\r' {
+			if next, err := p.r.Peek(1); err == nil && next[0] == '\n' {
+				continue
+			}
+		}
+		b[n] = c
+		n++
+		// Return buffered bytes rather than blocking on a slow source
+		if p.r.Buffered() == 0 {
+			break
+		}
+	}
+	return n, nil
+}
+
+// modePrefix starts the header line of every coverage profile
+const modePrefix = "mode:"
+
+// AssumeMode returns a reader of r that starts with a "mode: <mode>" header when r has none,
+// so profiles from tools that leave out the header can still be parsed. A present header is kept
+// even if mode differs, and an empty r stays empty. r should already be normalized by NewProfileReader
+func AssumeMode(r io.Reader, mode string) io.Reader {
+	br := bufio.NewReader(r)
+	prefix, _ := br.Peek(len(modePrefix))
+	if len(prefix) == 0 || bytes.Equal(prefix, []byte(modePrefix)) {
+		return br
+	}
+	return io.MultiReader(strings.NewReader(modePrefix+" "+mode+"\n"), br)
+}
+
+// ParseProfilesFromReader parses a coverage profile like cover.ParseProfilesFromReader,
+// additionally accepting a UTF-8 BOM and CRLF line endings
+func ParseProfilesFromReader(r io.Reader) ([]*cover.Profile, error) {
+	return cover.ParseProfilesFromReader(NewProfileReader(r))
+}
+
+// ParseProfiles parses the coverage profile at path like cover.ParseProfiles,
+// additionally accepting a UTF-8 BOM and CRLF line endings
+func ParseProfiles(path string) ([]*cover.Profile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseProfilesFromReader(file)
+}
@@ -0,0 +1,133 @@
+package gocov
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestNewProfileReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"unchanged", "mode: set\na.go:1.1,2.1 1 1\n", "mode: set\na.go:1.1,2.1 1 1\n"},
+		{"bom", "\ufeffmode: set\n", "mode: set\n"},
+		{"crlf", "mode: set\r\na.go:1.1,2.1 1 1\r\n", "mode: set\na.go:1.1,2.1 1 1\n"},
+		{"lone carriage return is kept", "a\rb\r", "a\rb\r"},
+		{"bom only at the start", "mode: set\n\ufeff", "mode: set\n\ufeff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// One byte reads exercise CRLF pairs split across reads
+			got, err := io.ReadAll(NewProfileReader(&oneByteReader{r: strings.NewReader(tt.input)}))
+			if err != nil {
+				t.Fatalf("ReadAll failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("NewProfileReader(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// oneByteReader returns at most one byte per Read
+type oneByteReader struct {
+	r io.Reader
+}
+
+func (o *oneByteReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	return o.r.Read(p[:1])
+}
+
+func TestParseProfilesBOMAndCRLF(t *testing.T) {
+	want, err := cover.ParseProfiles("../../testdata/coverage.out")
+	if err != nil {
+		t.Fatalf("Failed to parse profile: %v", err)
+	}
+	data, err := os.ReadFile("../../testdata/coverage.out")
+	if err != nil {
+		t.Fatalf("Failed to read profile: %v", err)
+	}
+	windows := "\ufeff" + strings.ReplaceAll(string(data), "\n", "\r\n")
+
+	t.Run("reader", func(t *testing.T) {
+		got, err := ParseProfilesFromReader(strings.NewReader(windows))
+		if err != nil {
+			t.Fatalf("ParseProfilesFromReader failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseProfilesFromReader() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "windows.out")
+		if err := os.WriteFile(path, []byte(windows), 0644); err != nil {
+			t.Fatalf("Failed to write profile: %v", err)
+		}
+		got, err := ParseProfiles(path)
+		if err != nil {
+			t.Fatalf("ParseProfiles failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseProfiles() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		analyzer := NewCoverageAnalyzer(0, nil)
+		got, mode, err := analyzer.AggregateReader(strings.NewReader(windows))
+		if err != nil {
+			t.Fatalf("AggregateReader failed: %v", err)
+		}
+		if mode != "set" {
+			t.Errorf("mode = %q, want set", mode)
+		}
+		if wantCoverage := analyzer.Aggregate(want); !reflect.DeepEqual(got, wantCoverage) {
+			t.Errorf("AggregateReader() = %v, want %v", got, wantCoverage)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := ParseProfiles(filepath.Join(t.TempDir(), "missing.out")); !os.IsNotExist(err) {
+			t.Errorf("ParseProfiles() error = %v, want not-exist error", err)
+		}
+	})
+}
+
+func TestAssumeMode(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"missing header", "a.go:1.1,2.1 1 1\n", "mode: count\na.go:1.1,2.1 1 1\n"},
+		{"header kept", "mode: set\na.go:1.1,2.1 1 1\n", "mode: set\na.go:1.1,2.1 1 1\n"},
+		{"bad header kept", "mode: \n", "mode: \n"},
+		{"short first line", "a\n", "mode: count\na\n"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := io.ReadAll(AssumeMode(strings.NewReader(tt.input), "count"))
+			if err != nil {
+				t.Fatalf("ReadAll failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("AssumeMode(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
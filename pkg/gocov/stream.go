@@ -0,0 +1,157 @@
+package gocov
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"golang.org/x/tools/cover"
+)
+
+// ErrUngroupedProfile is returned by AggregateReader when a file's blocks are split across the profile
+var ErrUngroupedProfile = errors.New("blocks of a file are not contiguous in the profile")
+
+// AggregateReader aggregates the coverage profile read from r one line at a time
+// Unlike Aggregate over cover.ParseProfiles, only the blocks of the file being read are held in memory,
+// which keeps memory flat on very large profiles. The result matches Aggregate as long as each file's
+// blocks are contiguous, which is how go test writes profiles; otherwise ErrUngroupedProfile is returned.
+// The profile mode is returned alongside, and is empty for an empty profile.
+// Like ParseProfiles, a UTF-8 BOM and CRLF line endings are accepted
+func (a *CoverageAnalyzer) AggregateReader(r io.Reader) (map[string]*DirCoverage, string, error) {
+	coverageByDir := make(map[string]*DirCoverage)
+	seen := make(map[string]bool)
+	mode := ""
+	var current *cover.Profile
+	// buf is reused for the blocks of each file, as addProfile keeps none of them
+	var buf []cover.ProfileBlock
+
+	// flush aggregates the current file like ParseProfiles followed by Aggregate would
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		blocks, err := mergeSameBlocks(current.Blocks, mode)
+		if err != nil {
+			return err
+		}
+		current.Blocks = blocks
+		a.addProfile(coverageByDir, current)
+		buf = blocks[:0]
+		current = nil
+		return nil
+	}
+
+	scanner := bufio.NewScanner(NewProfileReader(r))
+	for scanner.Scan() {
+		// Lines are parsed from the scanner's buffer; only file names are copied into strings
+		line := scanner.Bytes()
+		if mode == "" {
+			const prefix = "mode: "
+			if !bytes.HasPrefix(line, []byte(prefix)) || len(line) == len(prefix) {
+				return nil, "", fmt.Errorf("bad mode line: %s", line)
+			}
+			mode = string(line[len(prefix):])
+			continue
+		}
+
+		name, block, err := parseProfileLine(line)
+		if err != nil {
+			return nil, "", fmt.Errorf("line %q doesn't match expected format: %v", line, err)
+		}
+		if current == nil || current.FileName != string(name) {
+			fileName := string(name)
+			if err := flush(); err != nil {
+				return nil, "", err
+			}
+			if seen[fileName] {
+				return nil, "", fmt.Errorf("%w: %s", ErrUngroupedProfile, fileName)
+			}
+			seen[fileName] = true
+			current = &cover.Profile{FileName: fileName, Mode: mode, Blocks: buf}
+		}
+		current.Blocks = append(current.Blocks, block)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", err
+	}
+	if err := flush(); err != nil {
+		return nil, "", err
+	}
+	return coverageByDir, mode, nil
+}
+
+// mergeSameBlocks sorts blocks by start and combines samples of the same block, as cover.ParseProfiles does
+func mergeSameBlocks(blocks []cover.ProfileBlock, mode string) ([]cover.ProfileBlock, error) {
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blockStartsBefore(blocks[i], blocks[j])
+	})
+	if len(blocks) == 0 {
+		return blocks, nil
+	}
+	j := 1
+	for _, block := range blocks[1:] {
+		last := &blocks[j-1]
+		if positionOf(block) != positionOf(*last) {
+			blocks[j] = block
+			j++
+			continue
+		}
+		if block.NumStmt != last.NumStmt {
+			return nil, fmt.Errorf("inconsistent NumStmt: changed from %d to %d", last.NumStmt, block.NumStmt)
+		}
+		if mode == "set" {
+			last.Count |= block.Count
+		} else {
+			last.Count += block.Count
+		}
+	}
+	return blocks[:j], nil
+}
+
+// parseProfileLine parses a "name.go:line.column,line.column numberOfStatements count" profile line
+func parseProfileLine(line []byte) ([]byte, cover.ProfileBlock, error) {
+	// Fields are read from the end of the line, as the file name may itself contain separators
+	const separators = "  .,.:"
+	names := [...]string{"Count", "NumStmt", "EndCol", "EndLine", "StartCol", "StartLine"}
+	var values [len(names)]int
+	end := len(line)
+	for i, name := range names {
+		start := bytes.LastIndexByte(line[:end], separators[i])
+		if start < 0 {
+			return nil, cover.ProfileBlock{}, fmt.Errorf("couldn't find a %c before %s", separators[i], name)
+		}
+		value, ok := parseCount(line[start+1 : end])
+		if !ok {
+			return nil, cover.ProfileBlock{}, fmt.Errorf("couldn't parse %q: %q is not a non-negative integer", name, line[start+1:end])
+		}
+		values[i] = value
+		end = start
+	}
+	if end == 0 {
+		return nil, cover.ProfileBlock{}, errors.New("a FileName cannot be blank")
+	}
+	return line[:end], cover.ProfileBlock{
+		StartLine: values[5], StartCol: values[4],
+		EndLine: values[3], EndCol: values[2],
+		NumStmt: values[1], Count: values[0],
+	}, nil
+}
+
+// parseCount parses a non-negative decimal integer without allocating
+func parseCount(digits []byte) (int, bool) {
+	if len(digits) == 0 {
+		return 0, false
+	}
+	value := 0
+	for _, digit := range digits {
+		if digit < '0' || digit > '9' || value > (math.MaxInt-9)/10 {
+			return 0, false
+		}
+		value = value*10 + int(digit-'0')
+	}
+	return value, true
+}
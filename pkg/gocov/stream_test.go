@@ -0,0 +1,172 @@
+package gocov
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestAggregateReader(t *testing.T) {
+	profiles, err := cover.ParseProfiles("../../testdata/coverage.out")
+	if err != nil {
+		t.Fatalf("Failed to parse profile: %v", err)
+	}
+
+	for _, level := range []int{0, 1, 4, -1} {
+		t.Run(fmt.Sprintf("level %d", level), func(t *testing.T) {
+			file, err := os.Open("../../testdata/coverage.out")
+			if err != nil {
+				t.Fatalf("Failed to open profile: %v", err)
+			}
+			defer file.Close()
+
+			analyzer := NewCoverageAnalyzer(level, []string{"*/cmd/*"})
+			got, mode, err := analyzer.AggregateReader(file)
+			if err != nil {
+				t.Fatalf("AggregateReader failed: %v", err)
+			}
+			if mode != "set" {
+				t.Errorf("mode = %q, want set", mode)
+			}
+			if want := analyzer.Aggregate(profiles); !reflect.DeepEqual(got, want) {
+				t.Errorf("AggregateReader() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestAggregateReaderMergesSameBlocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		covered int
+		hits    int
+	}{
+		{"set", "set", 2, 2},
+		{"count", "count", 2, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "mode: " + tt.mode + "\n" +
+				"pkg/a/a.go:3.1,4.2 2 1\n" +
+				"pkg/a/a.go:1.1,2.2 1 0\n" +
+				"pkg/a/a.go:3.1,4.2 2 1\n"
+			got, _, err := NewCoverageAnalyzer(0, nil).AggregateReader(strings.NewReader(input))
+			if err != nil {
+				t.Fatalf("AggregateReader failed: %v", err)
+			}
+
+			profiles, err := cover.ParseProfilesFromReader(strings.NewReader(input))
+			if err != nil {
+				t.Fatalf("Failed to parse profile: %v", err)
+			}
+			if want := NewCoverageAnalyzer(0, nil).Aggregate(profiles); !reflect.DeepEqual(got, want) {
+				t.Errorf("AggregateReader() = %v, want %v", got, want)
+			}
+			if cov := got["pkg/a"]; cov.StmtCount != 3 || cov.StmtCovered != tt.covered || cov.TotalHits != tt.hits {
+				t.Errorf("pkg/a = %+v, want 3 statements, %d covered, %d hits", cov, tt.covered, tt.hits)
+			}
+		})
+	}
+}
+
+func TestAggregateReaderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{"bad mode line", "pkg/a/a.go:1.1,2.2 1 1\n", nil},
+		{"bad block line", "mode: set\npkg/a/a.go:1.1,2.2 1\n", nil},
+		{"blank file name", "mode: set\n:1.1,2.2 1 1\n", nil},
+		{"inconsistent statements", "mode: set\npkg/a/a.go:1.1,2.2 1 1\npkg/a/a.go:1.1,2.2 2 1\n", nil},
+		{"ungrouped", "mode: set\npkg/a/a.go:1.1,2.2 1 1\npkg/b/b.go:1.1,2.2 1 1\npkg/a/a.go:3.1,4.2 1 1\n", ErrUngroupedProfile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := NewCoverageAnalyzer(0, nil).AggregateReader(strings.NewReader(tt.input))
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		got, mode, err := NewCoverageAnalyzer(0, nil).AggregateReader(strings.NewReader(""))
+		if err != nil || mode != "" || len(got) != 0 {
+			t.Errorf("AggregateReader() = %v, %q, %v; want no directories and no error", got, mode, err)
+		}
+	})
+}
+
+// benchmarkProfileData returns a profile of files source files with blocks blocks each
+func benchmarkProfileData(files, blocks int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("mode: count\n")
+	for i := range files {
+		for j := range blocks {
+			fmt.Fprintf(&buf, "github.com/example/project/pkg/module%d/file%d.go:%d.1,%d.10 3 %d\n", i%50, i, j*2+1, j*2+2, j%3)
+		}
+	}
+	return buf.Bytes()
+}
+
+// heapInUse returns the live heap after a garbage collection
+func heapInUse() int64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return int64(stats.HeapAlloc)
+}
+
+// BenchmarkAggregateReader compares parsing the whole profile before aggregating with streaming it.
+// live-B/op is the heap still in use once aggregation is done, including the parsed profiles
+// Aggregate needs to hold all at once
+func BenchmarkAggregateReader(b *testing.B) {
+	data := benchmarkProfileData(2000, 100)
+	analyzer := NewCoverageAnalyzer(0, nil)
+
+	b.Run("ParseProfiles", func(b *testing.B) {
+		b.ReportAllocs()
+		var live int64
+		for i := 0; i < b.N; i++ {
+			before := heapInUse()
+			profiles, err := cover.ParseProfilesFromReader(bytes.NewReader(data))
+			if err != nil {
+				b.Fatal(err)
+			}
+			coverageByDir := analyzer.Aggregate(profiles)
+			live += heapInUse() - before
+			runtime.KeepAlive(profiles)
+			runtime.KeepAlive(coverageByDir)
+		}
+		b.ReportMetric(float64(live)/float64(b.N), "live-B/op")
+	})
+
+	b.Run("Stream", func(b *testing.B) {
+		b.ReportAllocs()
+		var live int64
+		for i := 0; i < b.N; i++ {
+			before := heapInUse()
+			coverageByDir, _, err := analyzer.AggregateReader(bytes.NewReader(data))
+			if err != nil {
+				b.Fatal(err)
+			}
+			live += heapInUse() - before
+			runtime.KeepAlive(coverageByDir)
+		}
+		b.ReportMetric(float64(live)/float64(b.N), "live-B/op")
+	})
+}
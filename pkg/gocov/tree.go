@@ -0,0 +1,92 @@
+package gocov
+
+import (
+	"sort"
+	"strings"
+)
+
+// TreeNode is a directory in the tree built by BuildTree
+// Statements and Covered aggregate the node's own directory and all of its descendants
+type TreeNode struct {
+	// Name is the path relative to the parent node; chains of single children are joined with "/"
+	Name string
+	// Path is the full directory of the node
+	Path       string
+	Statements int
+	Covered    int
+	// Children are sorted by name
+	Children []*TreeNode
+}
+
+// Coverage returns the aggregated coverage percentage of the node
+func (n *TreeNode) Coverage() float64 {
+	return CalculateCoverage(n.Statements, n.Covered)
+}
+
+// BuildTree arranges results into a prefix tree of their directories and returns the root nodes sorted by name
+// Intermediate directories without results of their own only appear where the tree branches,
+// so "a/b/c" and "a/b/d" become "a/b" with children "c" and "d"
+func BuildTree(results []CoverageResult) []*TreeNode {
+	root := &TreeNode{}
+	for _, result := range results {
+		node := root
+		for _, part := range splitTreePath(result.Directory) {
+			node = node.child(part)
+			node.Statements += result.Statements
+			node.Covered += result.Covered
+		}
+		// Mark directories with results of their own so they are never merged into a child
+		node.Path = result.Directory
+	}
+
+	for _, child := range root.Children {
+		child.compact("")
+	}
+	return root.Children
+}
+
+// splitTreePath splits dir into path segments, keeping the leading slash of an absolute path on the first one
+func splitTreePath(dir string) []string {
+	parts := strings.Split(strings.TrimPrefix(dir, "/"), "/")
+	if strings.HasPrefix(dir, "/") {
+		parts[0] = "/" + parts[0]
+	}
+	return parts
+}
+
+// child returns the child named name, adding it in sorted position when it does not exist yet
+func (n *TreeNode) child(name string) *TreeNode {
+	i := sort.Search(len(n.Children), func(i int) bool {
+		return n.Children[i].Name >= name
+	})
+	if i < len(n.Children) && n.Children[i].Name == name {
+		return n.Children[i]
+	}
+	node := &TreeNode{Name: name}
+	n.Children = append(n.Children, nil)
+	copy(n.Children[i+1:], n.Children[i:])
+	n.Children[i] = node
+	return node
+}
+
+// compact joins n with its only child while n has no results of its own and fills in the paths below parent
+func (n *TreeNode) compact(parent string) {
+	for n.Path == "" && len(n.Children) == 1 {
+		only := n.Children[0]
+		n.Name = n.Name + "/" + only.Name
+		n.Path = only.Path
+		n.Children = only.Children
+	}
+	n.Path = joinTreePath(parent, n.Name)
+	for _, child := range n.Children {
+		child.compact(n.Path)
+	}
+}
+
+// joinTreePath returns the full path of a node named name below parent
+func joinTreePath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "/" + name
+}
@@ -0,0 +1,165 @@
+package gocov
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestBuildTree(t *testing.T) {
+	results := []CoverageResult{
+		{Directory: "example.com/m/pkg", Statements: 4, Covered: 1},
+		{Directory: "example.com/m/pkg/util", Statements: 10, Covered: 8},
+		{Directory: "example.com/m/pkg/api/v1", Statements: 6, Covered: 3},
+		{Directory: "example.com/m/cmd/app", Statements: 5, Covered: 0},
+		{Directory: "/abs/tool", Statements: 2, Covered: 2},
+	}
+
+	type node struct {
+		Name, Path          string
+		Statements, Covered int
+		Children            []node
+	}
+	var flatten func(nodes []*TreeNode) []node
+	flatten = func(nodes []*TreeNode) []node {
+		var out []node
+		for _, n := range nodes {
+			out = append(out, node{n.Name, n.Path, n.Statements, n.Covered, flatten(n.Children)})
+		}
+		return out
+	}
+
+	want := []node{
+		{"/abs/tool", "/abs/tool", 2, 2, nil},
+		{"example.com/m", "example.com/m", 25, 12, []node{
+			{"cmd/app", "example.com/m/cmd/app", 5, 0, nil},
+			// pkg has results of its own, so it is kept even though it has children
+			{"pkg", "example.com/m/pkg", 20, 12, []node{
+				{"api/v1", "example.com/m/pkg/api/v1", 6, 3, nil},
+				{"util", "example.com/m/pkg/util", 10, 8, nil},
+			}},
+		}},
+	}
+	if got := flatten(BuildTree(results)); !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildTree() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBuildTreeFromProfile(t *testing.T) {
+	profiles, err := cover.ParseProfiles("../../testdata/coverage.out")
+	if err != nil {
+		t.Fatalf("Failed to parse profile: %v", err)
+	}
+	coverageByDir := NewCoverageAnalyzer(0, nil).Aggregate(profiles)
+
+	var results []CoverageResult
+	total := CoverageResult{}
+	for _, dir := range FilterDirectories(coverageByDir, 0, 100, 0) {
+		cov := coverageByDir[dir]
+		results = append(results, CoverageResult{Directory: dir, Statements: cov.StmtCount, Covered: cov.StmtCovered})
+		total.Statements += cov.StmtCount
+		total.Covered += cov.StmtCovered
+	}
+
+	roots := BuildTree(results)
+	if len(roots) != 1 {
+		t.Fatalf("Expected a single root, got %d", len(roots))
+	}
+	// The parent sums its children, which together are every directory of the profile
+	root := roots[0]
+	statements, covered := 0, 0
+	for _, child := range root.Children {
+		statements += child.Statements
+		covered += child.Covered
+	}
+	if root.Statements != statements || root.Covered != covered {
+		t.Errorf("Root has %d/%d statements, children sum to %d/%d", root.Covered, root.Statements, covered, statements)
+	}
+	if root.Statements != total.Statements || root.Covered != total.Covered {
+		t.Errorf("Root has %d/%d statements, want the profile total %d/%d", root.Covered, root.Statements, total.Covered, total.Statements)
+	}
+	if root.Name != "github.com/example/project" || len(root.Children) != 3 {
+		t.Errorf("Unexpected root %q with %d children", root.Name, len(root.Children))
+	}
+}
+
+func TestTreeFormatter(t *testing.T) {
+	results := []CoverageResult{
+		{Directory: "cmd/server", Statements: 7, Covered: 5},
+		{Directory: "pkg/util", Statements: 14, Covered: 11},
+		{Directory: "pkg/util/strs", Statements: 6, Covered: 0},
+	}
+	totalResult := CoverageResult{Directory: "TOTAL", Statements: 27, Covered: 16, Coverage: CalculateCoverage(27, 16)}
+
+	t.Run("tree", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := NewTreeFormatter(&buf, false).Format(results, totalResult, nil); err != nil {
+			t.Fatalf("TreeFormatter failed: %v", err)
+		}
+		want := `Directory                                          Statements    Covered Coverage
+--------------------------------------------------------------------------------
+cmd/server                                                  7          5    71.4%
+pkg/util                                                   20         11    55.0%
+  strs                                                      6          0     0.0%
+--------------------------------------------------------------------------------
+TOTAL                                                      27         16    59.3%
+`
+		if got := buf.String(); got != want {
+			t.Errorf("TreeFormatter output:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("width", func(t *testing.T) {
+		var buf bytes.Buffer
+		formatter := NewTreeFormatter(&buf, false)
+		formatter.SetWidth(10)
+		if err := formatter.Format(results, totalResult, nil); err != nil {
+			t.Fatalf("TreeFormatter failed: %v", err)
+		}
+		want := `Directory  Statements    Covered Coverage
+----------------------------------------
+cmd/server          7          5    71.4%
+pkg/util           20         11    55.0%
+  strs              6          0     0.0%
+----------------------------------------
+TOTAL              27         16    59.3%
+`
+		if got := buf.String(); got != want {
+			t.Errorf("TreeFormatter output:\n%s\nwant:\n%s", got, want)
+		}
+
+		buf.Reset()
+		long := []CoverageResult{{Directory: "internal/service", Statements: 1, Covered: 1}}
+		if err := formatter.Format(long, totalResult, nil); err != nil {
+			t.Fatalf("TreeFormatter failed: %v", err)
+		}
+		if !strings.Contains(buf.String(), "\ninterna...          1          1   100.0%\n") {
+			t.Errorf("Long names should be truncated to the width\nGot: %s", buf.String())
+		}
+	})
+
+	t.Run("group by package", func(t *testing.T) {
+		var buf bytes.Buffer
+		formatter := NewTreeFormatter(&buf, false)
+		formatter.SetGroupBy(GroupByPackage)
+		if err := formatter.Format(results, totalResult, nil); err != nil {
+			t.Fatalf("TreeFormatter failed: %v", err)
+		}
+		if !strings.HasPrefix(buf.String(), "Package                                            Statements") {
+			t.Errorf("Header should name packages\nGot: %s", buf.String())
+		}
+	})
+
+	t.Run("quiet", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := NewTreeFormatter(&buf, true).Format(results, totalResult, nil); err != nil {
+			t.Fatalf("TreeFormatter failed: %v", err)
+		}
+		if want := "TOTAL                                                      27         16    59.3%\n"; buf.String() != want {
+			t.Errorf("TreeFormatter quiet output = %q, want %q", buf.String(), want)
+		}
+	})
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package main
+
+import "io"
+
+// terminalWidth reports 0, since terminal sizes are only detected on Linux and macOS
+func terminalWidth(w io.Writer) int {
+	return 0
+}
@@ -2,10 +2,16 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/blck-snwmn/gocov/pkg/gocov"
 )
 
 func TestThresholdCheck(t *testing.T) {
@@ -135,7 +141,7 @@ func TestValidateThreshold(t *testing.T) {
 }
 
 func TestThresholdError(t *testing.T) {
-	err := NewThresholdError(80.0, 75.5)
+	err := NewThresholdError(80.0, 75.5, gocov.DefaultPrecision)
 	thresholdErr, ok := err.(*ThresholdError)
 	if !ok {
 		t.Fatalf("Expected ThresholdError but got %T", err)
@@ -145,9 +151,542 @@ func TestThresholdError(t *testing.T) {
 	if thresholdErr.Error() != expectedMsg {
 		t.Errorf("Expected error message %q but got %q", expectedMsg, thresholdErr.Error())
 	}
+
+	// The threshold is shown at the same precision as the coverage it was compared with
+	err = NewThresholdError(80, 79.96, 2)
+	if want := "coverage 79.96% is below threshold 80.00%"; err.Error() != want {
+		t.Errorf("Expected error message %q but got %q", want, err.Error())
+	}
+	err = NewThresholdErrorWithViolations(0, 85, []gocov.ThresholdViolation{
+		{Directory: "pkg/util", Pattern: "pkg/*", Threshold: 79.955, Actual: 79.95},
+	}, 3)
+	if want := "pkg/util coverage 79.950% is below threshold 79.955% (pkg/*)"; err.Error() != want {
+		t.Errorf("Expected error message %q but got %q", want, err.Error())
+	}
+}
+
+func TestDirectoryThresholdsFromConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, ".gocov.yml")
+	configContent := `format: table
+coverage:
+  min: 0
+  max: 100
+thresholds:
+  "pkg/*": 90
+  "internal/*": 50
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	cli := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-config", configFile})
+	err := cli.Run()
+
+	thresholdErr, ok := err.(*ThresholdError)
+	if !ok {
+		t.Fatalf("Expected ThresholdError but got: %T (%v)", err, err)
+	}
+	if len(thresholdErr.Violations) != 1 {
+		t.Fatalf("Expected 1 violation, got %v", thresholdErr.Violations)
+	}
+	if thresholdErr.Violations[0].Directory != "github.com/example/project/pkg/util" {
+		t.Errorf("Expected pkg/util violation, got %+v", thresholdErr.Violations[0])
+	}
+	if !strings.Contains(err.Error(), "github.com/example/project/pkg/util coverage 71.4% is below threshold 90.0% (pkg/*)") {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+}
+
+func TestFailUnderPerDir(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantDirs   []string
+		wantErrMsg string
+	}{
+		{
+			name: "every directory passes",
+			args: []string{"-fail-under-per-dir", "70"},
+		},
+		{
+			name:     "failing directories are listed",
+			args:     []string{"-fail-under-per-dir", "80"},
+			wantDirs: []string{"github.com/example/project/cmd/server", "github.com/example/project/pkg/util"},
+		},
+		{
+			name:       "total threshold stays independent",
+			args:       []string{"-fail-under-per-dir", "80", "-threshold", "90"},
+			wantDirs:   []string{"github.com/example/project/cmd/server", "github.com/example/project/pkg/util"},
+			wantErrMsg: "coverage 76.2% is below threshold 90.0%",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := NewCLI(&buf, append([]string{"-coverprofile", "testdata/coverage.out"}, tt.args...)).Run()
+			if len(tt.wantDirs) == 0 {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			thresholdErr, ok := err.(*ThresholdError)
+			if !ok {
+				t.Fatalf("Expected ThresholdError but got: %T (%v)", err, err)
+			}
+			var dirs []string
+			for _, v := range thresholdErr.Violations {
+				dirs = append(dirs, v.Directory)
+			}
+			if strings.Join(dirs, ",") != strings.Join(tt.wantDirs, ",") {
+				t.Errorf("Violations = %v, want %v", dirs, tt.wantDirs)
+			}
+			if !strings.Contains(err.Error(), "github.com/example/project/pkg/util coverage 71.4% is below threshold 80.0%") {
+				t.Errorf("Unexpected error message: %v", err)
+			}
+			if tt.wantErrMsg != "" && !strings.Contains(err.Error(), tt.wantErrMsg) {
+				t.Errorf("Error should contain %q, got: %v", tt.wantErrMsg, err)
+			}
+		})
+	}
+}
+
+func TestThresholdErrorWithViolations(t *testing.T) {
+	violations := []gocov.ThresholdViolation{
+		{Directory: "pkg/util", Pattern: "pkg/*", Threshold: 90, Actual: 80},
+	}
+
+	t.Run("total passes", func(t *testing.T) {
+		err := NewThresholdErrorWithViolations(0, 85, violations, gocov.DefaultPrecision)
+		expectedMsg := "pkg/util coverage 80.0% is below threshold 90.0% (pkg/*)"
+		if err.Error() != expectedMsg {
+			t.Errorf("Expected error message %q but got %q", expectedMsg, err.Error())
+		}
+	})
+
+	t.Run("total also fails", func(t *testing.T) {
+		err := NewThresholdErrorWithViolations(90, 85, violations, gocov.DefaultPrecision)
+		expectedMsg := "coverage 85.0% is below threshold 90.0%; pkg/util coverage 80.0% is below threshold 90.0% (pkg/*)"
+		if err.Error() != expectedMsg {
+			t.Errorf("Expected error message %q but got %q", expectedMsg, err.Error())
+		}
+	})
+
+	t.Run("violation without pattern", func(t *testing.T) {
+		err := NewThresholdErrorWithViolations(0, 85, []gocov.ThresholdViolation{
+			{Directory: "main.go", Threshold: 70, Actual: 50},
+		}, gocov.DefaultPrecision)
+		expectedMsg := "main.go coverage 50.0% is below threshold 70.0%"
+		if err.Error() != expectedMsg {
+			t.Errorf("Expected error message %q but got %q", expectedMsg, err.Error())
+		}
+	})
+}
+
+func TestCheckThreshold(t *testing.T) {
+	baseline := &gocov.JSONReport{Total: gocov.CoverageResult{Coverage: 80}}
+
+	tests := []struct {
+		name    string
+		config  Config
+		actual  float64
+		wantErr bool
+	}{
+		{"absolute pass", Config{Threshold: 70}, 75, false},
+		{"absolute fail", Config{Threshold: 80}, 75, true},
+		{"absolute disabled", Config{}, 10, false},
+		{"baseline improvement", Config{ThresholdMode: ThresholdModeBaseline}, 85, false},
+		{"baseline unchanged", Config{ThresholdMode: ThresholdModeBaseline}, 80, false},
+		{"baseline regression", Config{ThresholdMode: ThresholdModeBaseline}, 79.9, true},
+		{"baseline within tolerance", Config{ThresholdMode: ThresholdModeBaseline, BaselineTolerance: 0.5}, 79.6, false},
+		{"baseline beyond tolerance", Config{ThresholdMode: ThresholdModeBaseline, BaselineTolerance: 0.5}, 79.4, true},
+		{"baseline ignores threshold", Config{ThresholdMode: ThresholdModeBaseline, Threshold: 90}, 85, false},
+		{"rounded to displayed value", Config{Threshold: 80}, 79.96, false},
+		{"rounded boundary", Config{Threshold: 80}, 79.95, false},
+		{"below after rounding", Config{Threshold: 80}, 79.94, true},
+		{"more places", Config{Threshold: 80, Round: intPtr(2)}, 79.96, true},
+		{"no places", Config{Threshold: 80, Round: intPtr(0)}, 79.5, false},
+		{"raw comparison", Config{Threshold: 80, ThresholdPrecision: intPtr(gocov.RawPrecision)}, 79.96, true},
+		{"raw boundary", Config{Threshold: 80, ThresholdPrecision: intPtr(gocov.RawPrecision)}, 79.95, true},
+		{"raw exact", Config{Threshold: 80, ThresholdPrecision: intPtr(gocov.RawPrecision)}, 80, false},
+		{"threshold precision over round", Config{Threshold: 80, Round: intPtr(2), ThresholdPrecision: intPtr(1)}, 79.96, false},
+		{"threshold precision finer than round", Config{Threshold: 80, Round: intPtr(0), ThresholdPrecision: intPtr(2)}, 79.95, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckThreshold(&tt.config, tt.actual, baseline)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckThreshold() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if _, ok := err.(*ThresholdError); !ok {
+					t.Errorf("Expected ThresholdError but got: %T", err)
+				}
+			}
+		})
+	}
+
+	t.Run("baseline equal to unrounded coverage", func(t *testing.T) {
+		// 2001/2500 is 80.04%, which displays as 80.0%
+		unrounded := &gocov.JSONReport{Total: gocov.CoverageResult{Coverage: 80.04}}
+		config := &Config{ThresholdMode: ThresholdModeBaseline}
+		if err := CheckThreshold(config, 80.04, unrounded); err != nil {
+			t.Errorf("CheckThreshold() should pass when coverage equals the baseline, got %v", err)
+		}
+	})
+}
+
+func TestThresholdModeBaseline(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// testdata/coverage.out totals 76.2%
+	writeBaseline := func(name string, coverage float64) string {
+		path := filepath.Join(tmpDir, name)
+		content := fmt.Sprintf(`{"version": "1", "results": [], "total": {"directory": "TOTAL", "statements": 100, "covered": 0, "coverage": %v}}`, coverage)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write baseline: %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name      string
+		baseline  string
+		tolerance string
+		wantErr   bool
+	}{
+		{"improvement", writeBaseline("lower.json", 70), "", false},
+		{"regression", writeBaseline("higher.json", 80), "", true},
+		{"within tolerance", writeBaseline("close.json", 76.5), "1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{"-coverprofile", "testdata/coverage.out", "-threshold-mode", "baseline", "-baseline", tt.baseline}
+			if tt.tolerance != "" {
+				args = append(args, "-baseline-tolerance", tt.tolerance)
+			}
+
+			var buf bytes.Buffer
+			err := NewCLI(&buf, args).Run()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if _, ok := err.(*ThresholdError); !ok {
+					t.Errorf("Expected ThresholdError but got: %T (%v)", err, err)
+				}
+			}
+		})
+	}
+
+	t.Run("regression with directory violations", func(t *testing.T) {
+		args := []string{"-coverprofile", "testdata/coverage.out", "-threshold-mode", "baseline", "-baseline", writeBaseline("dirs.json", 80), "-fail-under-per-dir", "80"}
+		err := NewCLI(io.Discard, args).Run()
+		thresholdErr, ok := err.(*ThresholdError)
+		if !ok {
+			t.Fatalf("Expected ThresholdError but got: %T (%v)", err, err)
+		}
+		if len(thresholdErr.Violations) == 0 {
+			t.Error("Directory violations should be reported")
+		}
+		if !strings.Contains(err.Error(), "coverage 76.2% is below threshold 80.0%") {
+			t.Errorf("The baseline regression should be reported with the directory violations, got: %v", err)
+		}
+	})
+
+	t.Run("baseline written from the same profile", func(t *testing.T) {
+		var report bytes.Buffer
+		if err := NewCLI(&report, []string{"-coverprofile", "testdata/coverage.out", "-format", "json"}).Run(); err != nil {
+			t.Fatalf("Failed to write baseline: %v", err)
+		}
+		path := filepath.Join(tmpDir, "same.json")
+		if err := os.WriteFile(path, report.Bytes(), 0644); err != nil {
+			t.Fatalf("Failed to write baseline: %v", err)
+		}
+		if err := NewCLI(io.Discard, []string{"-coverprofile", "testdata/coverage.out", "-threshold-mode", "baseline", "-baseline", path}).Run(); err != nil {
+			t.Errorf("Run() should pass against its own baseline, got %v", err)
+		}
+	})
+
+	t.Run("baseline without total", func(t *testing.T) {
+		path := filepath.Join(tmpDir, "no-total.json")
+		if err := os.WriteFile(path, []byte(`{"version": "1", "results": []}`), 0644); err != nil {
+			t.Fatalf("Failed to write baseline: %v", err)
+		}
+		err := NewCLI(io.Discard, []string{"-coverprofile", "testdata/coverage.out", "-threshold-mode", "baseline", "-baseline", path}).Run()
+		if !errors.Is(err, ErrBaselineNoTotal) {
+			t.Errorf("Expected ErrBaselineNoTotal but got: %T (%v)", err, err)
+		}
+	})
+
+	t.Run("missing baseline", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-threshold-mode", "baseline", "-baseline", filepath.Join(tmpDir, "missing.json")}).Run()
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) {
+			t.Errorf("Expected ConfigError but got: %T (%v)", err, err)
+		}
+	})
+}
+
+func TestThresholdResultInJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantResult bool
+		wantPassed bool
+	}{
+		{"no threshold", nil, false, true},
+		{"passing threshold", []string{"-threshold", "70"}, true, true},
+		{"failing threshold", []string{"-threshold", "80"}, true, false},
+		{"failing directory", []string{"-threshold", "70", "-fail-under-per-dir", "80"}, true, false},
+		{"quiet", []string{"-threshold", "80", "-quiet"}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			args := append([]string{"-coverprofile", "testdata/coverage.out", "-format", "json"}, tt.args...)
+			err := NewCLI(&buf, args).Run()
+			if (err == nil) != tt.wantPassed {
+				t.Fatalf("Run() error = %v, want passed %v", err, tt.wantPassed)
+			}
+			if err != nil {
+				var thresholdErr *ThresholdError
+				if !errors.As(err, &thresholdErr) {
+					t.Fatalf("Expected ThresholdError but got: %T (%v)", err, err)
+				}
+			}
+
+			// The report is written even when a threshold fails
+			var report gocov.JSONReport
+			if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+				t.Fatalf("Failed to parse JSON: %v\nGot: %s", err, buf.String())
+			}
+			if report.Total.Statements != 21 {
+				t.Errorf("Expected the total in JSON output\nGot: %s", buf.String())
+			}
+			if !tt.wantResult {
+				if report.Threshold != nil || report.Passed != nil {
+					t.Errorf("Expected no threshold result\nGot: %s", buf.String())
+				}
+				return
+			}
+			if report.Threshold == nil || report.Passed == nil {
+				t.Fatalf("Expected threshold result\nGot: %s", buf.String())
+			}
+			if *report.Passed != tt.wantPassed {
+				t.Errorf("passed = %v, want %v", *report.Passed, tt.wantPassed)
+			}
+		})
+	}
+}
+
+func TestDirectoryPassStatus(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, ".gocov.yml")
+	configContent := `format: table
+coverage:
+  min: 0
+  max: 100
+thresholds:
+  "*/pkg/*": 90
+  "*/internal/*": 80
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	t.Run("table", func(t *testing.T) {
+		var buf bytes.Buffer
+		args := []string{"-coverprofile", "testdata/coverage.out", "-config", configFile, "-trim-prefix", "github.com/example/project", "-ascii"}
+		err := NewCLI(&buf, args).Run()
+		var thresholdErr *ThresholdError
+		if !errors.As(err, &thresholdErr) {
+			t.Fatalf("Expected ThresholdError but got: %T (%v)", err, err)
+		}
+
+		output := buf.String()
+		expected := []string{"85.7% PASS\n", "71.4% FAIL\n", "71.4%     \n"}
+		for _, want := range expected {
+			if !strings.Contains(output, want) {
+				t.Errorf("Output should contain %q\nGot: %s", want, output)
+			}
+		}
+	})
+
+	t.Run("color", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			color    string
+			terminal bool
+			want     string
+		}{
+			{"auto on a terminal", "auto", true, "85.7%    ✓\n"},
+			{"auto when piped", "auto", false, "85.7% PASS\n"},
+			{"always", "always", false, "85.7%    ✓\n"},
+			{"never", "never", true, "85.7% PASS\n"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				cli := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-config", configFile, "-trim-prefix", "github.com/example/project", "-width", "30", "-color", tt.color})
+				cli.isTerminal = func() bool { return tt.terminal }
+				if err := cli.Run(); err == nil {
+					t.Fatal("Expected threshold failure")
+				}
+				if !strings.Contains(buf.String(), tt.want) {
+					t.Errorf("Output should contain %q\nGot: %s", tt.want, buf.String())
+				}
+			})
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		args := []string{"-coverprofile", "testdata/coverage.out", "-config", configFile, "-format", "json"}
+		if err := NewCLI(&buf, args).Run(); err == nil {
+			t.Fatal("Expected threshold failure")
+		}
+
+		var report gocov.JSONReport
+		if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to parse JSON: %v\nGot: %s", err, buf.String())
+		}
+		want := map[string]string{
+			"github.com/example/project/cmd/server":       "unchecked",
+			"github.com/example/project/internal/service": "pass",
+			"github.com/example/project/pkg/util":         "fail",
+		}
+		for _, result := range report.Results {
+			got := "unchecked"
+			if result.Pass != nil && *result.Pass {
+				got = "pass"
+			} else if result.Pass != nil {
+				got = "fail"
+			}
+			if got != want[result.Directory] {
+				t.Errorf("%s: pass = %s, want %s", result.Directory, got, want[result.Directory])
+			}
+		}
+		if report.Total.Pass != nil {
+			t.Errorf("Expected no pass field on the total\nGot: %s", buf.String())
+		}
+	})
+}
+
+func TestNoTotalStillChecksThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold string
+		wantErr   bool
+	}{
+		{"passing", "70", false},
+		{"failing", "80", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-no-total", "-threshold", tt.threshold}).Run()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				var thresholdErr *ThresholdError
+				if !errors.As(err, &thresholdErr) || ExitCode(err) != 2 {
+					t.Errorf("Expected ThresholdError with exit code 2, got: %T (%v)", err, err)
+				}
+			}
+			if strings.Contains(buf.String(), "TOTAL") {
+				t.Errorf("TOTAL row should be omitted\nGot: %s", buf.String())
+			}
+			if !strings.Contains(buf.String(), "pkg/util") {
+				t.Errorf("Directory rows should be kept\nGot: %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestSourceThresholds(t *testing.T) {
+	tmpDir := t.TempDir()
+	payDir := filepath.Join(tmpDir, "pay")
+	if err := os.MkdirAll(payDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(payDir, "doc.go"), []byte("//gocov:threshold 90\npackage pay\n"), 0644); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+	// 80% coverage in pay, whose sources are on disk, and 50% in a package that is not
+	profile := filepath.Join(tmpDir, "coverage.out")
+	content := "mode: set\n" +
+		filepath.Join(payDir, "pay.go") + ":1.1,2.1 8 1\n" +
+		filepath.Join(payDir, "pay.go") + ":3.1,4.1 2 0\n" +
+		filepath.Join(tmpDir, "gone", "a.go") + ":1.1,2.1 1 0\n" +
+		filepath.Join(tmpDir, "gone", "a.go") + ":3.1,4.1 1 1\n"
+	if err := os.WriteFile(profile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write coverage file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"disabled by default", nil, ""},
+		{"directive fails", []string{"-source-thresholds"}, "pay coverage 80.0% is below threshold 90.0% (//gocov:threshold)"},
+		{"missing sources are not checked", []string{"-source-thresholds", "-ignore", "pay"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewCLI(io.Discard, append([]string{"-coverprofile", profile}, tt.args...)).Run()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+			var thresholdErr *ThresholdError
+			if !errors.As(err, &thresholdErr) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected ThresholdError containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("directive takes precedence over thresholds", func(t *testing.T) {
+		configPath := filepath.Join(tmpDir, ".gocov.yml")
+		configContent := "format: table\nsource_thresholds: true\nthresholds:\n  \"*/pay\": 70\n  \"*/gone\": 60\n"
+		if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		err := NewCLI(io.Discard, []string{"-coverprofile", profile, "-config", configPath}).Run()
+		var thresholdErr *ThresholdError
+		if !errors.As(err, &thresholdErr) {
+			t.Fatalf("Expected ThresholdError, got: %v", err)
+		}
+		var patterns []string
+		for _, v := range thresholdErr.Violations {
+			patterns = append(patterns, v.Pattern)
+		}
+		if strings.Join(patterns, ",") != "*/gone,//gocov:threshold" {
+			t.Errorf("Expected violations from */gone and the directive only, got %+v", thresholdErr.Violations)
+		}
+	})
 }
 
 // Helper function to format float as string
 func formatFloat(f float64) string {
 	return fmt.Sprintf("%.1f", f)
 }
+
+func intPtr(n int) *int {
+	return &n
+}
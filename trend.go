@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blck-snwmn/gocov/pkg/gocov"
+)
+
+// sparkBars are the levels of a sparkline, from lowest to highest coverage
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// TrendReport is a JSON report loaded for -trend
+type TrendReport struct {
+	Path   string
+	Report *gocov.JSONReport
+	// ModTime is the modification time of the file, used when the report has no metadata timestamp
+	ModTime time.Time
+}
+
+// TrendPoint is the total coverage of one report
+type TrendPoint struct {
+	Path       string
+	Time       time.Time
+	Statements int
+	Covered    int
+	Coverage   float64
+}
+
+// LoadTrendReports reads the JSON reports at paths, as written with -format json
+func LoadTrendReports(paths []string) ([]TrendReport, error) {
+	reports := make([]TrendReport, 0, len(paths))
+	for _, path := range paths {
+		report, err := LoadBaseline(path)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, NewParseError(path, err)
+		}
+		reports = append(reports, TrendReport{Path: path, Report: report, ModTime: info.ModTime()})
+	}
+	return reports, nil
+}
+
+// BuildTrend returns the total coverage of each report in chronological order
+// A report is dated by its metadata timestamp (-metadata), falling back to the file modification time.
+// Reports with the same time keep their given order
+func BuildTrend(reports []TrendReport) []TrendPoint {
+	points := make([]TrendPoint, 0, len(reports))
+	for _, report := range reports {
+		points = append(points, TrendPoint{
+			Path:       report.Path,
+			Time:       reportTime(report),
+			Statements: report.Report.Total.Statements,
+			Covered:    report.Report.Total.Covered,
+			Coverage:   report.Report.Total.Coverage,
+		})
+	}
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Time.Before(points[j].Time)
+	})
+	return points
+}
+
+// reportTime returns when report was generated
+func reportTime(report TrendReport) time.Time {
+	if metadata := report.Report.Metadata; metadata != nil {
+		if generatedAt, err := time.Parse(time.RFC3339, metadata.GeneratedAt); err == nil {
+			return generatedAt
+		}
+	}
+	return report.ModTime
+}
+
+// Sparkline draws the coverage of points as one bar each, scaled between the lowest and highest value
+func Sparkline(points []TrendPoint) string {
+	coverages := make([]float64, len(points))
+	for i, point := range points {
+		coverages[i] = point.Coverage
+	}
+	return RenderSparkline(coverages)
+}
+
+// RenderSparkline draws coverages as one bar each, scaled between the lowest and highest value
+func RenderSparkline(coverages []float64) string {
+	if len(coverages) == 0 {
+		return ""
+	}
+	low, high := coverages[0], coverages[0]
+	for _, coverage := range coverages[1:] {
+		low = min(low, coverage)
+		high = max(high, coverage)
+	}
+
+	var line strings.Builder
+	for _, coverage := range coverages {
+		level := 0
+		if high > low {
+			level = int((coverage - low) / (high - low) * float64(len(sparkBars)-1))
+		}
+		line.WriteRune(sparkBars[level])
+	}
+	return line.String()
+}
+
+// WriteTrend writes a table of the total coverage over time, rounded to places, followed by a sparkline
+func WriteTrend(w io.Writer, points []TrendPoint, places int) {
+	fmt.Fprintf(w, "%-20s %8s %8s  %s\n", "Date", "Coverage", "Change", "Report")
+	fmt.Fprintln(w, strings.Repeat("-", 60))
+	for i, point := range points {
+		change := ""
+		if i > 0 {
+			change = gocov.FormatCoverage(point.Coverage-points[i-1].Coverage, places)
+			if !strings.HasPrefix(change, "-") {
+				change = "+" + change
+			}
+		}
+		fmt.Fprintf(w, "%-20s %7s%% %8s  %s\n", point.Time.UTC().Format("2006-01-02T15:04Z"), gocov.FormatCoverage(point.Coverage, places), change, point.Path)
+	}
+	fmt.Fprintf(w, "\nTrend: %s\n", Sparkline(points))
+}
+
+// runTrend prints the coverage trend across the reports given to -trend with places decimal places
+func (c *CLI) runTrend(spec string, places int) error {
+	reports, err := LoadTrendReports(SplitPatterns(spec))
+	if err != nil {
+		return err
+	}
+	WriteTrend(c.Output, BuildTrend(reports), places)
+	return nil
+}
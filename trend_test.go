@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blck-snwmn/gocov/pkg/gocov"
+)
+
+func TestBuildTrend(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, 5, d, 9, 0, 0, 0, time.UTC)
+	}
+	report := func(coverage float64, generatedAt string) *gocov.JSONReport {
+		r := &gocov.JSONReport{Total: gocov.CoverageResult{Statements: 100, Covered: int(coverage), Coverage: coverage}}
+		if generatedAt != "" {
+			r.Metadata = &gocov.Metadata{GeneratedAt: generatedAt}
+		}
+		return r
+	}
+
+	reports := []TrendReport{
+		{Path: "wed.json", Report: report(75, ""), ModTime: day(8)},
+		{Path: "mon.json", Report: report(74, "2024-05-06T09:00:00Z"), ModTime: day(20)},
+		{Path: "tue.json", Report: report(76, "2024-05-07T18:00:00+09:00"), ModTime: day(1)},
+		{Path: "bad.json", Report: report(70, "yesterday"), ModTime: day(9)},
+	}
+
+	got := BuildTrend(reports)
+	want := []TrendPoint{
+		{Path: "mon.json", Time: day(6), Statements: 100, Covered: 74, Coverage: 74},
+		{Path: "tue.json", Time: day(7), Statements: 100, Covered: 76, Coverage: 76},
+		{Path: "wed.json", Time: day(8), Statements: 100, Covered: 75, Coverage: 75},
+		{Path: "bad.json", Time: day(9), Statements: 100, Covered: 70, Coverage: 70},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("BuildTrend() returned %d points, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Path != want[i].Path || !got[i].Time.Equal(want[i].Time) || got[i].Coverage != want[i].Coverage || got[i].Covered != want[i].Covered {
+			t.Errorf("point %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name      string
+		coverages []float64
+		want      string
+	}{
+		{"empty", nil, ""},
+		{"flat", []float64{80, 80}, "▁▁"},
+		{"rising", []float64{0, 50, 100}, "▁▄█"},
+		{"dip", []float64{74, 76.2, 75.1}, "▁█▄"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points := make([]TrendPoint, len(tt.coverages))
+			for i, coverage := range tt.coverages {
+				points[i].Coverage = coverage
+			}
+			if got := Sparkline(points); got != tt.want {
+				t.Errorf("Sparkline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrendMode(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, report gocov.JSONReport) string {
+		data, err := json.Marshal(report)
+		if err != nil {
+			t.Fatalf("Failed to encode report: %v", err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to write report: %v", err)
+		}
+		return path
+	}
+
+	older := write("older.json", gocov.JSONReport{
+		Total:    gocov.CoverageResult{Statements: 10, Covered: 7, Coverage: 70},
+		Metadata: &gocov.Metadata{GeneratedAt: "2024-05-06T09:00:00Z"},
+	})
+	// Without metadata the modification time dates the report
+	newer := write("newer.json", gocov.JSONReport{Total: gocov.CoverageResult{Statements: 10, Covered: 8, Coverage: 80}})
+	modTime := time.Date(2024, 5, 7, 9, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(newer, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set modification time: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := NewCLI(&buf, []string{"-trend", newer + ", " + older}).Run(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "Date                 Coverage   Change  Report\n" +
+		"------------------------------------------------------------\n" +
+		"2024-05-06T09:00Z       70.0%           " + older + "\n" +
+		"2024-05-07T09:00Z       80.0%    +10.0  " + newer + "\n" +
+		"\nTrend: ▁█\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected trend output\nGot:\n%s\nWant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := NewCLI(&buf, []string{"-trend", newer + ", " + older, "-round", "2"}).Run(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "2024-05-07T09:00Z      80.00%   +10.00  "+newer+"\n") {
+		t.Errorf("Trend should use -round\nGot:\n%s", buf.String())
+	}
+
+	err := NewCLI(&buf, []string{"-trend", filepath.Join(dir, "missing.json")}).Run()
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Errorf("Expected ConfigError for a missing report, got: %v", err)
+	}
+}
@@ -1,6 +1,12 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+
+	"github.com/blck-snwmn/gocov/pkg/gocov"
+	"golang.org/x/tools/cover"
+)
 
 // ValidateCoverageConfig validates coverage configuration values
 func ValidateCoverageConfig(min, max float64) error {
@@ -18,8 +24,8 @@ func ValidateCoverageConfig(min, max float64) error {
 
 // ValidateFormat validates the output format
 func ValidateFormat(format string) error {
-	if format != "table" && format != "json" {
-		return NewValidationError("format", format, "must be 'table' or 'json'")
+	if format != "table" && format != "json" && format != "html" && format != "summary" && format != "tsv" && format != "tree" {
+		return NewValidationError("format", format, "must be 'table', 'json', 'html', 'summary', 'tsv', or 'tree'")
 	}
 	return nil
 }
@@ -31,3 +37,246 @@ func ValidateThreshold(threshold float64) error {
 	}
 	return nil
 }
+
+// ValidateDiffThreshold validates the diff coverage threshold
+func ValidateDiffThreshold(threshold float64) error {
+	if threshold < 0 || threshold > 100 {
+		return NewValidationError("diff_threshold", threshold, "must be between 0 and 100")
+	}
+	return nil
+}
+
+// ValidateDiffFileThreshold validates the per-file diff coverage threshold
+func ValidateDiffFileThreshold(threshold float64) error {
+	if threshold < 0 || threshold > 100 {
+		return NewValidationError("diff_file_threshold", threshold, "must be between 0 and 100")
+	}
+	return nil
+}
+
+// ValidateDiffInclude validates the kind of changed lines counted in diff mode
+func ValidateDiffInclude(include string) error {
+	if include != "" && include != "added" && include != "modified" {
+		return NewValidationError("diff_include", include, "must be 'added' or 'modified'")
+	}
+	return nil
+}
+
+// ValidateDiffSort validates the file order used in diff mode
+func ValidateDiffSort(sortBy string) error {
+	if sortBy != "" && sortBy != "file" && sortBy != "uncovered" {
+		return NewValidationError("diff_sort", sortBy, "must be 'file' or 'uncovered'")
+	}
+	return nil
+}
+
+// ValidateThresholdMode validates the threshold mode and that baseline mode has a report to compare against
+func ValidateThresholdMode(mode, baseline string) error {
+	switch mode {
+	case "", ThresholdModeAbsolute:
+		return nil
+	case ThresholdModeBaseline:
+		if baseline == "" {
+			return NewValidationError("baseline", baseline, "is required when threshold_mode is 'baseline'")
+		}
+		return nil
+	default:
+		return NewValidationError("threshold_mode", mode, "must be 'absolute' or 'baseline'")
+	}
+}
+
+// ValidateBaselineTolerance validates the allowed drop from the baseline
+func ValidateBaselineTolerance(tolerance float64) error {
+	if tolerance < 0 || tolerance > 100 {
+		return NewValidationError("baseline_tolerance", tolerance, "must be between 0 and 100")
+	}
+	return nil
+}
+
+// ValidateHistogramBands validates that histogram band boundaries are ascending percentages
+func ValidateHistogramBands(bands []float64) error {
+	for i, band := range bands {
+		if band <= 0 || band > 100 {
+			return NewValidationError("histogram_bands", bands, "must be between 0 (exclusive) and 100")
+		}
+		if i > 0 && band <= bands[i-1] {
+			return NewValidationError("histogram_bands", bands, "must be in ascending order")
+		}
+	}
+	return nil
+}
+
+// ValidateDiffIncludeContext validates the number of context lines included in diff mode
+func ValidateDiffIncludeContext(contextLines int) error {
+	if contextLines < 0 {
+		return NewValidationError("diff_include_context", contextLines, "must be 0 or greater")
+	}
+	return nil
+}
+
+// ValidateFailUnderPerDir validates the minimum coverage required for every directory
+func ValidateFailUnderPerDir(minCoverage float64) error {
+	if minCoverage < 0 || minCoverage > 100 {
+		return NewValidationError("fail_under_per_dir", minCoverage, "must be between 0 and 100")
+	}
+	return nil
+}
+
+// ValidateGroupBy validates the grouping mode
+func ValidateGroupBy(groupBy string) error {
+	if groupBy != "" && groupBy != gocov.GroupByDir && groupBy != gocov.GroupByPackage {
+		return NewValidationError("by", groupBy, "must be 'dir' or 'package'")
+	}
+	return nil
+}
+
+// maxRound is the largest number of decimal places accepted by -round
+const maxRound = 4
+
+// ValidateRound validates the decimal places coverage is displayed and compared with
+func ValidateRound(places int) error {
+	if places < 0 || places > maxRound {
+		return NewValidationError("round", places, fmt.Sprintf("must be between 0 and %d", maxRound))
+	}
+	return nil
+}
+
+// ValidateThresholdPrecision validates the decimal places coverage is rounded to before threshold checks
+func ValidateThresholdPrecision(places int) error {
+	if places < gocov.RawPrecision || places > maxRound {
+		return NewValidationError("threshold_precision", places, fmt.Sprintf("must be between 0 and %d, or -1 to compare unrounded coverage", maxRound))
+	}
+	return nil
+}
+
+// ValidateWidth validates the directory column width
+func ValidateWidth(width int) error {
+	if width != 0 && width < gocov.MinColumnWidth {
+		return NewValidationError("width", width, fmt.Sprintf("must be 0 (auto) or at least %d", gocov.MinColumnWidth))
+	}
+	return nil
+}
+
+// ValidateColor validates when the table uses Unicode marks
+func ValidateColor(color string) error {
+	if color != "" && color != "auto" && color != "always" && color != "never" {
+		return NewValidationError("color", color, "must be 'auto', 'always' or 'never'")
+	}
+	return nil
+}
+
+// ValidateGitTimeout validates the timeout of git commands in diff mode
+func ValidateGitTimeout(timeout time.Duration) error {
+	if timeout < 0 {
+		return NewValidationError("git_timeout", timeout, "must be 0 (default) or positive")
+	}
+	return nil
+}
+
+// ValidateWorkers validates the concurrent worker count
+func ValidateWorkers(workers int) error {
+	if workers < 0 {
+		return NewValidationError("workers", workers, "must be 0 or greater")
+	}
+	return nil
+}
+
+// ValidateMinStatements validates the minimum statement count filter
+func ValidateMinStatements(minStatements int) error {
+	if minStatements < 0 {
+		return NewValidationError("min_statements", minStatements, "must be 0 or greater")
+	}
+	return nil
+}
+
+// ValidateTop validates the number of lowest-coverage directories to show
+func ValidateTop(top int) error {
+	if top < 0 {
+		return NewValidationError("top", top, "must be 0 (all directories) or greater")
+	}
+	return nil
+}
+
+// ValidateHistorySparkline validates the number of recorded runs drawn after each run
+func ValidateHistorySparkline(runs int, history string) error {
+	if runs < 0 {
+		return NewValidationError("history_sparkline", runs, "must be 0 (no sparkline) or greater")
+	}
+	if runs > 0 && history == "" {
+		return NewValidationError("history_sparkline", runs, "requires a history file (-history)")
+	}
+	return nil
+}
+
+// ValidateAssumeMode validates the coverage mode assumed for profiles without a mode: header
+func ValidateAssumeMode(mode string) error {
+	switch mode {
+	case "", "set", "count", "atomic":
+		return nil
+	default:
+		return NewValidationError("assume_mode", mode, "must be 'set', 'count', or 'atomic'")
+	}
+}
+
+// ValidateDirectoryThresholds validates per-directory threshold values
+func ValidateDirectoryThresholds(thresholds map[string]float64) error {
+	for pattern, threshold := range thresholds {
+		if threshold < 0 || threshold > 100 {
+			return NewValidationError("thresholds["+pattern+"]", threshold, "must be between 0 and 100")
+		}
+	}
+	return nil
+}
+
+// ValidateLevelRules validates per-tree aggregation levels
+func ValidateLevelRules(rules []gocov.LevelRule) error {
+	for i, rule := range rules {
+		field := fmt.Sprintf("levels[%d]", i)
+		if rule.Pattern == "" {
+			return NewValidationError(field+".pattern", rule.Pattern, "must not be empty")
+		}
+		if rule.Level < -1 {
+			return NewValidationError(field+".level", rule.Level, "must be -1 or greater")
+		}
+	}
+	return nil
+}
+
+// ValidateProfileModes checks that all profiles share the same coverage mode
+// and returns that mode ("" when there are no profiles)
+func ValidateProfileModes(profiles []*cover.Profile) (string, error) {
+	if len(profiles) == 0 {
+		return "", nil
+	}
+	mode := profiles[0].Mode
+	for _, profile := range profiles[1:] {
+		if profile.Mode != mode {
+			return "", fmt.Errorf("%w: %s (%s) and %s (%s)", ErrMixedModes, profiles[0].FileName, mode, profile.FileName, profile.Mode)
+		}
+	}
+	return mode, nil
+}
+
+// MalformedBlocks returns a description of each block that parsed but cannot come from go test:
+// positions before line or column 1, blocks ending before they start, and counts above 1 in set mode
+func MalformedBlocks(profiles []*cover.Profile) []string {
+	var malformed []string
+	for _, profile := range profiles {
+		for _, block := range profile.Blocks {
+			var reason string
+			switch {
+			case block.StartLine < 1 || block.StartCol < 1 || block.EndLine < 1 || block.EndCol < 1:
+				reason = "position before line or column 1"
+			case block.EndLine < block.StartLine || block.EndLine == block.StartLine && block.EndCol < block.StartCol:
+				reason = "block ends before it starts"
+			case profile.Mode == "set" && block.Count > 1:
+				reason = fmt.Sprintf("count %d in set mode", block.Count)
+			default:
+				continue
+			}
+			malformed = append(malformed, fmt.Sprintf("%s:%d.%d,%d.%d: %s",
+				profile.FileName, block.StartLine, block.StartCol, block.EndLine, block.EndCol, reason))
+		}
+	}
+	return malformed
+}
@@ -2,7 +2,12 @@ package main
 
 import (
 	"errors"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/blck-snwmn/gocov/pkg/gocov"
+	"golang.org/x/tools/cover"
 )
 
 func TestValidateCoverageConfig(t *testing.T) {
@@ -95,6 +100,21 @@ func TestValidateFormat(t *testing.T) {
 			format:  "json",
 			wantErr: false,
 		},
+		{
+			name:    "valid html format",
+			format:  "html",
+			wantErr: false,
+		},
+		{
+			name:    "valid summary format",
+			format:  "summary",
+			wantErr: false,
+		},
+		{
+			name:    "valid tsv format",
+			format:  "tsv",
+			wantErr: false,
+		},
 		{
 			name:    "invalid xml format",
 			format:  "xml",
@@ -128,3 +148,571 @@ func TestValidateFormat(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateWorkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		wantErr bool
+	}{
+		{"default", 0, false},
+		{"positive", 8, false},
+		{"negative", -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWorkers(tt.workers)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateWorkers(%v) error = %v, wantErr %v", tt.workers, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDiffFileThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float64
+		wantErr   bool
+	}{
+		{"unset", 0, false},
+		{"valid", 70, false},
+		{"negative", -1, true},
+		{"over 100", 101, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDiffFileThreshold(tt.threshold)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDiffFileThreshold(%v) error = %v, wantErr %v", tt.threshold, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDiffInclude(t *testing.T) {
+	tests := []struct {
+		name    string
+		include string
+		wantErr bool
+	}{
+		{"unset", "", false},
+		{"added", "added", false},
+		{"modified", "modified", false},
+		{"unknown", "deleted", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDiffInclude(tt.include)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDiffInclude(%q) error = %v, wantErr %v", tt.include, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDiffSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		sortBy  string
+		wantErr bool
+	}{
+		{"unset", "", false},
+		{"file", "file", false},
+		{"uncovered", "uncovered", false},
+		{"unknown", "coverage", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDiffSort(tt.sortBy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDiffSort(%q) error = %v, wantErr %v", tt.sortBy, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateColor(t *testing.T) {
+	tests := []struct {
+		name    string
+		color   string
+		wantErr bool
+	}{
+		{"unset", "", false},
+		{"auto", "auto", false},
+		{"always", "always", false},
+		{"never", "never", false},
+		{"unknown", "sometimes", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateColor(tt.color)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateColor(%q) error = %v, wantErr %v", tt.color, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateThresholdMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		baseline string
+		wantErr  bool
+	}{
+		{"unset", "", "", false},
+		{"absolute", "absolute", "", false},
+		{"baseline", "baseline", "old.json", false},
+		{"baseline without report", "baseline", "", true},
+		{"unknown", "relative", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateThresholdMode(tt.mode, tt.baseline)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateThresholdMode(%q, %q) error = %v, wantErr %v", tt.mode, tt.baseline, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateBaselineTolerance(t *testing.T) {
+	tests := []struct {
+		name      string
+		tolerance float64
+		wantErr   bool
+	}{
+		{"zero", 0, false},
+		{"positive", 0.5, false},
+		{"negative", -1, true},
+		{"over 100", 101, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBaselineTolerance(tt.tolerance)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateBaselineTolerance(%v) error = %v, wantErr %v", tt.tolerance, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateHistogramBands(t *testing.T) {
+	tests := []struct {
+		name    string
+		bands   []float64
+		wantErr bool
+	}{
+		{"unset", nil, false},
+		{"ascending", []float64{50, 80, 100}, false},
+		{"zero", []float64{0, 50}, true},
+		{"over 100", []float64{50, 120}, true},
+		{"descending", []float64{80, 50}, true},
+		{"duplicate", []float64{50, 50}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateHistogramBands(tt.bands)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateHistogramBands(%v) error = %v, wantErr %v", tt.bands, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDiffIncludeContext(t *testing.T) {
+	tests := []struct {
+		name         string
+		contextLines int
+		wantErr      bool
+	}{
+		{"default", 0, false},
+		{"positive", 3, false},
+		{"negative", -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDiffIncludeContext(tt.contextLines)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDiffIncludeContext(%d) error = %v, wantErr %v", tt.contextLines, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFailUnderPerDir(t *testing.T) {
+	tests := []struct {
+		name        string
+		minCoverage float64
+		wantErr     bool
+	}{
+		{"disabled", 0, false},
+		{"valid", 70, false},
+		{"negative", -1, true},
+		{"over 100", 101, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFailUnderPerDir(tt.minCoverage)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFailUnderPerDir(%v) error = %v, wantErr %v", tt.minCoverage, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateGroupBy(t *testing.T) {
+	tests := []struct {
+		name    string
+		groupBy string
+		wantErr bool
+	}{
+		{"unset", "", false},
+		{"dir", "dir", false},
+		{"package", "package", false},
+		{"unknown", "module", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateGroupBy(tt.groupBy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateGroupBy(%q) error = %v, wantErr %v", tt.groupBy, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateAssumeMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		wantErr bool
+	}{
+		{"unset", "", false},
+		{"set", "set", false},
+		{"count", "count", false},
+		{"atomic", "atomic", false},
+		{"unknown", "func", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAssumeMode(tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAssumeMode(%q) error = %v, wantErr %v", tt.mode, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateLevelRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []gocov.LevelRule
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"valid", []gocov.LevelRule{{Pattern: "cmd", Level: 2}, {Pattern: "internal", Level: -1}}, false},
+		{"empty pattern", []gocov.LevelRule{{Pattern: "", Level: 2}}, true},
+		{"level below -1", []gocov.LevelRule{{Pattern: "cmd", Level: -2}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateLevelRules(tt.rules)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateLevelRules(%+v) error = %v, wantErr %v", tt.rules, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateWidth(t *testing.T) {
+	tests := []struct {
+		name    string
+		width   int
+		wantErr bool
+	}{
+		{"auto", 0, false},
+		{"minimum", gocov.MinColumnWidth, false},
+		{"wide", 120, false},
+		{"too narrow", gocov.MinColumnWidth - 1, true},
+		{"negative", -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWidth(tt.width)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateWidth(%d) error = %v, wantErr %v", tt.width, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateGitTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		wantErr bool
+	}{
+		{"default", 0, false},
+		{"positive", time.Minute, false},
+		{"negative", -time.Second, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateGitTimeout(tt.timeout)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateGitTimeout(%v) error = %v, wantErr %v", tt.timeout, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRound(t *testing.T) {
+	tests := []struct {
+		name    string
+		places  int
+		wantErr bool
+	}{
+		{"none", 0, false},
+		{"default", 1, false},
+		{"max", 4, false},
+		{"negative", -1, true},
+		{"too many", 5, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRound(tt.places)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRound(%v) error = %v, wantErr %v", tt.places, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateMinStatements(t *testing.T) {
+	tests := []struct {
+		name          string
+		minStatements int
+		wantErr       bool
+	}{
+		{"default", 0, false},
+		{"positive", 5, false},
+		{"negative", -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMinStatements(tt.minStatements)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMinStatements(%v) error = %v, wantErr %v", tt.minStatements, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTop(t *testing.T) {
+	tests := []struct {
+		name    string
+		top     int
+		wantErr bool
+	}{
+		{"all", 0, false},
+		{"positive", 10, false},
+		{"negative", -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTop(tt.top)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTop(%v) error = %v, wantErr %v", tt.top, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateThresholdPrecision(t *testing.T) {
+	tests := []struct {
+		name    string
+		places  int
+		wantErr bool
+	}{
+		{"raw", gocov.RawPrecision, false},
+		{"none", 0, false},
+		{"max", 4, false},
+		{"below raw", -2, true},
+		{"too many", 5, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateThresholdPrecision(tt.places)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateThresholdPrecision(%v) error = %v, wantErr %v", tt.places, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateHistorySparkline(t *testing.T) {
+	tests := []struct {
+		name    string
+		runs    int
+		history string
+		wantErr bool
+	}{
+		{"off", 0, "", false},
+		{"with history", 10, ".gocov-history.json", false},
+		{"without history", 10, "", true},
+		{"negative", -1, ".gocov-history.json", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateHistorySparkline(tt.runs, tt.history)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateHistorySparkline(%v, %q) error = %v, wantErr %v", tt.runs, tt.history, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDiffThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float64
+		wantErr   bool
+	}{
+		{"unset", 0, false},
+		{"valid", 80, false},
+		{"negative", -1, true},
+		{"over 100", 101, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDiffThreshold(tt.threshold)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDiffThreshold(%v) error = %v, wantErr %v", tt.threshold, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateProfileModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles []*cover.Profile
+		wantMode string
+		wantErr  bool
+	}{
+		{
+			name:     "no profiles",
+			profiles: nil,
+			wantMode: "",
+			wantErr:  false,
+		},
+		{
+			name: "single mode",
+			profiles: []*cover.Profile{
+				{FileName: "a.go", Mode: "atomic"},
+				{FileName: "b.go", Mode: "atomic"},
+			},
+			wantMode: "atomic",
+			wantErr:  false,
+		},
+		{
+			name: "mixed modes",
+			profiles: []*cover.Profile{
+				{FileName: "a.go", Mode: "set"},
+				{FileName: "b.go", Mode: "atomic"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, err := ValidateProfileModes(tt.profiles)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateProfileModes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrMixedModes) {
+					t.Errorf("Expected ErrMixedModes, got: %v", err)
+				}
+				return
+			}
+			if mode != tt.wantMode {
+				t.Errorf("ValidateProfileModes() mode = %q, want %q", mode, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestMalformedBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles []*cover.Profile
+		want     []string
+	}{
+		{
+			name: "well formed",
+			profiles: []*cover.Profile{{FileName: "a.go", Mode: "set", Blocks: []cover.ProfileBlock{
+				{StartLine: 1, StartCol: 1, EndLine: 1, EndCol: 1, NumStmt: 0, Count: 0},
+				{StartLine: 2, StartCol: 5, EndLine: 4, EndCol: 2, NumStmt: 2, Count: 1},
+			}}},
+		},
+		{
+			name: "zero position",
+			profiles: []*cover.Profile{{FileName: "a.go", Mode: "set", Blocks: []cover.ProfileBlock{
+				{StartLine: 0, StartCol: 1, EndLine: 2, EndCol: 1, NumStmt: 1},
+			}}},
+			want: []string{"a.go:0.1,2.1: position before line or column 1"},
+		},
+		{
+			name: "end before start",
+			profiles: []*cover.Profile{{FileName: "a.go", Mode: "count", Blocks: []cover.ProfileBlock{
+				{StartLine: 3, StartCol: 1, EndLine: 2, EndCol: 1, NumStmt: 1},
+				{StartLine: 4, StartCol: 9, EndLine: 4, EndCol: 2, NumStmt: 1},
+			}}},
+			want: []string{
+				"a.go:3.1,2.1: block ends before it starts",
+				"a.go:4.9,4.2: block ends before it starts",
+			},
+		},
+		{
+			name: "counts only limited in set mode",
+			profiles: []*cover.Profile{
+				{FileName: "a.go", Mode: "set", Blocks: []cover.ProfileBlock{{StartLine: 1, StartCol: 1, EndLine: 2, EndCol: 1, NumStmt: 1, Count: 2}}},
+				{FileName: "b.go", Mode: "count", Blocks: []cover.ProfileBlock{{StartLine: 1, StartCol: 1, EndLine: 2, EndCol: 1, NumStmt: 1, Count: 2}}},
+			},
+			want: []string{"a.go:1.1,2.1: count 2 in set mode"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MalformedBlocks(tt.profiles); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MalformedBlocks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
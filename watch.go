@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// watchPollInterval is how often -watch checks the coverage profiles for changes
+const watchPollInterval = 500 * time.Millisecond
+
+// clearScreen moves the cursor home and clears the terminal between -watch runs
+const clearScreen = "\033[H\033[2J"
+
+// runWatch re-runs the CLI with the same arguments whenever the coverage profiles change
+// Errors, including threshold failures, are printed and watching continues until the process is stopped
+func (c *CLI) runWatch(coverProfile, profileList string) error {
+	if !c.outputIsTerminal() {
+		return ErrWatchNotTerminal
+	}
+
+	interval := c.watchInterval
+	if interval <= 0 {
+		interval = watchPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	errOutput := c.errOutput()
+
+	c.watching = true
+	defer func() { c.watching = false }()
+
+	last := ""
+	for {
+		if stamp := profileStamp(coverProfile, profileList); stamp != last {
+			last = stamp
+			fmt.Fprint(c.Output, clearScreen)
+			if err := c.Run(); err != nil {
+				fmt.Fprintln(errOutput, err)
+			}
+			fmt.Fprintf(c.Output, "\nWatching for changes (Ctrl-C to stop)\n")
+		}
+
+		select {
+		case <-c.watchStop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// outputIsTerminal reports whether results are written to an interactive terminal
+func (c *CLI) outputIsTerminal() bool {
+	if c.isTerminal != nil {
+		return c.isTerminal()
+	}
+	file, ok := c.Output.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// profileStamp summarizes the names, sizes, and modification times of the watched profiles
+// Globs and list files are expanded on every call, so new profiles are noticed as well
+func profileStamp(coverProfile, profileList string) string {
+	var paths []string
+	if strings.ContainsAny(coverProfile, "*?[") {
+		paths, _ = filepath.Glob(coverProfile)
+	} else if coverProfile != "" {
+		paths = append(paths, coverProfile)
+	}
+	if profileList != "" {
+		paths = append(paths, profileList)
+		if files, err := readProfileList(profileList); err == nil {
+			paths = append(paths, files...)
+		}
+	}
+
+	var stamp strings.Builder
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(&stamp, "%s missing\n", path)
+			continue
+		}
+		fmt.Fprintf(&stamp, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return stamp.String()
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer that can be read while -watch writes to it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatch(t *testing.T) {
+	t.Run("requires a terminal", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := NewCLI(&buf, []string{"-coverprofile", "testdata/coverage.out", "-watch"}).Run()
+		if !errors.Is(err, ErrWatchNotTerminal) {
+			t.Errorf("Expected ErrWatchNotTerminal, got: %v", err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("Nothing should be written outside a terminal\nGot: %s", buf.String())
+		}
+	})
+
+	t.Run("re-runs on change", func(t *testing.T) {
+		profile := filepath.Join(t.TempDir(), "coverage.out")
+		writeProfile := func(covered int) {
+			content := "mode: set\ngithub.com/example/project/pkg/util/a.go:1.1,2.1 4 " + strconv.Itoa(covered) + "\n"
+			if err := os.WriteFile(profile, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write coverage file: %v", err)
+			}
+		}
+		writeProfile(0)
+
+		var out, errOut syncBuffer
+		stop := make(chan struct{})
+		cli := NewCLI(&out, []string{"-coverprofile", profile, "-watch", "-format", "summary", "-threshold", "50"})
+		cli.ErrOutput = &errOut
+		cli.watchStop = stop
+		cli.watchInterval = 10 * time.Millisecond
+		cli.isTerminal = func() bool { return true }
+
+		done := make(chan error)
+		go func() { done <- cli.Run() }()
+
+		waitFor := func(want string) {
+			t.Helper()
+			deadline := time.Now().Add(5 * time.Second)
+			for !strings.Contains(out.String(), want) {
+				if time.Now().After(deadline) {
+					t.Fatalf("Timed out waiting for %q\nGot: %s", want, out.String())
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+
+		waitFor("coverage: 0.0% (0/4 statements)")
+		// Threshold failures are reported without stopping the watch
+		if !strings.Contains(errOut.String(), "below threshold") {
+			t.Errorf("Expected the threshold failure on stderr\nGot: %s", errOut.String())
+		}
+
+		// Make sure the modification time changes even on coarse file systems
+		later := time.Now().Add(time.Second)
+		writeProfile(1)
+		if err := os.Chtimes(profile, later, later); err != nil {
+			t.Fatalf("Failed to touch coverage file: %v", err)
+		}
+		waitFor("coverage: 100.0% (4/4 statements)")
+		if !strings.Contains(out.String(), clearScreen+"coverage: 100.0%") {
+			t.Errorf("Expected the screen to be cleared before each run\nGot: %q", out.String())
+		}
+
+		close(stop)
+		if err := <-done; err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+}